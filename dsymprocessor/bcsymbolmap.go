@@ -0,0 +1,63 @@
+package dsymprocessor
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidBCSymbolMap = errors.New("invalid bcsymbolmap")
+
+	// hiddenSymbolRegex matches the obfuscated names that bitcode builds put in
+	// dSYMs, e.g. __hidden#1234_. The number is an index into the bcsymbolmap.
+	hiddenSymbolRegex = regexp.MustCompile(`__hidden#(\d+)_`)
+)
+
+// bcSymbolMap resolves the hidden symbol and file names of a dSYM built from
+// bitcode. It is parsed from a <uuid>.bcsymbolmap file, which is a header line
+// followed by one original name per line.
+type bcSymbolMap struct {
+	names []string
+}
+
+func parseBCSymbolMap(data []byte) (*bcSymbolMap, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if !strings.HasPrefix(lines[0], "BCSymbolMap Version:") {
+		return nil, errInvalidBCSymbolMap
+	}
+
+	names := lines[1:]
+	if len(names) > 0 && names[len(names)-1] == "" {
+		names = names[:len(names)-1]
+	}
+
+	return &bcSymbolMap{names: names}, nil
+}
+
+// resolve replaces every hidden name in s with its original name. Hidden names
+// that are out of range of the map are left as-is.
+func (m *bcSymbolMap) resolve(s string) string {
+	if !strings.Contains(s, "__hidden#") {
+		return s
+	}
+
+	return hiddenSymbolRegex.ReplaceAllStringFunc(s, func(hidden string) string {
+		idx, err := strconv.Atoi(hiddenSymbolRegex.FindStringSubmatch(hidden)[1])
+		if err != nil || idx >= len(m.names) {
+			return hidden
+		}
+		return m.names[idx]
+	})
+}
+
+// hasHiddenSymbols reports whether any of the frames has a hidden symbol or file name.
+func hasHiddenSymbols(frames []*mappedDSYMStackFrame) bool {
+	for _, frame := range frames {
+		if strings.Contains(frame.symbol, "__hidden#") || strings.Contains(frame.path, "__hidden#") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package dsymprocessor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestParseBCSymbolMap(t *testing.T) {
+	symbolMap, err := parseBCSymbolMap([]byte("BCSymbolMap Version: 2.0\n$s16Chateaux_Bufeaux11ContentViewV4bodyQrvg\nContentView.swift\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "$s16Chateaux_Bufeaux11ContentViewV4bodyQrvg", symbolMap.resolve("__hidden#0_"))
+	assert.Equal(t, "/Users/me/ContentView.swift", symbolMap.resolve("/Users/me/__hidden#1_"))
+	assert.Equal(t, "__hidden#2_", symbolMap.resolve("__hidden#2_"))
+	assert.Equal(t, "main", symbolMap.resolve("main"))
+
+	_, err = parseBCSymbolMap([]byte("not a symbol map"))
+	assert.ErrorIs(t, err, errInvalidBCSymbolMap)
+}
+
+func TestFileStoreBCSymbolMap(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "6A8CB813-45F6-3652-AD33-778FD1EAB196.bcsymbolmap"), []byte("BCSymbolMap Version: 2.0\nmain\n"), 0o600))
+
+	// a recompiled dSYM that points at the symbol map of the original build
+	resources := filepath.Join(dir, "11111111-2222-3333-4444-555555555555.dSYM", "Contents", "Resources")
+	require.NoError(t, os.MkdirAll(resources, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(resources, "11111111-2222-3333-4444-555555555555.plist"), []byte(`<plist version="1.0"><dict>
+	<key>DBGOriginalUUID</key>
+	<string>6A8CB813-45F6-3652-AD33-778FD1EAB196</string>
+</dict></plist>`), 0o600))
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDSYMConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	source, err := fs.GetBCSymbolMap(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+
+	source, err = fs.GetBCSymbolMap(ctx, "11111111-2222-3333-4444-555555555555")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+
+	_, err = fs.GetBCSymbolMap(ctx, "99999999-2222-3333-4444-555555555555")
+	assert.ErrorIs(t, err, errFailedToFindBCSymbolMap)
+}
+
+func TestFileStoreBCSymbolMapLayouts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	symsorter := filepath.Join(dir, "6a", "8cb81345f63652ad33778fd1eab196")
+	require.NoError(t, os.MkdirAll(symsorter, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(symsorter, "bcsymbolmap"), []byte("BCSymbolMap Version: 2.0\nmain\n"), 0o600))
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDSYMConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	// the symbol map is only looked up in the configured layouts
+	_, err = fs.GetBCSymbolMap(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.ErrorIs(t, err, errFailedToFindBCSymbolMap)
+
+	fs.layouts = []string{storeLayoutBreakpad, storeLayoutSymsorter}
+	source, err := fs.GetBCSymbolMap(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+}
+
+func TestStoreBCSymbolMapFetchError(t *testing.T) {
+	s := newStore(zaptest.NewLogger(t), "", func(ctx context.Context, key string) ([]byte, error) {
+		return nil, errors.New("access denied")
+	})
+
+	// an error other than the symbol map not existing is a fetch failure
+	_, err := s.GetBCSymbolMap(context.Background(), "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errFailedToFindBCSymbolMap)
+}
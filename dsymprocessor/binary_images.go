@@ -0,0 +1,216 @@
+package dsymprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// binaryImage is a binary loaded into a crashed process, as listed by a crash
+// report. Absolute instruction addresses are made relative to the image they
+// fall in before they are symbolicated.
+type binaryImage struct {
+	name        string
+	uuid        string
+	loadAddress uint64
+	// size is the size of the image in memory, or 0 if unknown.
+	size uint64
+	// arch is the architecture of the image, eg. arm64e, or empty if unknown.
+	arch string
+}
+
+// binaryImages are the images of a process, sorted by load address.
+type binaryImages []binaryImage
+
+func newBinaryImages(images []binaryImage) binaryImages {
+	sorted := make(binaryImages, 0, len(images))
+	for _, image := range images {
+		// Crash reporters list images by their full path on the device.
+		image.name = path.Base(image.name)
+		image.uuid = formatUUID(image.uuid)
+		sorted = append(sorted, image)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].loadAddress < sorted[j].loadAddress
+	})
+
+	return sorted
+}
+
+const (
+	// maxUserSpaceAddress is the highest user space address on any 64-bit Apple
+	// platform. Anything above it must carry pointer authentication bits.
+	maxUserSpaceAddress = 0x00007FFFFFFFFFFF
+	// pointerAuthenticationMask keeps the virtual address bits of an arm64e
+	// pointer, dropping the pointer authentication code signed into its upper bits.
+	pointerAuthenticationMask = 0x0000000FFFFFFFFF
+	// maxUnsizedImageSize is how far the last image is assumed to extend when
+	// its size isn't known.
+	maxUnsizedImageSize = 1 << 30
+)
+
+// arm64 reports whether the images were loaded into an arm64 process, whose
+// return addresses can carry pointer authentication bits anywhere above the
+// virtual address bits. Without architectures, images loaded within the
+// virtual address bits are assumed to be arm64, since x86_64 processes load
+// their system libraries far above them.
+func (images binaryImages) arm64() bool {
+	if len(images) == 0 {
+		return false
+	}
+
+	for _, image := range images {
+		if image.arch != "" {
+			return strings.HasPrefix(image.arch, "arm64")
+		}
+	}
+	return images[len(images)-1].loadAddress <= pointerAuthenticationMask
+}
+
+// stripPointerAuthentication removes the pointer authentication code from a
+// return address. The addresses of arm64 processes are always masked, others
+// only if they are above any user space address.
+func (images binaryImages) stripPointerAuthentication(addr uint64) uint64 {
+	if images.arm64() || addr > maxUserSpaceAddress {
+		return addr & pointerAuthenticationMask
+	}
+	return addr
+}
+
+// resolve returns the image that contains an absolute address, and the offset
+// of the address into that image.
+func (images binaryImages) resolve(addr uint64) (binaryImage, uint64, bool) {
+	addr = images.stripPointerAuthentication(addr)
+
+	image, ok := images.find(addr)
+	if !ok {
+		return binaryImage{}, 0, false
+	}
+
+	return image, addr - image.loadAddress, true
+}
+
+// find returns the image that contains addr. An image without a size is
+// assumed to extend up to the next image, or by maxUnsizedImageSize if it's
+// the last one.
+func (images binaryImages) find(addr uint64) (binaryImage, bool) {
+	idx := sort.Search(len(images), func(i int) bool {
+		return images[i].loadAddress > addr
+	}) - 1
+
+	if idx < 0 {
+		return binaryImage{}, false
+	}
+
+	image := images[idx]
+	size := image.size
+	if size == 0 && idx == len(images)-1 {
+		size = maxUnsizedImageSize
+	}
+	if size > 0 && addr-image.loadAddress >= size {
+		return binaryImage{}, false
+	}
+
+	return image, true
+}
+
+// findByName returns the first image with the given name.
+func (images binaryImages) findByName(name string) (binaryImage, bool) {
+	for _, image := range images {
+		if image.name == name {
+			return image, true
+		}
+	}
+	return binaryImage{}, false
+}
+
+// formatUUID formats a UUID the way dSYMs are named, e.g. turning
+// 6a8cb81345f63652ad33778fd1eab196 into 6A8CB813-45F6-3652-AD33-778FD1EAB196.
+func formatUUID(uuid string) string {
+	uuid = strings.ToUpper(strings.ReplaceAll(uuid, "-", ""))
+	if len(uuid) != 32 {
+		return uuid
+	}
+
+	return uuid[0:8] + "-" + uuid[8:12] + "-" + uuid[12:16] + "-" + uuid[16:20] + "-" + uuid[20:32]
+}
+
+// binaryImageJSON is a binary image as sent in the binary images attribute,
+// e.g. {"name": "Chateaux Bufeaux", "uuid": "6A8CB813-...", "load_address": "0x102500000", "size": 65536, "arch": "arm64"}.
+type binaryImageJSON struct {
+	Name        string      `json:"name"`
+	UUID        string      `json:"uuid"`
+	LoadAddress jsonAddress `json:"load_address"`
+	Size        jsonAddress `json:"size"`
+	Arch        string      `json:"arch"`
+}
+
+// jsonAddress is an address or size given either as a JSON number or as a
+// decimal or 0x-prefixed hex string, since JSON numbers can't represent every
+// 64-bit address exactly in most SDKs.
+type jsonAddress uint64
+
+func (a *jsonAddress) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", data, err)
+	}
+	*a = jsonAddress(v)
+	return nil
+}
+
+// parseBinaryImages parses the binary images attribute, a JSON array of images.
+func parseBinaryImages(raw string) (binaryImages, error) {
+	var parsed []binaryImageJSON
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, err
+	}
+
+	images := make([]binaryImage, len(parsed))
+	for i, image := range parsed {
+		images[i] = binaryImage{
+			name:        image.Name,
+			uuid:        image.UUID,
+			loadAddress: uint64(image.LoadAddress),
+			size:        uint64(image.Size),
+			arch:        image.Arch,
+		}
+	}
+
+	return newBinaryImages(images), nil
+}
+
+// binaryImages returns the images from the binary images attribute of a record,
+// falling back to its resource, or none if neither has one.
+func (sp *symbolicatorProcessor) binaryImages(attributes, resourceAttributes pcommon.Map) (binaryImages, error) {
+	value, ok := attributes.Get(sp.cfg.BinaryImagesAttributeKey)
+	if !ok {
+		value, ok = resourceAttributes.Get(sp.cfg.BinaryImagesAttributeKey)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	images, err := parseBinaryImages(value.Str())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s attribute: %w", sp.cfg.BinaryImagesAttributeKey, err)
+	}
+
+	return images, nil
+}
+
+// hasBinaryImages reports whether a record or its resource has binary images.
+func (sp *symbolicatorProcessor) hasBinaryImages(attributes, resourceAttributes pcommon.Map) bool {
+	if _, ok := attributes.Get(sp.cfg.BinaryImagesAttributeKey); ok {
+		return true
+	}
+	_, ok := resourceAttributes.Get(sp.cfg.BinaryImagesAttributeKey)
+	return ok
+}
@@ -0,0 +1,202 @@
+package dsymprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBinaryImagesResolve(t *testing.T) {
+	images, err := parseBinaryImages(testBinaryImages)
+	require.NoError(t, err)
+
+	image, offset, ok := images.resolve(0x102507548)
+	assert.True(t, ok)
+	assert.Equal(t, "Chateaux Bufeaux", image.name)
+	assert.Equal(t, "6A8CB813-45F6-3652-AD33-778FD1EAB196", image.uuid)
+	assert.Equal(t, uint64(30024), offset)
+
+	// an arm64e return address signed with pointer authentication
+	image, offset, ok = images.resolve(0x2f6b800102507548)
+	assert.True(t, ok)
+	assert.Equal(t, "Chateaux Bufeaux", image.name)
+	assert.Equal(t, uint64(30024), offset)
+
+	// past the end of the sized image, and before any image
+	_, _, ok = images.resolve(0x102510000)
+	assert.False(t, ok)
+	_, _, ok = images.resolve(0x1000)
+	assert.False(t, ok)
+
+	// pointer authentication bits below the highest user space address
+	image, offset, ok = images.resolve(0x00003A0102507548)
+	assert.True(t, ok)
+	assert.Equal(t, "Chateaux Bufeaux", image.name)
+	assert.Equal(t, uint64(30024), offset)
+
+	// the last image has no size, so is only assumed to extend so far
+	image, _, ok = images.resolve(0x180b00000)
+	assert.True(t, ok)
+	assert.Equal(t, "libobjc.A.dylib", image.name)
+	_, _, ok = images.resolve(0x1c0b00000)
+	assert.False(t, ok)
+}
+
+func TestBinaryImagesArm64(t *testing.T) {
+	images, err := parseBinaryImages(testBinaryImages)
+	require.NoError(t, err)
+	assert.True(t, images.arm64())
+
+	// x86_64 processes load system libraries above the pointer authentication mask
+	images, err = parseBinaryImages(`[
+		{"name": "App", "load_address": "0x100000000", "size": 65536},
+		{"name": "libobjc.A.dylib", "load_address": "0x7ff800000000"}
+	]`)
+	require.NoError(t, err)
+	assert.False(t, images.arm64())
+	assert.Equal(t, uint64(0x3A0100001000), images.stripPointerAuthentication(0x3A0100001000))
+
+	// an architecture given by the images is used as-is
+	images, err = parseBinaryImages(`[{"name": "App", "load_address": "0x100000000", "arch": "x86_64"}]`)
+	require.NoError(t, err)
+	assert.False(t, images.arm64())
+	images, err = parseBinaryImages(`[{"name": "App", "load_address": "0x7ff800000000", "arch": "arm64e"}]`)
+	require.NoError(t, err)
+	assert.True(t, images.arm64())
+}
+
+func TestParseBinaryImages_Invalid(t *testing.T) {
+	_, err := parseBinaryImages(`[{"name": "Chateaux Bufeaux", "load_address": "main"}]`)
+	assert.Error(t, err)
+}
+
+func TestProcessMetricKitAbsoluteAddresses(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	resourceLog := logs.ResourceLogs().AppendEmpty()
+	// images can be sent once on the resource rather than on every record
+	resourceLog.Resource().Attributes().PutStr(cfg.BinaryImagesAttributeKey, testBinaryImages)
+	log := resourceLog.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.MetricKitStackTraceAttributeKey, `{"callStacks": [{"threadAttributed": true, "callStackFrames": [
+		{"address": 3416965484112803144},
+		{"binaryName": "libobjc.A.dylib", "address": 6453435068}
+	]}]}`)
+
+	_, err := processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	stackTrace, ok := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, "Chateaux Bufeaux\t\t\t0x7548 main (MyFile.swift:1) + 1\n    libobjc.A.dylib(7821F73C-378B-3A10-BE90-EF526B7DBA93) +88", stackTrace.Str())
+}
+
+func TestProcessStackTraceLoadAddress(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	log := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.StackTraceAttributeKey, "3   Chateaux Bufeaux                    0x0000000102507548 0x102500000 + 30024")
+	log.Attributes().PutStr(cfg.BuildUUIDAttributeKey, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	log.Attributes().PutStr(cfg.AppExecutableAttributeKey, "Chateaux Bufeaux")
+
+	processor.processStackTraceAttributes(ctx, log.Attributes(), log.Attributes())
+
+	stackTrace, ok := log.Attributes().Get(cfg.StackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, "3   Chateaux Bufeaux                    0x0000000102507548 main (in Chateaux Bufeaux) (MyFile.swift:1) + 30024", stackTrace.Str())
+}
+
+func TestProcessStackTraceInvalidBinaryImages(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	log := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.StackTraceAttributeKey, `3   Chateaux Bufeaux                    0x0000000102507548 Chateaux Bufeaux + 30024
+4   Chateaux Bufeaux                    0x0000000102507548 $s15ChateauxBufeaux4mainyyF + 100`)
+	log.Attributes().PutStr(cfg.BuildUUIDAttributeKey, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	log.Attributes().PutStr(cfg.AppExecutableAttributeKey, "Chateaux Bufeaux")
+	log.Attributes().PutStr(cfg.BinaryImagesAttributeKey, `[{"name": "Chateaux Bufeaux", "load_address": "main"}]`)
+
+	processor.processStackTraceAttributes(ctx, log.Attributes(), log.Attributes())
+
+	// malformed images only leave the lines that need them as-is
+	stackTrace, ok := log.Attributes().Get(cfg.StackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, `3   Chateaux Bufeaux                    0x0000000102507548 main (in Chateaux Bufeaux) (MyFile.swift:1) + 30024
+4   Chateaux Bufeaux                    0x0000000102507548 $s15ChateauxBufeaux4mainyyF + 100`, stackTrace.Str())
+
+	failed, ok := log.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	require.True(t, ok)
+	assert.False(t, failed.Bool())
+}
+
+func TestProcessMetricKitInvalidBinaryImages(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	log := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.BinaryImagesAttributeKey, `[{"name": "Chateaux Bufeaux", "load_address": "main"}]`)
+	log.Attributes().PutStr(cfg.MetricKitStackTraceAttributeKey, `{"callStacks": [{"threadAttributed": true, "callStackFrames": [
+		{"binaryName": "Chateaux Bufeaux", "binaryUUID": "6A8CB813-45F6-3652-AD33-778FD1EAB196", "offsetAddress": 30024},
+		{"address": 3416965484112803144}
+	]}]}`)
+
+	_, err := processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	// malformed images only fail the frames that need them
+	stackTrace, ok := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, "Chateaux Bufeaux\t\t\t0x7548 main (MyFile.swift:1) + 1\n    () +0", stackTrace.Str())
+
+	statuses, ok := log.Attributes().Get(cfg.OutputMetricKitFrameStatusesAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, []any{"symbolicated", "failed"}, statuses.Slice().AsRaw())
+}
@@ -31,6 +31,70 @@ type Config struct {
 	// symbolicated metrickit stack trace.
 	OutputMetricKitExceptionMessageAttributeKey string `mapstructure:"output_metrickit_exception_message_attribute_key"`
 
+	// OutputMetricKitFoldedStacksAttributeKey is the attribute key that contains the
+	// symbolicated metrickit call stack trees in the folded (flame graph) format.
+	OutputMetricKitFoldedStacksAttributeKey string `mapstructure:"output_metrickit_folded_stacks_attribute_key"`
+
+	// OutputMetricKitHeaviestStackTraceAttributeKey is the attribute key that contains the
+	// symbolicated path with the most samples across all metrickit call stack trees.
+	OutputMetricKitHeaviestStackTraceAttributeKey string `mapstructure:"output_metrickit_heaviest_stack_trace_attribute_key"`
+
+	// OutputMetricKitFrameStatusesAttributeKey is the attribute key that contains the
+	// symbolication status of each frame in the symbolicated metrickit stack trace.
+	OutputMetricKitFrameStatusesAttributeKey string `mapstructure:"output_metrickit_frame_statuses_attribute_key"`
+
+	// CrashReportAttributeKey is the attribute key that contains a KSCrash JSON or
+	// PLCrashReporter text crash report.
+	CrashReportAttributeKey string `mapstructure:"crash_report_attribute_key"`
+
+	// ReturnAddressesAttributeKey is the attribute key that contains the absolute
+	// return addresses of a stack, e.g. NSException.callStackReturnAddresses.
+	ReturnAddressesAttributeKey string `mapstructure:"return_addresses_attribute_key"`
+
+	// BinaryImagesAttributeKey is the attribute key that contains a JSON array of the
+	// binary images loaded into the process, with their name, UUID, load address and size.
+	BinaryImagesAttributeKey string `mapstructure:"binary_images_attribute_key"`
+
+	// ExceptionTypeAttributeKey is the attribute key that the exception type of a
+	// crash report is populated into.
+	ExceptionTypeAttributeKey string `mapstructure:"exception_type_attribute_key"`
+
+	// ExceptionMessageAttributeKey is the attribute key that the exception message
+	// of a crash report is populated into.
+	ExceptionMessageAttributeKey string `mapstructure:"exception_message_attribute_key"`
+
+	// OutputStackTraceBinariesAttributeKey is the attribute key that contains the
+	// binary of each symbolicated frame of a crash report's crashed thread.
+	OutputStackTraceBinariesAttributeKey string `mapstructure:"output_stack_trace_binaries_attribute_key"`
+
+	// OutputStackTraceFunctionsAttributeKey is the attribute key that contains the
+	// function of each symbolicated frame of a crash report's crashed thread.
+	OutputStackTraceFunctionsAttributeKey string `mapstructure:"output_stack_trace_functions_attribute_key"`
+
+	// OutputStackTraceFilesAttributeKey is the attribute key that contains the
+	// source file of each symbolicated frame of a crash report's crashed thread.
+	OutputStackTraceFilesAttributeKey string `mapstructure:"output_stack_trace_files_attribute_key"`
+
+	// OutputStackTraceLinesAttributeKey is the attribute key that contains the
+	// line of each symbolicated frame of a crash report's crashed thread.
+	OutputStackTraceLinesAttributeKey string `mapstructure:"output_stack_trace_lines_attribute_key"`
+
+	// OutputStackTraceFrameStatusesAttributeKey is the attribute key that contains the
+	// symbolication status of each frame of a crash report's crashed thread.
+	OutputStackTraceFrameStatusesAttributeKey string `mapstructure:"output_stack_trace_frame_statuses_attribute_key"`
+
+	// OutputStackTraceInlineDepthsAttributeKey is the attribute key that contains the
+	// inline depth of each symbolicated location of a crash report's crashed thread.
+	// Like the other structured attributes, it is only written for crash reports and
+	// return addresses; generic and MetricKit stack traces mark inlined frames in
+	// their text instead.
+	OutputStackTraceInlineDepthsAttributeKey string `mapstructure:"output_stack_trace_inline_depths_attribute_key"`
+
+	// OutputStackTraceInlineParentsAttributeKey is the attribute key that contains the
+	// index of the location each inlined location of a crash report's crashed thread
+	// was inlined into. It is only written for crash reports and return addresses.
+	OutputStackTraceInlineParentsAttributeKey string `mapstructure:"output_stack_trace_inline_parents_attribute_key"`
+
 	// preserveStackTrace is a config option that determines whether to keep the
 	// original stack trace in the output.
 	PreserveStackTrace bool `mapstructure:"preserve_stack_trace"`
@@ -47,6 +111,15 @@ type Config struct {
 
 	DSYMStoreKey string `mapstructure:"dsym_store"`
 
+	// DSYMStoreLayouts are the layouts used to look up dSYMs in the store, tried in
+	// order: "dsym", "symsorter", "symstore" or "breakpad".
+	DSYMStoreLayouts []string `mapstructure:"dsym_store_layouts"`
+
+	// IL2CPPLineMappings enables fetching Unity's LineNumberMappings.json for
+	// binaries built with IL2CPP, and mapping their generated C++ locations back
+	// to the original C# methods, files and lines.
+	IL2CPPLineMappings bool `mapstructure:"il2cpp_line_mappings"`
+
 	// LocalDSYMConfiguration is the configuration for sourcing source maps on a local volume.
 	LocalDSYMConfiguration *LocalDSYMConfiguration `mapstructure:"local_dsyms"`
 
@@ -96,5 +169,5 @@ type GCSDSYMConfiguration struct {
 
 // Validate checks the configuration for any issues.
 func (c *Config) Validate() error {
-	return nil
+	return validateStoreLayouts(c.DSYMStoreLayouts)
 }
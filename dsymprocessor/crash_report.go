@@ -0,0 +1,249 @@
+package dsymprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+)
+
+var errUnknownCrashReportFormat = errors.New("unknown crash report format")
+
+// unknownBinaryName is shown for frames whose binary image isn't known, as in
+// Apple's crash reports.
+const unknownBinaryName = "???"
+
+// crashReport is a crash report from a third-party crash reporter, such as
+// KSCrash or PLCrashReporter, normalized so that every format is symbolicated
+// and rebuilt the same way.
+type crashReport struct {
+	exceptionType    string
+	exceptionMessage string
+	images           binaryImages
+	threads          []crashThread
+}
+
+type crashThread struct {
+	index   int
+	name    string
+	crashed bool
+	// lastExceptionBacktrace is set for the backtrace of where an uncaught
+	// exception was thrown. The crashed thread only shows the runtime aborting
+	// after it.
+	lastExceptionBacktrace bool
+	frames                 []crashFrame
+}
+
+type crashFrame struct {
+	binaryName string
+	// binaryUUID is empty when the frame's binary image is unknown.
+	binaryUUID string
+	// address is the absolute instruction address.
+	address uint64
+	// offset is the address relative to the binary's load address.
+	offset uint64
+}
+
+// parseCrashReport detects the format of a crash report and parses it.
+func parseCrashReport(raw string) (*crashReport, error) {
+	trimmed := strings.TrimSpace(raw)
+
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		return parseKSCrashReport([]byte(trimmed))
+	case strings.Contains(trimmed, "Binary Images:"):
+		return parsePLCrashReport(trimmed)
+	}
+
+	return nil, errUnknownCrashReportFormat
+}
+
+// frameAt resolves an absolute instruction address to a frame in one of the
+// report's binary images. If the address isn't in any listed image, the binary
+// name and load address given by the frame itself are used instead.
+func (r *crashReport) frameAt(addr uint64, binaryName string, loadAddress uint64) crashFrame {
+	if image, offset, ok := r.images.resolve(addr); ok {
+		return crashFrame{
+			binaryName: image.name,
+			binaryUUID: image.uuid,
+			address:    addr,
+			offset:     offset,
+		}
+	}
+
+	frame := crashFrame{binaryName: binaryName, address: addr}
+
+	addr = r.images.stripPointerAuthentication(addr)
+
+	// Without a load address there is no way to know the offset to symbolicate.
+	if loadAddress == 0 || loadAddress > addr {
+		return frame
+	}
+
+	frame.offset = addr - loadAddress
+	if image, ok := r.images.findByName(binaryName); ok {
+		frame.binaryUUID = image.uuid
+	}
+
+	return frame
+}
+
+// structuredThread returns the last exception backtrace, or else the thread
+// that crashed, or the first thread if the report doesn't say.
+func (r *crashReport) structuredThread() int {
+	for i, thread := range r.threads {
+		if thread.lastExceptionBacktrace {
+			return i
+		}
+	}
+	for i, thread := range r.threads {
+		if thread.crashed {
+			return i
+		}
+	}
+	return 0
+}
+
+func (sp *symbolicatorProcessor) processCrashReportAttributes(ctx context.Context, attributes pcommon.Map) {
+	// Start timing symbolication only when we actually perform it
+	// End timing deferred to after processing is done
+	startTime := time.Now()
+	defer func() {
+		sp.telemetryBuilder.ProcessorSymbolicationDuration.Record(ctx, time.Since(startTime).Seconds(), sp.attributes)
+	}()
+
+	// Add processor type and version as attributes
+	attributes.PutStr("honeycomb.processor_type", typeStr.String())
+	attributes.PutStr("honeycomb.processor_version", processorVersion)
+
+	err := sp.processCrashReportAttributesThrows(ctx, attributes)
+	if err != nil {
+		attributes.PutBool(sp.cfg.SymbolicatorFailureAttributeKey, true)
+		attributes.PutStr("exception.symbolicator.error", err.Error())
+		sp.logger.Debug("Error processing span", zap.Error(err))
+	} else {
+		attributes.PutBool(sp.cfg.SymbolicatorFailureAttributeKey, false)
+	}
+}
+
+func (sp *symbolicatorProcessor) processCrashReportAttributesThrows(ctx context.Context, attributes pcommon.Map) error {
+	var ok bool
+	var crashReportValue pcommon.Value
+
+	if crashReportValue, ok = attributes.Get(sp.cfg.CrashReportAttributeKey); !ok {
+		// we should never get here (our caller checks this)
+		return fmt.Errorf("Invalid state! Called processCrashReportAttributes while missing %s attribute", sp.cfg.CrashReportAttributeKey)
+	}
+
+	report, err := parseCrashReport(crashReportValue.Str())
+	if err != nil {
+		return err
+	}
+
+	if err := sp.symbolicateCrashReport(ctx, attributes, report); err != nil {
+		return err
+	}
+
+	if !sp.cfg.PreserveStackTrace {
+		attributes.Remove(sp.cfg.CrashReportAttributeKey)
+	}
+
+	return nil
+}
+
+// symbolicateCrashReport symbolicates every thread of a report, writing the
+// rebuilt stack trace, its exception and the structured crashed thread.
+func (sp *symbolicatorProcessor) symbolicateCrashReport(ctx context.Context, attributes pcommon.Map, report *crashReport) error {
+	threads := make([]string, 0, len(report.threads))
+	structured := sp.newStructuredStackTrace(attributes)
+	structuredThread := report.structuredThread()
+	symbolicationFailed := false
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	for i, thread := range report.threads {
+		lines := make([]string, 0, len(thread.frames)+2)
+		if thread.name != "" {
+			lines = append(lines, fmt.Sprintf("Thread %d name:  %s", thread.index, thread.name))
+		}
+		if thread.lastExceptionBacktrace {
+			lines = append(lines, "Last Exception Backtrace:")
+		} else if thread.crashed {
+			lines = append(lines, fmt.Sprintf("Thread %d Crashed:", thread.index))
+		} else if len(report.threads) > 1 || thread.name != "" {
+			// a lone anonymous thread, such as a list of return addresses, has no header
+			lines = append(lines, fmt.Sprintf("Thread %d:", thread.index))
+		}
+
+		for idx, frame := range thread.frames {
+			locations, status := sp.symbolicateCrashFrame(ctx, frame, fetchErrorCache)
+			if status == frameStatusFailed {
+				symbolicationFailed = true
+			}
+
+			lines = append(lines, formatCrashFrame(idx, frame, locations))
+
+			if i == structuredThread {
+				structured.appendFrame(frame.binaryName, locations, status)
+			}
+		}
+
+		threads = append(threads, strings.Join(lines, "\n"))
+	}
+
+	if stackTraceValue, ok := attributes.Get(sp.cfg.StackTraceAttributeKey); ok && sp.cfg.PreserveStackTrace {
+		attributes.PutStr(sp.cfg.OriginalStackTraceAttributeKey, stackTraceValue.Str())
+	}
+	attributes.PutStr(sp.cfg.StackTraceAttributeKey, strings.Join(threads, "\n\n"))
+
+	if report.exceptionType != "" {
+		attributes.PutStr(sp.cfg.ExceptionTypeAttributeKey, report.exceptionType)
+	}
+	if report.exceptionMessage != "" {
+		attributes.PutStr(sp.cfg.ExceptionMessageAttributeKey, report.exceptionMessage)
+	}
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+
+	return nil
+}
+
+// symbolicateCrashFrame symbolicates a single crash report frame. Frames whose
+// binary image isn't listed in the report can't be looked up, so they are
+// treated like frames without a dSYM.
+func (sp *symbolicatorProcessor) symbolicateCrashFrame(ctx context.Context, frame crashFrame, fetchErrorCache map[string]error) ([]*mappedDSYMStackFrame, frameStatus) {
+	if frame.binaryUUID == "" {
+		return nil, frameStatusMissingDSYM
+	}
+
+	locations, err := sp.lookupFrame(ctx, frame.binaryUUID, frame.binaryName, frame.offset, fetchErrorCache)
+	switch {
+	case err != nil:
+		sp.logger.Debug("could not symbolicate frame", zap.String("binary", frame.binaryName), zap.Error(err))
+		return nil, frameStatusFailed
+	case len(locations) == 0:
+		return nil, frameStatusMissingDSYM
+	}
+
+	return locations, frameStatusSymbolicated
+}
+
+// formatCrashFrame formats a crash report frame in the same layout as Apple's
+// crash reports. Unsymbolicated frames keep their binary and offset, in the
+// format that generic stack traces are symbolicated from.
+func formatCrashFrame(idx int, frame crashFrame, locations []*mappedDSYMStackFrame) string {
+	prefix := fmt.Sprintf("%-4d%-35s 0x%016x", idx, frame.binaryName, frame.address)
+
+	if len(locations) == 0 {
+		return fmt.Sprintf("%s %s + %d", prefix, frame.binaryName, frame.offset)
+	}
+
+	return formatStackFrames(prefix, frame.binaryName, frame.offset, locations)
+}
@@ -0,0 +1,248 @@
+package dsymprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zaptest"
+)
+
+const testKSCrashReport = `{
+  "binary_images": [
+    {"image_addr": 4357816320, "image_size": 65536, "name": "/private/var/containers/Bundle/Application/3F2C/Chateaux Bufeaux.app/Chateaux Bufeaux", "uuid": "6A8CB813-45F6-3652-AD33-778FD1EAB196", "cpu_type": 16777228, "cpu_subtype": 0},
+    {"image_addr": 6492127232, "image_size": 4096, "name": "/usr/lib/system/libsystem_kernel.dylib", "uuid": "7821F73C-378B-3A10-BE90-EF526B7DBA93", "cpu_type": 16777228, "cpu_subtype": 2147483650}
+  ],
+  "crash": {
+    "error": {
+      "type": "nsexception",
+      "reason": "index 3 beyond bounds [0 .. 2]",
+      "nsexception": {"name": "NSRangeException"}
+    },
+    "threads": [
+      {
+        "index": 0,
+        "crashed": true,
+        "dispatch_queue": "com.apple.main-thread",
+        "backtrace": {"contents": [
+          {"instruction_addr": 4357847112, "object_addr": 4357816320, "object_name": "Chateaux Bufeaux"},
+          {"instruction_addr": 6492127400, "object_addr": 6492127232, "object_name": "libsystem_kernel.dylib"},
+          {"instruction_addr": 7000000000, "object_addr": 0, "object_name": "unknown"}
+        ]}
+      },
+      {
+        "index": 1,
+        "crashed": false,
+        "backtrace": {"contents": [
+          {"instruction_addr": 6492127300, "object_addr": 6492127232, "object_name": "libsystem_kernel.dylib"}
+        ]}
+      }
+    ]
+  }
+}`
+
+const testPLCrashReport = `Incident Identifier: 7B5B5A83-4D1B-4E8F-9F9D-1B2D6E4C8A11
+Hardware Model:      iPhone14,2
+Process:         Chateaux Bufeaux [1234]
+Code Type:       ARM-64
+
+Exception Type:  SIGABRT
+Exception Codes: #0 at 0x1c0b5a1d8
+Crashed Thread:  0
+
+Application Specific Information:
+*** Terminating app due to uncaught exception 'NSRangeException', reason: 'index 3 beyond bounds [0 .. 2]'
+
+Thread 0 name:  Dispatch queue: com.apple.main-thread
+Thread 0 Crashed:
+0   Chateaux Bufeaux                    0x0000000103c07548 0x103c00000 + 30024
+1   libsystem_kernel.dylib              0x00000001832f50a8 __pthread_kill + 8
+
+Thread 1:
+0   libsystem_kernel.dylib              0x00000001832f5044 0x1832f5000 + 68
+
+Thread 0 crashed with ARM Thread State (64-bit):
+    x0: 0x0000000000000000   x1: 0x0000000000000000
+
+Binary Images:
+       0x103c00000 -        0x103c0ffff +Chateaux Bufeaux arm64  <6a8cb81345f63652ad33778fd1eab196> /private/var/containers/Bundle/Application/3F2C/Chateaux Bufeaux.app/Chateaux Bufeaux
+       0x1832f5000 -        0x1832f5fff  libsystem_kernel.dylib arm64e  <7821f73c378b3a10be90ef526b7dba93> /usr/lib/system/libsystem_kernel.dylib
+`
+
+const testPLCrashReportLastExceptionBacktrace = `Incident Identifier: 7B5B5A83-4D1B-4E8F-9F9D-1B2D6E4C8A11
+Hardware Model:      iPhone14,2
+Process:         Chateaux Bufeaux [1234]
+Code Type:       ARM-64
+
+Exception Type:  SIGABRT
+Exception Codes: #0 at 0x1c0b5a1d8
+Crashed Thread:  0
+
+Application Specific Information:
+*** Terminating app due to uncaught exception 'NSRangeException', reason: 'index 3 beyond bounds [0 .. 2]'
+
+Last Exception Backtrace:
+0   CoreFoundation                      0x00000001838a2d3c __exceptionPreprocess + 220
+1   Chateaux Bufeaux                    0x0000000103c07548 0x103c00000 + 30024
+
+Thread 0 name:  Dispatch queue: com.apple.main-thread
+Thread 0 Crashed:
+0   libsystem_kernel.dylib              0x00000001832f50a8 __pthread_kill + 8
+
+Binary Images:
+       0x103c00000 -        0x103c0ffff +Chateaux Bufeaux arm64  <6a8cb81345f63652ad33778fd1eab196> /private/var/containers/Bundle/Application/3F2C/Chateaux Bufeaux.app/Chateaux Bufeaux
+       0x1832f5000 -        0x1832f5fff  libsystem_kernel.dylib arm64e  <7821f73c378b3a10be90ef526b7dba93> /usr/lib/system/libsystem_kernel.dylib
+`
+
+func TestParseKSCrashReport(t *testing.T) {
+	report, err := parseCrashReport(testKSCrashReport)
+	require.NoError(t, err)
+
+	assert.Equal(t, "NSRangeException", report.exceptionType)
+	assert.Equal(t, "index 3 beyond bounds [0 .. 2]", report.exceptionMessage)
+	require.Len(t, report.threads, 2)
+	assert.True(t, report.threads[0].crashed)
+	assert.Equal(t, "com.apple.main-thread", report.threads[0].name)
+	assert.Equal(t, []crashFrame{
+		{binaryName: "Chateaux Bufeaux", binaryUUID: "6A8CB813-45F6-3652-AD33-778FD1EAB196", address: 4357847112, offset: 30792},
+		{binaryName: "libsystem_kernel.dylib", binaryUUID: "7821F73C-378B-3A10-BE90-EF526B7DBA93", address: 6492127400, offset: 168},
+		{binaryName: "unknown", address: 7000000000},
+	}, report.threads[0].frames)
+	assert.Equal(t, "arm64", report.images[0].arch)
+	assert.Equal(t, "arm64e", report.images[1].arch)
+}
+
+func TestParsePLCrashReport(t *testing.T) {
+	report, err := parseCrashReport(testPLCrashReport)
+	require.NoError(t, err)
+
+	assert.Equal(t, "NSRangeException", report.exceptionType)
+	assert.Equal(t, "index 3 beyond bounds [0 .. 2]", report.exceptionMessage)
+	require.Len(t, report.threads, 2)
+	assert.True(t, report.threads[0].crashed)
+	assert.Equal(t, "Dispatch queue: com.apple.main-thread", report.threads[0].name)
+	assert.False(t, report.threads[1].crashed)
+	assert.Equal(t, []crashFrame{
+		{binaryName: "Chateaux Bufeaux", binaryUUID: "6A8CB813-45F6-3652-AD33-778FD1EAB196", address: 0x103c07548, offset: 30024},
+		{binaryName: "libsystem_kernel.dylib", binaryUUID: "7821F73C-378B-3A10-BE90-EF526B7DBA93", address: 0x1832f50a8, offset: 168},
+	}, report.threads[0].frames)
+	assert.Len(t, report.threads[1].frames, 1)
+}
+
+func TestParsePLCrashReport_LastExceptionBacktrace(t *testing.T) {
+	report, err := parseCrashReport(testPLCrashReportLastExceptionBacktrace)
+	require.NoError(t, err)
+
+	require.Len(t, report.threads, 2)
+	assert.True(t, report.threads[0].lastExceptionBacktrace)
+	assert.Empty(t, report.threads[0].name)
+	assert.Equal(t, []crashFrame{
+		{binaryName: "CoreFoundation", address: 0x1838a2d3c},
+		{binaryName: "Chateaux Bufeaux", binaryUUID: "6A8CB813-45F6-3652-AD33-778FD1EAB196", address: 0x103c07548, offset: 30024},
+	}, report.threads[0].frames)
+
+	assert.False(t, report.threads[1].lastExceptionBacktrace)
+	assert.True(t, report.threads[1].crashed)
+	assert.Equal(t, "Dispatch queue: com.apple.main-thread", report.threads[1].name)
+	assert.Len(t, report.threads[1].frames, 1)
+
+	// the exception's backtrace is more useful than the runtime aborting
+	assert.Equal(t, 0, report.structuredThread())
+}
+
+func TestParseCrashReport_UnknownFormat(t *testing.T) {
+	_, err := parseCrashReport("not a crash report")
+	assert.ErrorIs(t, err, errUnknownCrashReportFormat)
+}
+
+func TestProcessCrashReport(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	log := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.CrashReportAttributeKey, testKSCrashReport)
+
+	_, err := processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	stackTrace, ok := log.Attributes().Get(cfg.StackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, `Thread 0 name:  com.apple.main-thread
+Thread 0 Crashed:
+0   Chateaux Bufeaux                    0x0000000103bf7848 main (in Chateaux Bufeaux) (MyFile.swift:1) + 30792
+1   libsystem_kernel.dylib              0x0000000182f600a8 libsystem_kernel.dylib + 168
+2   unknown                             0x00000001a13b8600 unknown + 0
+
+Thread 1:
+0   libsystem_kernel.dylib              0x0000000182f60044 libsystem_kernel.dylib + 68`, stackTrace.Str())
+
+	exceptionType, _ := log.Attributes().Get(cfg.ExceptionTypeAttributeKey)
+	assert.Equal(t, "NSRangeException", exceptionType.Str())
+	exceptionMessage, _ := log.Attributes().Get(cfg.ExceptionMessageAttributeKey)
+	assert.Equal(t, "index 3 beyond bounds [0 .. 2]", exceptionMessage.Str())
+
+	functions, _ := log.Attributes().Get(cfg.OutputStackTraceFunctionsAttributeKey)
+	assert.Equal(t, []any{"main", "", ""}, functions.Slice().AsRaw())
+	lines, _ := log.Attributes().Get(cfg.OutputStackTraceLinesAttributeKey)
+	assert.Equal(t, []any{int64(1), int64(0), int64(0)}, lines.Slice().AsRaw())
+	statuses, _ := log.Attributes().Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "missing_dsym", "missing_dsym"}, statuses.Slice().AsRaw())
+
+	failed, _ := log.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+
+	// the original report is preserved
+	_, ok = log.Attributes().Get(cfg.CrashReportAttributeKey)
+	assert.True(t, ok)
+}
+
+func TestProcessCrashReport_LastExceptionBacktrace(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	log := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.CrashReportAttributeKey, testPLCrashReportLastExceptionBacktrace)
+
+	_, err := processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	stackTrace, ok := log.Attributes().Get(cfg.StackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, `Last Exception Backtrace:
+0   CoreFoundation                      0x00000001838a2d3c CoreFoundation + 0
+1   Chateaux Bufeaux                    0x0000000103c07548 main (in Chateaux Bufeaux) (MyFile.swift:1) + 30024
+
+Thread 0 name:  Dispatch queue: com.apple.main-thread
+Thread 0 Crashed:
+0   libsystem_kernel.dylib              0x00000001832f50a8 libsystem_kernel.dylib + 168`, stackTrace.Str())
+
+	// the structured stack trace is where the exception was thrown
+	functions, _ := log.Attributes().Get(cfg.OutputStackTraceFunctionsAttributeKey)
+	assert.Equal(t, []any{"", "main"}, functions.Slice().AsRaw())
+	statuses, _ := log.Attributes().Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"missing_dsym", "symbolicated"}, statuses.Slice().AsRaw())
+}
@@ -0,0 +1,262 @@
+package dsymprocessor
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// loadCmdUUID is the Mach-O LC_UUID load command, which debug/macho does not parse.
+	loadCmdUUID macho.LoadCmd = 0x1b
+
+	// machoMagicFat64 is the magic of a fat file with 64-bit offsets, which
+	// debug/macho does not parse.
+	machoMagicFat64 = 0xcafebabf
+
+	// maxMachOFatArches is the most slices a fat file is read with. A Java
+	// class file has the same magic as a fat file, followed by its version.
+	maxMachOFatArches = 64
+
+	// maxMachOLoadCommandsSize is the largest size of the load commands read
+	// from a Mach-O header, well above those of any real binary.
+	maxMachOLoadCommandsSize = 16 << 20
+
+	// dsymZipCacheBytes is the total size of the downloaded zip archives kept in
+	// memory to serve lookups for the other UUIDs they contain. The most recent
+	// archive is always kept, however large.
+	dsymZipCacheBytes = 256 << 20
+
+	// dsymZipIndexSize is the number of UUIDs whose zip archive is remembered
+	// after the archive itself is evicted, so it can be downloaded again.
+	dsymZipIndexSize = 16384
+
+	// dsymZipMissTTL is how long a zip archive that doesn't exist is remembered,
+	// so the build UUID of every resource doesn't fetch it again.
+	dsymZipMissTTL = 5 * time.Minute
+)
+
+var errInvalidDSYMZip = errors.New("invalid dSYM zip archive")
+
+// dsymZip is a downloaded zip archive of one or more dSYM bundles, as produced
+// by Xcode Cloud, fastlane or App Store Connect. Its Mach-O files are indexed
+// by UUID so that one download serves every binary in the archive.
+type dsymZip struct {
+	files map[string]*zip.File
+	size  int64
+}
+
+// newDSYMZip indexes the DWARF files of every dSYM bundle in a zip archive by
+// their UUIDs. A fat file is indexed under the UUID of each of its slices.
+func newDSYMZip(data []byte) (*dsymZip, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidDSYMZip, err)
+	}
+
+	z := &dsymZip{files: make(map[string]*zip.File), size: int64(len(data))}
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !strings.Contains(f.Name, ".dSYM/Contents/Resources/DWARF/") {
+			continue
+		}
+
+		// only the headers are read, and a member that can't be read is
+		// skipped rather than failing the other binaries in the archive
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		uuids := machoUUIDs(rc)
+		rc.Close()
+
+		for _, uuid := range uuids {
+			z.files[uuid] = f
+		}
+	}
+
+	return z, nil
+}
+
+// uuids returns every UUID in the archive.
+func (z *dsymZip) uuids() []string {
+	uuids := make([]string, 0, len(z.files))
+	for uuid := range z.files {
+		uuids = append(uuids, uuid)
+	}
+	return uuids
+}
+
+// get returns the DWARF file of the binary with the given UUID and name.
+func (z *dsymZip) get(debugId, binaryName string) ([]byte, bool) {
+	f, ok := z.files[strings.ToUpper(debugId)]
+	if !ok || path.Base(f.Name) != binaryName {
+		return nil, false
+	}
+
+	contents, err := readZipFile(f)
+	if err != nil {
+		return nil, false
+	}
+
+	return contents, true
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// machoUUIDs reads the uppercase UUIDs of a thin or fat Mach-O file, or none
+// if the file is not Mach-O or can't be read. Only the header and load
+// commands of each slice are read, and the rest of the file is skipped, so a
+// zip member isn't decompressed into memory to find them.
+func machoUUIDs(r io.Reader) []string {
+	mr := &machoReader{r: r}
+	magic, err := mr.read(4)
+	if err != nil {
+		return nil
+	}
+
+	switch fatMagic := binary.BigEndian.Uint32(magic); fatMagic {
+	case macho.MagicFat, machoMagicFat64:
+		return fatMachOUUIDs(mr, fatMagic == machoMagicFat64)
+	}
+
+	if uuid, ok := thinMachOUUID(mr, magic); ok {
+		return []string{uuid}
+	}
+	return nil
+}
+
+// fatMachOUUIDs reads the UUID of each slice of a fat file, in the order of
+// their offsets, stopping at the first slice that can't be read.
+func fatMachOUUIDs(mr *machoReader, is64 bool) []string {
+	header, err := mr.read(4)
+	if err != nil {
+		return nil
+	}
+	nArches := binary.BigEndian.Uint32(header)
+	if nArches == 0 || nArches > maxMachOFatArches {
+		return nil
+	}
+
+	archSize := 20
+	if is64 {
+		archSize = 32
+	}
+
+	offsets := make([]int64, 0, nArches)
+	for range nArches {
+		arch, err := mr.read(archSize)
+		if err != nil {
+			return nil
+		}
+		if is64 {
+			offsets = append(offsets, int64(binary.BigEndian.Uint64(arch[8:16])))
+		} else {
+			offsets = append(offsets, int64(binary.BigEndian.Uint32(arch[8:12])))
+		}
+	}
+	slices.Sort(offsets)
+
+	uuids := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		if err := mr.skipTo(offset); err != nil {
+			break
+		}
+		magic, err := mr.read(4)
+		if err != nil {
+			break
+		}
+		if uuid, ok := thinMachOUUID(mr, magic); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids
+}
+
+// thinMachOUUID reads the header and load commands of a thin Mach-O file, or
+// a slice of a fat file, after its magic, and returns its LC_UUID.
+func thinMachOUUID(mr *machoReader, magic []byte) (string, bool) {
+	var order binary.ByteOrder
+	var headerSize int
+	for _, o := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch o.Uint32(magic) {
+		case macho.Magic32:
+			order, headerSize = o, 28
+		case macho.Magic64:
+			order, headerSize = o, 32
+		}
+	}
+	if order == nil {
+		return "", false
+	}
+
+	header, err := mr.read(headerSize - 4)
+	if err != nil {
+		return "", false
+	}
+	nCmds := order.Uint32(header[12:16])
+	sizeOfCmds := order.Uint32(header[16:20])
+	if sizeOfCmds > maxMachOLoadCommandsSize {
+		return "", false
+	}
+
+	cmds, err := mr.read(int(sizeOfCmds))
+	if err != nil {
+		return "", false
+	}
+
+	for offset := 0; nCmds > 0 && offset+8 <= len(cmds); nCmds-- {
+		cmd := macho.LoadCmd(order.Uint32(cmds[offset : offset+4]))
+		size := int(order.Uint32(cmds[offset+4 : offset+8]))
+		if size < 8 || offset+size > len(cmds) {
+			break
+		}
+		if cmd == loadCmdUUID && size >= 24 {
+			u := cmds[offset+8 : offset+24]
+			return fmt.Sprintf("%X-%X-%X-%X-%X", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), true
+		}
+		offset += size
+	}
+	return "", false
+}
+
+// machoReader reads a Mach-O file from a stream, keeping track of its offset
+// so the slices of a fat file can be skipped to.
+type machoReader struct {
+	r      io.Reader
+	offset int64
+}
+
+func (mr *machoReader) read(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := io.ReadFull(mr.r, buf)
+	mr.offset += int64(read)
+	return buf, err
+}
+
+// skipTo discards the stream up to an offset, which must not be behind the
+// current one.
+func (mr *machoReader) skipTo(offset int64) error {
+	if offset < mr.offset {
+		return fmt.Errorf("mach-o slice at %d overlaps the one before it", offset)
+	}
+	skipped, err := io.CopyN(io.Discard, mr.r, offset-mr.offset)
+	mr.offset += skipped
+	return err
+}
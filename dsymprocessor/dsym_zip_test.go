@@ -0,0 +1,236 @@
+package dsymprocessor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zaptest"
+)
+
+// testMachO returns a minimal 64-bit Mach-O dSYM with just an LC_UUID load command.
+func testMachO(uuid [16]byte) []byte {
+	buf := new(bytes.Buffer)
+	for _, v := range []uint32{0xfeedfacf, 0x0100000c, 0, 0xa, 1, 24, 0, 0} {
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+	_ = binary.Write(buf, binary.LittleEndian, uint32(loadCmdUUID))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(24))
+	buf.Write(uuid[:])
+	return buf.Bytes()
+}
+
+func testDSYMZip(t *testing.T, files map[string][]byte) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, contents := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write(contents)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestMachOUUIDs(t *testing.T) {
+	uuid := [16]byte{0x6a, 0x8c, 0xb8, 0x13, 0x45, 0xf6, 0x36, 0x52, 0xad, 0x33, 0x77, 0x8f, 0xd1, 0xea, 0xb1, 0x96}
+
+	assert.Equal(t, []string{"6A8CB813-45F6-3652-AD33-778FD1EAB196"}, machoUUIDs(bytes.NewReader(testMachO(uuid))))
+	assert.Empty(t, machoUUIDs(bytes.NewReader([]byte("not a mach-o file"))))
+
+	// a truncated header
+	assert.Empty(t, machoUUIDs(bytes.NewReader(testMachO(uuid)[:40])))
+}
+
+func TestMachOUUIDsFat(t *testing.T) {
+	arm64 := testMachO([16]byte{0x6a, 0x8c, 0xb8, 0x13, 0x45, 0xf6, 0x36, 0x52, 0xad, 0x33, 0x77, 0x8f, 0xd1, 0xea, 0xb1, 0x96})
+	x86 := testMachO([16]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00})
+
+	// the arches aren't in the order of their offsets
+	buf := new(bytes.Buffer)
+	for _, v := range []uint32{
+		0xcafebabe, 2,
+		0x0100000c, 0, 0x2000, uint32(len(arm64)), 12,
+		0x01000007, 3, 0x1000, uint32(len(x86)), 12,
+	} {
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+	buf.Write(make([]byte, 0x1000-buf.Len()))
+	buf.Write(x86)
+	buf.Write(make([]byte, 0x2000-buf.Len()))
+	buf.Write(arm64)
+
+	assert.Equal(t, []string{"11223344-5566-7788-99AA-BBCCDDEEFF00", "6A8CB813-45F6-3652-AD33-778FD1EAB196"}, machoUUIDs(bytes.NewReader(buf.Bytes())))
+
+	// a slice past the end of the file is skipped
+	assert.Equal(t, []string{"11223344-5566-7788-99AA-BBCCDDEEFF00"}, machoUUIDs(bytes.NewReader(buf.Bytes()[:0x1800])))
+
+	// a Java class file has the same magic
+	assert.Empty(t, machoUUIDs(bytes.NewReader([]byte{0xca, 0xfe, 0xba, 0xbe, 0x00, 0x00, 0x00, 0x41})))
+}
+
+func TestNewDSYMZipSkipsUnreadableMembers(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+
+	f, err := w.Create("App.dSYM/Contents/Resources/DWARF/App")
+	require.NoError(t, err)
+	_, err = f.Write(testMachO([16]byte{0x6a, 0x8c, 0xb8, 0x13, 0x45, 0xf6, 0x36, 0x52, 0xad, 0x33, 0x77, 0x8f, 0xd1, 0xea, 0xb1, 0x96}))
+	require.NoError(t, err)
+
+	// compressed with a method that can't be decompressed
+	f, err = w.CreateRaw(&zip.FileHeader{Name: "Unknown.dSYM/Contents/Resources/DWARF/Unknown", Method: 99})
+	require.NoError(t, err)
+	_, err = f.Write([]byte("compressed"))
+	require.NoError(t, err)
+
+	// corrupt deflate data
+	f, err = w.CreateRaw(&zip.FileHeader{Name: "Corrupt.dSYM/Contents/Resources/DWARF/Corrupt", Method: zip.Deflate})
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Close())
+
+	z, err := newDSYMZip(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"6A8CB813-45F6-3652-AD33-778FD1EAB196"}, z.uuids())
+}
+
+func TestFileStoreDSYMZip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	app := testMachO([16]byte{0x6a, 0x8c, 0xb8, 0x13, 0x45, 0xf6, 0x36, 0x52, 0xad, 0x33, 0x77, 0x8f, 0xd1, 0xea, 0xb1, 0x96})
+	framework := testMachO([16]byte{0x11, 0x11, 0x11, 0x11, 0x22, 0x22, 0x33, 0x33, 0x44, 0x44, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55})
+
+	zipPath := filepath.Join(dir, "6A8CB813-45F6-3652-AD33-778FD1EAB196.dSYM.zip")
+	require.NoError(t, os.WriteFile(zipPath, testDSYMZip(t, map[string][]byte{
+		"dSYMs/Chateaux Bufeaux.app.dSYM/Contents/Resources/DWARF/Chateaux Bufeaux": app,
+		"dSYMs/Kit.framework.dSYM/Contents/Resources/DWARF/Kit":                     framework,
+		"dSYMs/Kit.framework.dSYM/Contents/Info.plist":                              []byte("<plist/>"),
+	}), 0o600))
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDSYMConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	source, err := fs.GetDSYM(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196", "Chateaux Bufeaux")
+	assert.NoError(t, err)
+	assert.Equal(t, app, source)
+
+	// the framework is served from the archive that was already downloaded
+	require.NoError(t, os.Remove(zipPath))
+
+	source, err = fs.GetDSYM(ctx, "11111111-2222-3333-4444-555555555555", "Kit")
+	assert.NoError(t, err)
+	assert.Equal(t, framework, source)
+
+	_, err = fs.GetDSYM(ctx, "11111111-2222-3333-4444-555555555555", "Not A Binary")
+	assert.ErrorIs(t, err, errFailedToFindDSYM)
+}
+
+// storeSymbolicator symbolicates every address of a binary it can fetch from a
+// store to main, so that tests can tell which binaries were found.
+type storeSymbolicator struct {
+	store *store
+}
+
+func (ss *storeSymbolicator) indexBuild(ctx context.Context, debugId string) {
+	ss.store.IndexDSYMZip(ctx, debugId)
+}
+
+func (ss *storeSymbolicator) symbolicateFrame(ctx context.Context, debugId, binaryName string, addr uint64) ([]*mappedDSYMStackFrame, error) {
+	if _, err := ss.store.GetDSYM(ctx, debugId, binaryName); err != nil {
+		return nil, &FetchError{DebugID: debugId, Err: err}
+	}
+	return []*mappedDSYMStackFrame{{path: "MyFile.swift", line: 1, symbol: "main"}}, nil
+}
+
+func TestFileStoreDSYMZip_FrameworkFirst(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	app := testMachO([16]byte{0x6a, 0x8c, 0xb8, 0x13, 0x45, 0xf6, 0x36, 0x52, 0xad, 0x33, 0x77, 0x8f, 0xd1, 0xea, 0xb1, 0x96})
+	framework := testMachO([16]byte{0x11, 0x11, 0x11, 0x11, 0x22, 0x22, 0x33, 0x33, 0x44, 0x44, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55})
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "6A8CB813-45F6-3652-AD33-778FD1EAB196.dSYM.zip"), testDSYMZip(t, map[string][]byte{
+		"dSYMs/Chateaux Bufeaux.app.dSYM/Contents/Resources/DWARF/Chateaux Bufeaux": app,
+		"dSYMs/Kit.framework.dSYM/Contents/Resources/DWARF/Kit":                     framework,
+	}), 0o600))
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDSYMConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	cfg := createDefaultConfig().(*Config)
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &storeSymbolicator{store: fs}, tb, attributes)
+
+	logs := plog.NewLogs()
+	resourceLog := logs.ResourceLogs().AppendEmpty()
+	resourceLog.Resource().Attributes().PutStr(cfg.BuildUUIDAttributeKey, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	resourceLog.Resource().Attributes().PutStr(cfg.AppExecutableAttributeKey, "Chateaux Bufeaux")
+
+	// the framework frame is looked up before the app's
+	log := resourceLog.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.StackTraceAttributeKey, `0   Kit                                 0x00000001025a0758 11111111-2222-3333-4444-555555555555 + 231256
+1   Chateaux Bufeaux                    0x0000000102577fd1 Chateaux Bufeaux + 65489`)
+
+	_, err = processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	symbolicated, _ := log.Attributes().Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `0   Kit                                 0x00000001025a0758 main (in Kit) (MyFile.swift:1) + 231256
+1   Chateaux Bufeaux                    0x0000000102577fd1 main (in Chateaux Bufeaux) (MyFile.swift:1) + 65489`, symbolicated.Str())
+}
+
+func TestStoreDSYMZipIndexOutlivesArchive(t *testing.T) {
+	ctx := context.Background()
+	framework := testMachO([16]byte{0x11, 0x11, 0x11, 0x11, 0x22, 0x22, 0x33, 0x33, 0x44, 0x44, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55})
+	zipBytes := testDSYMZip(t, map[string][]byte{
+		"dSYMs/Kit.framework.dSYM/Contents/Resources/DWARF/Kit": framework,
+	})
+
+	fetches := map[string]int{}
+	s := newStore(zaptest.NewLogger(t), "", func(_ context.Context, key string) ([]byte, error) {
+		fetches[key]++
+		if key == "6A8CB813-45F6-3652-AD33-778FD1EAB196.dSYM.zip" {
+			return zipBytes, nil
+		}
+		return nil, os.ErrNotExist
+	})
+
+	s.IndexDSYMZip(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	s.IndexDSYMZip(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.Equal(t, 1, fetches["6A8CB813-45F6-3652-AD33-778FD1EAB196.dSYM.zip"])
+	assert.Equal(t, int64(len(zipBytes)), s.zipBytes)
+
+	// the archive is downloaded again once evicted
+	s.zips.Purge()
+	assert.Zero(t, s.zipBytes)
+
+	source, err := s.GetDSYM(ctx, "11111111-2222-3333-4444-555555555555", "Kit")
+	assert.NoError(t, err)
+	assert.Equal(t, framework, source)
+	assert.Equal(t, 2, fetches["6A8CB813-45F6-3652-AD33-778FD1EAB196.dSYM.zip"])
+
+	// archives that don't exist aren't fetched again
+	s.IndexDSYMZip(ctx, "99999999-2222-3333-4444-555555555555")
+	s.IndexDSYMZip(ctx, "99999999-2222-3333-4444-555555555555")
+	assert.Equal(t, 1, fetches["99999999-2222-3333-4444-555555555555.dSYM.zip"])
+}
@@ -23,18 +23,35 @@ const (
 // createDefaultConfig creates the default configuration for the processor.
 func createDefaultConfig() component.Config {
 	return &Config{
-		SymbolicatorFailureAttributeKey:             "exception.symbolicator.failed",
-		SymbolicatorErrorAttributeKey:               "exception.symbolicator.error",
-		StackTraceAttributeKey:                      "exception.stacktrace",
-		MetricKitStackTraceAttributeKey:             "metrickit.diagnostic.crash.exception.stacktrace_json",
-		OutputMetricKitStackTraceAttributeKey:       "exception.stacktrace",
-		OutputMetricKitExceptionTypeAttributeKey:    "exception.type",
-		OutputMetricKitExceptionMessageAttributeKey: "exception.message",
-		PreserveStackTrace:                          true,
-		OriginalStackTraceAttributeKey:              "exception.stacktrace.original",
-		BuildUUIDAttributeKey:                       "app.debug.build_uuid",
-		AppExecutableAttributeKey:                   "app.bundle.executable",
-		DSYMStoreKey:                                "file_store",
+		SymbolicatorFailureAttributeKey:               "exception.symbolicator.failed",
+		SymbolicatorErrorAttributeKey:                 "exception.symbolicator.error",
+		StackTraceAttributeKey:                        "exception.stacktrace",
+		MetricKitStackTraceAttributeKey:               "metrickit.diagnostic.crash.exception.stacktrace_json",
+		OutputMetricKitStackTraceAttributeKey:         "exception.stacktrace",
+		OutputMetricKitExceptionTypeAttributeKey:      "exception.type",
+		OutputMetricKitExceptionMessageAttributeKey:   "exception.message",
+		OutputMetricKitFoldedStacksAttributeKey:       "metrickit.diagnostic.callstack.folded",
+		OutputMetricKitHeaviestStackTraceAttributeKey: "metrickit.diagnostic.callstack.heaviest_stacktrace",
+		OutputMetricKitFrameStatusesAttributeKey:      "metrickit.diagnostic.callstack.frame_statuses",
+		CrashReportAttributeKey:                       "exception.crash_report",
+		ReturnAddressesAttributeKey:                   "exception.return_addresses",
+		BinaryImagesAttributeKey:                      "app.binary_images",
+		ExceptionTypeAttributeKey:                     "exception.type",
+		ExceptionMessageAttributeKey:                  "exception.message",
+		OutputStackTraceBinariesAttributeKey:          "exception.structured_stacktrace.binaries",
+		OutputStackTraceFunctionsAttributeKey:         "exception.structured_stacktrace.functions",
+		OutputStackTraceFilesAttributeKey:             "exception.structured_stacktrace.files",
+		OutputStackTraceLinesAttributeKey:             "exception.structured_stacktrace.lines",
+		OutputStackTraceFrameStatusesAttributeKey:     "exception.structured_stacktrace.frame_statuses",
+		OutputStackTraceInlineDepthsAttributeKey:      "exception.structured_stacktrace.inline_depths",
+		OutputStackTraceInlineParentsAttributeKey:     "exception.structured_stacktrace.inline_parents",
+		PreserveStackTrace:                            true,
+		OriginalStackTraceAttributeKey:                "exception.stacktrace.original",
+		BuildUUIDAttributeKey:                         "app.debug.build_uuid",
+		AppExecutableAttributeKey:                     "app.bundle.executable",
+		DSYMStoreKey:                                  "file_store",
+		DSYMStoreLayouts:                              []string{storeLayoutDSYM},
+		IL2CPPLineMappings:                            false,
 		LocalDSYMConfiguration: &LocalDSYMConfiguration{
 			Path: ".",
 		},
@@ -48,32 +65,37 @@ func createDefaultConfig() component.Config {
 // createLogsProcessor creates a logs processor
 func createLogsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Logs) (processor.Logs, error) {
 	symCfg := cfg.(*Config)
-	var store dsymStore
+	var s *store
 	var err error
 
 	switch symCfg.DSYMStoreKey {
 	case "file_store":
-		store, err = newFileStore(ctx, set.Logger, symCfg.LocalDSYMConfiguration)
+		s, err = newFileStore(ctx, set.Logger, symCfg.LocalDSYMConfiguration)
 	case "s3_store":
-		store, err = newS3Store(ctx, set.Logger, symCfg.S3DSYMConfiguration)
+		s, err = newS3Store(ctx, set.Logger, symCfg.S3DSYMConfiguration)
 	case "gcs_store":
-		store, err = newGCSStore(ctx, set.Logger, symCfg.GCSDSYMConfiguration)
+		s, err = newGCSStore(ctx, set.Logger, symCfg.GCSDSYMConfiguration)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	if s != nil && len(symCfg.DSYMStoreLayouts) > 0 {
+		s.layouts = symCfg.DSYMStoreLayouts
+	}
+
 	tb, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
 	if err != nil {
 		return nil, err
 	}
 	// Set up resource attributes for telemetry
 	attributeSet := setUpResourceAttributes()
-	sym, err := newBasicSymbolicator(ctx, symCfg.Timeout, symCfg.DSYMCacheSize, store, tb, attributeSet)
+	sym, err := newBasicSymbolicator(ctx, symCfg.Timeout, symCfg.DSYMCacheSize, s, tb, attributeSet)
 	if err != nil {
 		return nil, err
 	}
+	sym.il2cppLineMappings = symCfg.IL2CPPLineMappings
 
 	processor := newSymbolicatorProcessor(ctx, symCfg, set, sym, tb, attributeSet)
 	return processorhelper.NewLogs(ctx, set, cfg, next, processor.processLogs, processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
@@ -0,0 +1,154 @@
+package dsymprocessor
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// il2cppMethodRegex matches the names IL2CPP gives the C++ functions it generates
+// for managed methods, eg. Player_Update_m3A9F6D2C. The class and method are
+// separated by the last underscore before the method hash, except for accessors
+// and constructors, whose prefix and leading dot become underscores, eg.
+// Player_get_Health_m3A9F6D2C and Player__ctor_m3A9F6D2C. Shared generic
+// methods also end in _gshared.
+// groups: class, accessor prefix, underscore of a constructor, method
+var il2cppMethodRegex = regexp.MustCompile(`^(.+?)(?:_(get_|set_|add_|remove_)|_(_)|_)([^_]+)_m[\dA-Fa-f]+(?:_gshared)?$`)
+
+// il2cppLineMappings maps the lines of the C++ files generated by IL2CPP back to
+// the C# lines they were generated from, as written to LineNumberMappings.json
+// by Unity.
+type il2cppLineMappings struct {
+	// files is keyed by both the full path and the base name of each C++ file, as
+	// the build machine's paths in the dSYM may not match the mapping file exactly.
+	files map[string][]il2cppLine
+}
+
+// il2cppLine is a C++ line that was generated from a C# line.
+type il2cppLine struct {
+	cppLine uint32
+	csFile  string
+	csLine  uint32
+}
+
+// parseIL2CPPLineMappings parses Unity's LineNumberMappings.json, which is
+// keyed by C++ file, then C# file, then C++ line, eg.
+// {"Bulk_Assembly-CSharp_0.cpp": {"Assets/Scripts/Player.cs": {"1042": 17}}}.
+func parseIL2CPPLineMappings(data []byte) (*il2cppLineMappings, error) {
+	var raw map[string]map[string]map[string]uint32
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	m := &il2cppLineMappings{files: make(map[string][]il2cppLine, len(raw)*2)}
+	for cppFile, csFiles := range raw {
+		lines := make([]il2cppLine, 0)
+		for csFile, csLines := range csFiles {
+			for cppLine, csLine := range csLines {
+				line, err := strconv.ParseUint(cppLine, 10, 32)
+				if err != nil {
+					continue
+				}
+				lines = append(lines, il2cppLine{cppLine: uint32(line), csFile: csFile, csLine: csLine})
+			}
+		}
+
+		sort.Slice(lines, func(i, j int) bool {
+			return lines[i].cppLine < lines[j].cppLine
+		})
+
+		m.files[cppFile] = lines
+		if _, ok := m.files[path.Base(cppFile)]; !ok {
+			m.files[path.Base(cppFile)] = lines
+		}
+	}
+
+	return m, nil
+}
+
+// lookup returns the C# file and line that a C++ line was generated from. IL2CPP
+// only maps the first line of the code generated for each C# line, so the
+// closest mapped line at or before the C++ line is used.
+func (m *il2cppLineMappings) lookup(cppFile string, cppLine uint32) (string, uint32, bool) {
+	lines, ok := m.files[cppFile]
+	if !ok {
+		lines, ok = m.files[path.Base(cppFile)]
+	}
+	if !ok {
+		return "", 0, false
+	}
+
+	idx := sort.Search(len(lines), func(i int) bool {
+		return lines[i].cppLine > cppLine
+	}) - 1
+	if idx < 0 {
+		return "", 0, false
+	}
+
+	return lines[idx].csFile, lines[idx].csLine, true
+}
+
+// resolve rewrites a location in IL2CPP generated C++ to the C# method, file
+// and line it was generated from. Only the functions generated for managed
+// methods are mapped, as lookup would otherwise attribute the helpers and
+// runtime code that follow them in the same file to their last C# line.
+// Locations that aren't mapped are left as-is.
+func (m *il2cppLineMappings) resolve(frame *mappedDSYMStackFrame) {
+	method, ok := il2cppManagedMethodName(frame.symbol)
+	if !ok {
+		return
+	}
+
+	csFile, csLine, ok := m.lookup(frame.path, frame.line)
+	if !ok {
+		return
+	}
+
+	frame.path = csFile
+	frame.line = csLine
+	frame.symbol = method
+}
+
+// il2cppManagedMethodName turns the name of a generated C++ function into the
+// C# method it was generated from, eg. Player_Update_m3A9F6D2C into
+// Player.Update. It reports false for names that don't look generated.
+func il2cppManagedMethodName(symbol string) (string, bool) {
+	name := symbol
+	// demangled names include the C++ parameter list
+	if idx := strings.Index(name, "("); idx > 0 {
+		name = name[:idx]
+	}
+
+	matches := il2cppMethodRegex.FindStringSubmatch(name)
+	if matches == nil {
+		return "", false
+	}
+
+	class, accessor, method := matches[1], matches[2], matches[4]
+	switch {
+	case accessor != "":
+		// property and event accessors keep their prefix, as in C# stack traces
+		method = accessor + method
+	case matches[3] != "" && (method == "ctor" || method == "cctor"):
+		method = "." + method
+	case matches[3] != "":
+		// a method whose name starts with an underscore
+		method = "_" + method
+	}
+
+	return class + "." + method, true
+}
+
+// hasIL2CPPFrames reports whether any location is in a C++ file, which the
+// generated code of an IL2CPP build is.
+func hasIL2CPPFrames(frames []*mappedDSYMStackFrame) bool {
+	for _, frame := range frames {
+		if strings.HasSuffix(frame.path, ".cpp") {
+			return true
+		}
+	}
+	return false
+}
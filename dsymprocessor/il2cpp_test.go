@@ -0,0 +1,161 @@
+package dsymprocessor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const testIL2CPPLineMappings = `{
+	"/Users/me/Game/Library/Il2cppBuildCache/iOS/il2cppOutput/Bulk_Assembly-CSharp_0.cpp": {
+		"/Users/me/Game/Assets/Scripts/Player.cs": {"1042": 17, "1050": 18},
+		"/Users/me/Game/Assets/Scripts/Enemy.cs": {"2210": 40}
+	}
+}`
+
+func TestIL2CPPLineMappings(t *testing.T) {
+	mappings, err := parseIL2CPPLineMappings([]byte(testIL2CPPLineMappings))
+	require.NoError(t, err)
+
+	cppFile := "/Users/me/Game/Library/Il2cppBuildCache/iOS/il2cppOutput/Bulk_Assembly-CSharp_0.cpp"
+
+	csFile, csLine, ok := mappings.lookup(cppFile, 1042)
+	assert.True(t, ok)
+	assert.Equal(t, "/Users/me/Game/Assets/Scripts/Player.cs", csFile)
+	assert.Equal(t, uint32(17), csLine)
+
+	// lines between mappings belong to the closest mapping before them
+	csFile, csLine, ok = mappings.lookup(cppFile, 1055)
+	assert.True(t, ok)
+	assert.Equal(t, "/Users/me/Game/Assets/Scripts/Player.cs", csFile)
+	assert.Equal(t, uint32(18), csLine)
+
+	// built on another machine
+	csFile, csLine, ok = mappings.lookup("/private/var/build/il2cppOutput/Bulk_Assembly-CSharp_0.cpp", 2211)
+	assert.True(t, ok)
+	assert.Equal(t, "/Users/me/Game/Assets/Scripts/Enemy.cs", csFile)
+	assert.Equal(t, uint32(40), csLine)
+
+	_, _, ok = mappings.lookup(cppFile, 12)
+	assert.False(t, ok)
+
+	_, _, ok = mappings.lookup("/Users/me/Game/main.mm", 1042)
+	assert.False(t, ok)
+
+	_, err = parseIL2CPPLineMappings([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestIL2CPPResolve(t *testing.T) {
+	mappings, err := parseIL2CPPLineMappings([]byte(testIL2CPPLineMappings))
+	require.NoError(t, err)
+
+	frame := &mappedDSYMStackFrame{
+		path:   "/Users/me/Game/Library/Il2cppBuildCache/iOS/il2cppOutput/Bulk_Assembly-CSharp_0.cpp",
+		line:   1046,
+		symbol: "Player_Update_m3A9F6D2C4B8E1F0A7D5C3B2A1908F7E6D5C4B3A2(Player_t*, MethodInfo const*)",
+	}
+	mappings.resolve(frame)
+
+	assert.Equal(t, "/Users/me/Game/Assets/Scripts/Player.cs", frame.path)
+	assert.Equal(t, uint32(17), frame.line)
+	assert.Equal(t, "Player.Update", frame.symbol)
+
+	native := &mappedDSYMStackFrame{path: "/Users/me/Game/main.mm", line: 12, symbol: "main"}
+	mappings.resolve(native)
+
+	assert.Equal(t, "/Users/me/Game/main.mm", native.path)
+	assert.Equal(t, uint32(12), native.line)
+	assert.Equal(t, "main", native.symbol)
+
+	// a helper generated after the mapped lines isn't part of the last C# line
+	helper := &mappedDSYMStackFrame{
+		path:   "/Users/me/Game/Library/Il2cppBuildCache/iOS/il2cppOutput/Bulk_Assembly-CSharp_0.cpp",
+		line:   1080,
+		symbol: "il2cpp_codegen_raise_null_reference_exception()",
+	}
+	mappings.resolve(helper)
+
+	assert.Equal(t, "/Users/me/Game/Library/Il2cppBuildCache/iOS/il2cppOutput/Bulk_Assembly-CSharp_0.cpp", helper.path)
+	assert.Equal(t, uint32(1080), helper.line)
+	assert.Equal(t, "il2cpp_codegen_raise_null_reference_exception()", helper.symbol)
+}
+
+func TestIL2CPPManagedMethodName(t *testing.T) {
+	for symbol, expected := range map[string]string{
+		"Player_Update_m3A9F6D2C":                                        "Player.Update",
+		"List_1_Add_mE1D2C3B4_gshared":                                   "List_1.Add",
+		"GameManager_Start_m12345678(GameManager_t*, MethodInfo const*)": "GameManager.Start",
+		"Player_get_Health_m3A9F6D2C":                                    "Player.get_Health",
+		"Player_set_Health_m3A9F6D2C":                                    "Player.set_Health",
+		"Player_add_OnDied_m3A9F6D2C":                                    "Player.add_OnDied",
+		"Player_remove_OnDied_m3A9F6D2C":                                 "Player.remove_OnDied",
+		"Player__ctor_m3A9F6D2C":                                         "Player..ctor",
+		"Player__cctor_m3A9F6D2C":                                        "Player..cctor",
+		"Game_Manager_get_Instance_m3A9F6D2C":                            "Game_Manager.get_Instance",
+		"Dictionary_2__ctor_mE1D2C3B4_gshared":                           "Dictionary_2..ctor",
+	} {
+		method, ok := il2cppManagedMethodName(symbol)
+		assert.True(t, ok)
+		assert.Equal(t, expected, method)
+	}
+
+	_, ok := il2cppManagedMethodName("il2cpp_codegen_raise_exception")
+	assert.False(t, ok)
+}
+
+func TestFileStoreIL2CPPLineMappings(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "6A8CB813-45F6-3652-AD33-778FD1EAB196"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "6A8CB813-45F6-3652-AD33-778FD1EAB196", "LineNumberMappings.json"), []byte(testIL2CPPLineMappings), 0o600))
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDSYMConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	source, err := fs.GetIL2CPPLineMappings(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+
+	_, err = fs.GetIL2CPPLineMappings(ctx, "99999999-2222-3333-4444-555555555555")
+	assert.ErrorIs(t, err, errFailedToFindIL2CPPLines)
+}
+
+func TestFileStoreIL2CPPLineMappingsLayouts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	symsorter := filepath.Join(dir, "6a", "8cb81345f63652ad33778fd1eab196")
+	require.NoError(t, os.MkdirAll(symsorter, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(symsorter, "il2cpp"), []byte(testIL2CPPLineMappings), 0o600))
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDSYMConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	// the line mappings are only looked up in the configured layouts
+	_, err = fs.GetIL2CPPLineMappings(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.ErrorIs(t, err, errFailedToFindIL2CPPLines)
+
+	fs.layouts = []string{storeLayoutBreakpad, storeLayoutSymsorter}
+	source, err := fs.GetIL2CPPLineMappings(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+}
+
+func TestStoreIL2CPPLineMappingsFetchError(t *testing.T) {
+	s := newStore(zaptest.NewLogger(t), "", func(ctx context.Context, key string) ([]byte, error) {
+		return nil, errors.New("access denied")
+	})
+
+	// an error other than the line mappings not existing is a fetch failure
+	_, err := s.GetIL2CPPLineMappings(context.Background(), "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errFailedToFindIL2CPPLines)
+}
@@ -0,0 +1,161 @@
+package dsymprocessor
+
+import (
+	"encoding/json"
+)
+
+// ksCrashReport is the subset of a KSCrash JSON crash report that is needed to
+// symbolicate it.
+type ksCrashReport struct {
+	BinaryImages []ksCrashBinaryImage `json:"binary_images"`
+	Crash        struct {
+		Error   ksCrashError    `json:"error"`
+		Threads []ksCrashThread `json:"threads"`
+	} `json:"crash"`
+}
+
+type ksCrashBinaryImage struct {
+	ImageAddr  uint64 `json:"image_addr"`
+	ImageSize  uint64 `json:"image_size"`
+	Name       string `json:"name"`
+	UUID       string `json:"uuid"`
+	CPUType    int64  `json:"cpu_type"`
+	CPUSubtype int64  `json:"cpu_subtype"`
+}
+
+const (
+	// the Mach-O CPU types and subtypes KSCrash reports images with
+	ksCrashCPUTypeX86_64    = 0x01000007
+	ksCrashCPUTypeARM64     = 0x0100000C
+	ksCrashCPUSubtypeARM64E = 2
+)
+
+// arch returns the architecture of an image, or empty if it isn't known.
+func (image ksCrashBinaryImage) arch() string {
+	switch image.CPUType {
+	case ksCrashCPUTypeX86_64:
+		return "x86_64"
+	case ksCrashCPUTypeARM64:
+		// the upper bits of the subtype are capability flags
+		if image.CPUSubtype&0xFFFFFF == ksCrashCPUSubtypeARM64E {
+			return "arm64e"
+		}
+		return "arm64"
+	}
+	return ""
+}
+
+type ksCrashError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+
+	NSException *struct {
+		Name   string `json:"name"`
+		Reason string `json:"reason"`
+	} `json:"nsexception"`
+
+	CPPException *struct {
+		Name string `json:"name"`
+	} `json:"cpp_exception"`
+
+	Mach *struct {
+		ExceptionName string `json:"exception_name"`
+		CodeName      string `json:"code_name"`
+	} `json:"mach"`
+
+	Signal *struct {
+		Name     string `json:"name"`
+		CodeName string `json:"code_name"`
+	} `json:"signal"`
+}
+
+type ksCrashThread struct {
+	Index         int    `json:"index"`
+	Name          string `json:"name"`
+	DispatchQueue string `json:"dispatch_queue"`
+	Crashed       bool   `json:"crashed"`
+	Backtrace     *struct {
+		Contents []ksCrashFrame `json:"contents"`
+	} `json:"backtrace"`
+}
+
+type ksCrashFrame struct {
+	InstructionAddr uint64 `json:"instruction_addr"`
+	ObjectAddr      uint64 `json:"object_addr"`
+	ObjectName      string `json:"object_name"`
+}
+
+// parseKSCrashReport parses a KSCrash JSON crash report.
+func parseKSCrashReport(data []byte) (*crashReport, error) {
+	var raw ksCrashReport
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	images := make([]binaryImage, len(raw.BinaryImages))
+	for i, image := range raw.BinaryImages {
+		images[i] = binaryImage{
+			name:        image.Name,
+			uuid:        image.UUID,
+			loadAddress: image.ImageAddr,
+			size:        image.ImageSize,
+			arch:        image.arch(),
+		}
+	}
+
+	report := &crashReport{
+		exceptionType:    raw.Crash.Error.exceptionType(),
+		exceptionMessage: raw.Crash.Error.exceptionMessage(),
+		images:           newBinaryImages(images),
+		threads:          make([]crashThread, 0, len(raw.Crash.Threads)),
+	}
+
+	for _, rawThread := range raw.Crash.Threads {
+		thread := crashThread{
+			index:   rawThread.Index,
+			name:    rawThread.Name,
+			crashed: rawThread.Crashed,
+		}
+		if thread.name == "" {
+			thread.name = rawThread.DispatchQueue
+		}
+
+		if rawThread.Backtrace != nil {
+			for _, rawFrame := range rawThread.Backtrace.Contents {
+				thread.frames = append(thread.frames, report.frameAt(rawFrame.InstructionAddr, rawFrame.ObjectName, rawFrame.ObjectAddr))
+			}
+		}
+
+		report.threads = append(report.threads, thread)
+	}
+
+	return report, nil
+}
+
+func (e ksCrashError) exceptionType() string {
+	switch {
+	case e.NSException != nil && e.NSException.Name != "":
+		return e.NSException.Name
+	case e.CPPException != nil && e.CPPException.Name != "":
+		return e.CPPException.Name
+	case e.Mach != nil && e.Mach.ExceptionName != "":
+		return e.Mach.ExceptionName
+	case e.Signal != nil && e.Signal.Name != "":
+		return e.Signal.Name
+	}
+	return e.Type
+}
+
+func (e ksCrashError) exceptionMessage() string {
+	switch {
+	case e.Reason != "":
+		return e.Reason
+	case e.NSException != nil && e.NSException.Reason != "":
+		return e.NSException.Reason
+	case e.Mach != nil && e.Mach.CodeName != "":
+		return e.Mach.CodeName
+	case e.Signal != nil && e.Signal.CodeName != "":
+		return e.Signal.CodeName
+	}
+	return ""
+}
@@ -27,6 +27,7 @@ var (
 // symbolicator interface is used to symbolicate stack traces.
 type symbolicator interface {
 	symbolicateFrame(ctx context.Context, debugId, binaryName string, addr uint64) ([]*mappedDSYMStackFrame, error)
+	indexBuild(ctx context.Context, debugId string)
 }
 
 // symbolicatorProcessor is a processor that finds and symbolicates stack
@@ -69,6 +70,8 @@ func (sp *symbolicatorProcessor) processLogs(ctx context.Context, logs plog.Logs
 // processResourceSpans takes resource spans and processes the attributes
 // found on the spans.
 func (sp *symbolicatorProcessor) processResourceSpans(ctx context.Context, rl plog.ResourceLogs) {
+	buildIndexed := false
+
 	for i := 0; i < rl.ScopeLogs().Len(); i++ {
 		sl := rl.ScopeLogs().At(i)
 
@@ -96,13 +99,32 @@ func (sp *symbolicatorProcessor) processResourceSpans(ctx context.Context, rl pl
 				}
 			}
 
+			// the app's zipped dSYMs may hold the frameworks of any frame, so
+			// they're indexed before the first record is symbolicated
+			if !buildIndexed && sp.symbolicates(attributes) {
+				sp.indexBuild(ctx, resourceAttrs)
+				buildIndexed = true
+			}
+
+			// third-party crash reports carry their own threads and binary images
+			if _, ok := attributes.Get(sp.cfg.CrashReportAttributeKey); ok {
+				sp.processCrashReportAttributes(ctx, attributes)
+				continue
+			}
+
+			// return addresses can only be symbolicated with the images they were loaded from
+			if _, ok := attributes.Get(sp.cfg.ReturnAddressesAttributeKey); ok && sp.hasBinaryImages(attributes, resourceAttrs) {
+				sp.processReturnAddressesAttributes(ctx, attributes, resourceAttrs)
+				continue
+			}
+
 			// if we have a stack trace, try symbolicating it
 			if _, ok := attributes.Get(sp.cfg.StackTraceAttributeKey); ok {
 				// Check if this is a MetricKit diagnostic via eventName
 				eventName := log.EventName()
 				if strings.HasPrefix(eventName, "metrickit.diagnostic.") {
 					// MetricKit JSON format
-					sp.processMetricKitAttributes(ctx, attributes)
+					sp.processMetricKitAttributes(ctx, attributes, resourceAttrs)
 				} else {
 					// Regular text format
 					sp.processStackTraceAttributes(ctx, attributes, resourceAttrs)
@@ -112,7 +134,7 @@ func (sp *symbolicatorProcessor) processResourceSpans(ctx context.Context, rl pl
 
 			// no stack trace, let's check if there's a metrickit attribute (for backwards compatibility)
 			if _, ok := attributes.Get(sp.cfg.MetricKitStackTraceAttributeKey); ok {
-				sp.processMetricKitAttributes(ctx, attributes)
+				sp.processMetricKitAttributes(ctx, attributes, resourceAttrs)
 				continue
 			}
 
@@ -123,15 +145,42 @@ func (sp *symbolicatorProcessor) processResourceSpans(ctx context.Context, rl pl
 	}
 }
 
+// symbolicates reports whether a log record has anything to symbolicate.
+func (sp *symbolicatorProcessor) symbolicates(attributes pcommon.Map) bool {
+	for _, key := range []string{sp.cfg.CrashReportAttributeKey, sp.cfg.ReturnAddressesAttributeKey, sp.cfg.StackTraceAttributeKey, sp.cfg.MetricKitStackTraceAttributeKey} {
+		if _, ok := attributes.Get(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// indexBuild indexes the zipped dSYMs of the app build of a resource, if any.
+func (sp *symbolicatorProcessor) indexBuild(ctx context.Context, resourceAttributes pcommon.Map) {
+	if buildUUID, ok := resourceAttributes.Get(sp.cfg.BuildUUIDAttributeKey); ok && buildUUID.Str() != "" {
+		sp.symbolicator.indexBuild(ctx, buildUUID.Str())
+	}
+}
+
 func formatStackFrames(prefix, binaryName string, offset uint64, frames []*mappedDSYMStackFrame) string {
 	lines := make([]string, len(frames))
 	for i, loc := range frames {
-		lines[i] = fmt.Sprintf("%s %s (in %s) (%s:%d) + %d", prefix, loc.symbol, binaryName, loc.path, loc.line, offset)
+		lines[i] = fmt.Sprintf("%s %s (in %s) (%s:%d) + %d", prefix, loc.symbol, binaryName, loc.path, loc.line, offset) + inlinedMarker(i, frames)
 	}
 
 	return strings.Join(lines, "\n")
 }
 
+// inlinedMarker returns Apple's [inlined] annotation for every location of an
+// address but the last, which is the outermost real function the others were
+// inlined into.
+func inlinedMarker(i int, frames []*mappedDSYMStackFrame) string {
+	if i < len(frames)-1 {
+		return " [inlined]"
+	}
+	return ""
+}
+
 func (sp *symbolicatorProcessor) processStackTraceAttributes(ctx context.Context, attributes pcommon.Map, resourceAttributes pcommon.Map) {
 	// Start timing symbolication only when we actually perform it
 	// End timing deferred to after processing is done
@@ -176,6 +225,23 @@ func (sp *symbolicatorProcessor) processStackTraceAttributesThrows(ctx context.C
 	}
 	binaryName := binaryNameValue.Str()
 
+	// Binary images are only needed for lines that name a symbol rather than a
+	// binary, so they're parsed on first use. If they're malformed, those lines
+	// are left as-is, as they were before binary images were supported.
+	var images binaryImages
+	imagesParsed := false
+	lazyImages := func() binaryImages {
+		if !imagesParsed {
+			imagesParsed = true
+
+			var err error
+			if images, err = sp.binaryImages(attributes, resourceAttributes); err != nil {
+				sp.logger.Debug("Not resolving stack trace lines with binary images", zap.Error(err))
+			}
+		}
+		return images
+	}
+
 	lines := strings.Split(rawStackTrace, "\n")
 	res := make([]string, len(lines))
 	symbolicationFailed := false
@@ -184,7 +250,7 @@ func (sp *symbolicatorProcessor) processStackTraceAttributesThrows(ctx context.C
 	fetchErrorCache := make(map[string]error)
 
 	for idx, line := range lines {
-		symbolicated, err := sp.symbolicateStackLine(ctx, line, binaryName, buildUUID, fetchErrorCache)
+		symbolicated, err := sp.symbolicateStackLine(ctx, line, binaryName, buildUUID, lazyImages, fetchErrorCache)
 		if err != nil {
 			sp.logger.Debug("could not symbolicate line")
 			res[idx] = line
@@ -208,15 +274,26 @@ func (sp *symbolicatorProcessor) processStackTraceAttributesThrows(ctx context.C
 
 // groups: stack index, library name, hex address, uuid or binary name, offset
 var stackLineRegex = regexp.MustCompile(`^([0-9]+)\s+([\w _\-\.]+[\w_\-\.])\s+(0x[\da-f]+)\s+([\w _\-\.]*) \+ (\d+)`)
+var hexAddressRegex = regexp.MustCompile(`^0x[\da-fA-F]+$`)
 var uuidRegex = regexp.MustCompile(`[0-9A-Z]{8}-[0-9A-Z]{4}-[0-9A-Z]{4}-[0-9A-Z]{4}-[0-9A-Z]{12}`)
 
-func (sp *symbolicatorProcessor) symbolicateStackLine(ctx context.Context, line, binaryName, buildUUID string, fetchErrorCache map[string]error) (string, error) {
-	if !stackLineRegex.MatchString(line) {
+// groups: stack index, library name, hex address, symbol name, offset into the symbol
+//
+// Thread.callStackSymbols prints the nearest symbol rather than the binary, and
+// symbol names may contain any character, e.g. -[NSObject(NSObject) doesNotRecognizeSelector:].
+var callStackSymbolLineRegex = regexp.MustCompile(`^([0-9]+)\s+(.+?)\s+(0x[\da-fA-F]+)\s+(.+) \+ (\d+)\s*$`)
+
+func (sp *symbolicatorProcessor) symbolicateStackLine(ctx context.Context, line, binaryName, buildUUID string, images func() binaryImages, fetchErrorCache map[string]error) (string, error) {
+	matches := stackLineRegex.FindStringSubmatch(line)
+	matchIdxes := stackLineRegex.FindStringSubmatchIndex(line)
+	if matches == nil {
+		matches = callStackSymbolLineRegex.FindStringSubmatch(line)
+		matchIdxes = callStackSymbolLineRegex.FindStringSubmatchIndex(line)
+	}
+	if matches == nil {
 		// stacktrace line not formated the way we expect, skip it
 		return line, nil
 	}
-	matches := stackLineRegex.FindStringSubmatch(line)
-	matchIdxes := stackLineRegex.FindStringSubmatchIndex(line)
 	libName := matches[2]
 	uuidOrBinary := matches[4]
 	offsetInt, err := strconv.Atoi(matches[5])
@@ -230,9 +307,17 @@ func (sp *symbolicatorProcessor) symbolicateStackLine(ctx context.Context, line,
 	if isUUID(uuidOrBinary) {
 		uuid = uuidOrBinary
 		bin = libName
-	} else if uuidOrBinary == binaryName {
+	} else if uuidOrBinary == binaryName || (libName == binaryName && isHexAddress(uuidOrBinary)) {
+		// either the binary name or its load address, e.g. 0x102500000 + 231256,
+		// followed by the offset into the binary
 		uuid = buildUUID
 		bin = binaryName
+	} else if image, imageOffset, ok := images().resolve(parseStackLineAddress(matches[3])); ok && image.uuid != "" {
+		// The line names a symbol and the offset is relative to it, so the
+		// binary and offset come from the absolute address instead.
+		uuid = image.uuid
+		bin = image.name
+		offset = imageOffset
 	} else {
 		return line, nil
 	}
@@ -269,21 +354,26 @@ func (sp *symbolicatorProcessor) symbolicateStackLine(ctx context.Context, line,
 	return formatStackFrames(prefix, bin, offset, locations), nil
 }
 
+func parseStackLineAddress(hexAddress string) uint64 {
+	// the regexes only match valid hex addresses
+	addr, _ := strconv.ParseUint(hexAddress, 0, 64)
+	return addr
+}
+
+func isHexAddress(maybeAddress string) bool {
+	return hexAddressRegex.MatchString(maybeAddress)
+}
+
 func isUUID(maybeUUID string) bool {
 	return uuidRegex.MatchString(maybeUUID)
 }
 
 func formatMetricKitStackFrames(frame MetricKitCallStackFrame, frames []*mappedDSYMStackFrame) string {
-	var offset uint64 = 0
-	if frame.OffsetIntoBinaryTextSegment != nil {
-		offset = *frame.OffsetIntoBinaryTextSegment
-	} else if frame.OffsetAddress != nil {
-		offset = *frame.OffsetAddress
-	}
+	offset := metricKitFrameOffset(frame)
 
 	lines := make([]string, len(frames))
 	for i, loc := range frames {
-		lines[i] = fmt.Sprintf("%s\t\t\t0x%X %s (%s:%d) + %d", frame.BinaryName, offset, loc.symbol, loc.path, loc.line, loc.symAddr)
+		lines[i] = fmt.Sprintf("%s\t\t\t0x%X %s (%s:%d) + %d", frame.BinaryName, offset, loc.symbol, loc.path, loc.line, loc.symAddr) + inlinedMarker(i, frames)
 	}
 
 	return strings.Join(lines, "\n")
@@ -311,11 +401,18 @@ type MetricKitCallStackFrame struct {
 	OffsetIntoBinaryTextSegment *uint64                    `json:"offsetIntoBinaryTextSegment"`
 	SubFrames                   *[]MetricKitCallStackFrame `json:"subFrames"`
 
+	// the number of samples in which this frame was observed, for call stack
+	// trees aggregated from hang, CPU and disk-write diagnostics
+	SampleCount *uint64 `json:"sampleCount"`
+
 	// the simplified OpenTelemetry format
 	OffsetAddress *uint64 `json:"offsetAddress"`
+
+	// the absolute address of the frame, only used when there is no offset
+	Address *uint64 `json:"address"`
 }
 
-func (sp *symbolicatorProcessor) processMetricKitAttributes(ctx context.Context, attributes pcommon.Map) {
+func (sp *symbolicatorProcessor) processMetricKitAttributes(ctx context.Context, attributes, resourceAttributes pcommon.Map) {
 	// Start timing symbolication only when we actually perform it
 	// End timing deferred to after processing is done
 	startTime := time.Now()
@@ -327,7 +424,7 @@ func (sp *symbolicatorProcessor) processMetricKitAttributes(ctx context.Context,
 	attributes.PutStr("honeycomb.processor_type", typeStr.String())
 	attributes.PutStr("honeycomb.processor_version", processorVersion)
 
-	err := sp.processMetricKitAttributesThrows(ctx, attributes)
+	err := sp.processMetricKitAttributesThrows(ctx, attributes, resourceAttributes)
 	if err != nil {
 		attributes.PutBool(sp.cfg.SymbolicatorFailureAttributeKey, true)
 		attributes.PutStr("exception.symbolicator.error", err.Error())
@@ -337,7 +434,7 @@ func (sp *symbolicatorProcessor) processMetricKitAttributes(ctx context.Context,
 	}
 }
 
-func (sp *symbolicatorProcessor) processMetricKitAttributesThrows(ctx context.Context, attributes pcommon.Map) error {
+func (sp *symbolicatorProcessor) processMetricKitAttributesThrows(ctx context.Context, attributes, resourceAttributes pcommon.Map) error {
 	var ok bool
 	var metrickitStackTraceValue pcommon.Value
 
@@ -354,48 +451,91 @@ func (sp *symbolicatorProcessor) processMetricKitAttributesThrows(ctx context.Co
 		return err
 	}
 
+	// Frames that only have an absolute address are resolved against the binary
+	// images, so they're parsed on first use. If they're malformed, only those
+	// frames fail to symbolicate.
+	var images binaryImages
+	var imagesErr error
+	imagesParsed := false
+	lazyImages := func() (binaryImages, error) {
+		if !imagesParsed {
+			imagesParsed = true
+			images, imagesErr = sp.binaryImages(attributes, resourceAttributes)
+		}
+		return images, imagesErr
+	}
+
 	stacks := make([]string, 0, len(report.CallStacks))
+	folded := make([]string, 0)
+	var heaviest []*metricKitCallTreeNode
+	symbolicationFailed := false
+
+	// Record how each frame of the output stack trace was symbolicated, in order.
+	frameStatuses := attributes.PutEmptySlice(sp.cfg.OutputMetricKitFrameStatusesAttributeKey)
 
 	// Cache FetchErrors to avoid redundant fetches for missing resources.
 	fetchErrorCache := make(map[string]error)
 
 	for _, callStack := range report.CallStacks {
-		symbolicatedStack := make([]string, 0, 512)
-
-		// Try the old Apple format.
-		if callStack.CallStackRootFrames != nil && len(*callStack.CallStackRootFrames) > 0 {
-			frame := &(*callStack.CallStackRootFrames)[0]
-			for frame != nil {
-				line, err := sp.symbolicateFrame(ctx, *frame, fetchErrorCache)
-				if err != nil {
-					return err
-				}
-
-				symbolicatedStack = append(symbolicatedStack, line)
+		roots := make([]*metricKitCallTreeNode, 0, 1)
 
-				frames := frame.SubFrames
-				frame = nil
-				if frames != nil && len(*frames) > 0 {
-					frame = &(*frames)[0]
-				}
+		// Try the old Apple format. Hang, CPU and disk-write diagnostics branch
+		// into sibling subframes, so the whole tree is symbolicated.
+		if callStack.CallStackRootFrames != nil {
+			for _, frame := range *callStack.CallStackRootFrames {
+				roots = append(roots, sp.symbolicateCallTree(ctx, frame, lazyImages, fetchErrorCache))
 			}
 		}
 
-		// Try the new OTel format.
+		// Try the new OTel format, which is already a single flattened path.
 		if callStack.CallStackFrames != nil {
+			var parent *metricKitCallTreeNode
 			for _, frame := range *callStack.CallStackFrames {
-				line, err := sp.symbolicateFrame(ctx, frame, fetchErrorCache)
-				if err != nil {
-					return err
+				node := sp.symbolicateCallTreeNode(ctx, frame, lazyImages, fetchErrorCache)
+
+				if parent == nil {
+					roots = append(roots, node)
+				} else {
+					parent.subNodes = append(parent.subNodes, node)
 				}
-				symbolicatedStack = append(symbolicatedStack, line)
+				parent = node
 			}
 		}
 
+		path := heaviestCallTreePath(roots)
+		symbolicatedStack := make([]string, len(path))
+		for i, node := range path {
+			symbolicatedStack[i] = node.line
+			frameStatuses.AppendEmpty().SetStr(string(node.status))
+		}
 		stacks = append(stacks, strings.Join(symbolicatedStack, "\n    "))
+
+		// A frame that failed anywhere in the tree makes the report partial, even
+		// when it isn't on the heaviest path.
+		if callTreeHasFailures(roots) {
+			symbolicationFailed = true
+		}
+
+		if heavierCallTreePath(path, heaviest) {
+			heaviest = path
+		}
+
+		for _, root := range roots {
+			folded = appendFoldedCallTree(folded, root, nil)
+		}
 	}
 
 	attributes.PutStr(sp.cfg.OutputMetricKitStackTraceAttributeKey, strings.Join(stacks, "\n\n\n"))
+	if len(folded) > 0 {
+		attributes.PutStr(sp.cfg.OutputMetricKitFoldedStacksAttributeKey, strings.Join(folded, "\n"))
+	}
+	if heaviest != nil {
+		heaviestStack := make([]string, len(heaviest))
+		for i, node := range heaviest {
+			heaviestStack[i] = node.line
+		}
+		attributes.PutStr(sp.cfg.OutputMetricKitHeaviestStackTraceAttributeKey, strings.Join(heaviestStack, "\n    "))
+	}
 	if !sp.cfg.PreserveStackTrace {
 		attributes.Remove(sp.cfg.MetricKitStackTraceAttributeKey)
 	}
@@ -403,6 +543,10 @@ func (sp *symbolicatorProcessor) processMetricKitAttributesThrows(ctx context.Co
 	// and we need to set exception.type and exception.message to make this a semantically valid exception
 	sp.setMetricKitExceptionAttrs(ctx, attributes)
 
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+
 	return nil
 }
 
@@ -432,40 +576,98 @@ func (sp *symbolicatorProcessor) setMetricKitExceptionAttrs(ctx context.Context,
 	attributes.PutStr(sp.cfg.OutputMetricKitExceptionMessageAttributeKey, exceptionMsg)
 }
 
-func (sp *symbolicatorProcessor) symbolicateFrame(ctx context.Context, frame MetricKitCallStackFrame, fetchErrorCache map[string]error) (string, error) {
-	// Check if we have a cached fetch error for this UUID
-	if cachedError, exists := fetchErrorCache[frame.BinaryUUID]; exists {
-		return "", cachedError
-	}
-
-	var offset uint64 = 0
-	if frame.OffsetAddress != nil {
-		offset = *frame.OffsetAddress
+// symbolicateFrame symbolicates a single MetricKit frame. Frames from binaries
+// without a dSYM are returned unsymbolicated with no locations and no error.
+func (sp *symbolicatorProcessor) symbolicateFrame(ctx context.Context, frame MetricKitCallStackFrame, fetchErrorCache map[string]error) (string, []*mappedDSYMStackFrame, error) {
+	locations, err := sp.lookupFrame(ctx, frame.BinaryUUID, frame.BinaryName, metricKitFrameOffset(frame), fetchErrorCache)
+	if err != nil {
+		return "", nil, err
 	}
-	if frame.OffsetIntoBinaryTextSegment != nil {
-		offset = *frame.OffsetIntoBinaryTextSegment
+	if len(locations) == 0 {
+		return formatUnsymbolicatedMetricKitFrame(frame), nil, nil
 	}
 
-	locations, err := sp.symbolicator.symbolicateFrame(ctx, frame.BinaryUUID, frame.BinaryName, offset)
-	sp.telemetryBuilder.ProcessorTotalProcessedFrames.Add(ctx, 1, sp.attributes)
+	return formatMetricKitStackFrames(frame, locations), locations, nil
+}
 
-	// Only cache FetchErrors (404, timeout, etc.) - not parse errors
-	if err != nil {
-		var fetchErr *FetchError
-		if errors.As(err, &fetchErr) {
-			fetchErrorCache[frame.BinaryUUID] = err
+// lookupFrame symbolicates an offset into a binary, caching fetch errors by UUID.
+// Binaries without a dSYM return no locations and no error.
+func (sp *symbolicatorProcessor) lookupFrame(ctx context.Context, debugId, binaryName string, offset uint64, fetchErrorCache map[string]error) ([]*mappedDSYMStackFrame, error) {
+	var locations []*mappedDSYMStackFrame
+	var err error
+
+	// Check if we have a cached fetch error for this UUID
+	if cachedError, exists := fetchErrorCache[debugId]; exists {
+		err = cachedError
+	} else {
+		locations, err = sp.symbolicator.symbolicateFrame(ctx, debugId, binaryName, offset)
+		sp.telemetryBuilder.ProcessorTotalProcessedFrames.Add(ctx, 1, sp.attributes)
+
+		// Only cache FetchErrors (404, timeout, etc.) - not parse errors
+		if err != nil {
+			var fetchErr *FetchError
+			if errors.As(err, &fetchErr) {
+				fetchErrorCache[debugId] = err
+			}
 		}
 	}
 
 	if errors.Is(err, errFailedToFindDSYM) {
-		return fmt.Sprintf("%s(%s) +%d", frame.BinaryName, frame.BinaryUUID, offset), nil
+		return nil, nil
 	}
 	if err != nil {
 		sp.telemetryBuilder.ProcessorTotalFailedFrames.Add(ctx, 1, sp.attributes)
-		return "", err
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// formatUnsymbolicatedMetricKitFrame formats a frame that could not be symbolicated,
+// keeping the binary and offset so it can be symbolicated later.
+func formatUnsymbolicatedMetricKitFrame(frame MetricKitCallStackFrame) string {
+	return fmt.Sprintf("%s(%s) +%d", frame.BinaryName, frame.BinaryUUID, metricKitFrameOffset(frame))
+}
+
+// resolveMetricKitFrameAddress fills in the offset, and the binary if missing, of
+// a frame that only has an absolute address from the image it was loaded from.
+// The images are only parsed for such frames.
+func resolveMetricKitFrameAddress(frame MetricKitCallStackFrame, images func() (binaryImages, error)) (MetricKitCallStackFrame, error) {
+	if frame.OffsetIntoBinaryTextSegment != nil || frame.OffsetAddress != nil || frame.Address == nil {
+		return frame, nil
+	}
+
+	parsed, err := images()
+	if err != nil {
+		return frame, err
+	}
+
+	image, offset, ok := parsed.resolve(*frame.Address)
+	if !ok {
+		return frame, nil
+	}
+
+	frame.OffsetAddress = &offset
+	if frame.BinaryName == "" {
+		frame.BinaryName = image.name
+	}
+	if frame.BinaryUUID == "" {
+		frame.BinaryUUID = image.uuid
 	}
 
-	return formatMetricKitStackFrames(frame, locations), nil
+	return frame, nil
+}
+
+// metricKitFrameOffset returns the offset of a frame into its binary, in either
+// the Apple or the OpenTelemetry format.
+func metricKitFrameOffset(frame MetricKitCallStackFrame) uint64 {
+	if frame.OffsetIntoBinaryTextSegment != nil {
+		return *frame.OffsetIntoBinaryTextSegment
+	}
+	if frame.OffsetAddress != nil {
+		return *frame.OffsetAddress
+	}
+	return 0
 }
 
 func getFirstAvailableString(attributes pcommon.Map, keys []string, fallbackValue string) string {
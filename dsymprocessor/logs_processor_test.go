@@ -45,6 +45,8 @@ func createTestTelemetry(t *testing.T) (*metadata.TelemetryBuilder, attribute.Se
 	return tb, attributes, tb.Shutdown
 }
 
+func (ts *testSymbolicator) indexBuild(ctx context.Context, debugId string) {}
+
 func (ts *testSymbolicator) symbolicateFrame(ctx context.Context, debugId, binaryName string, addr uint64) ([]*mappedDSYMStackFrame, error) {
 	if debugId != "6A8CB813-45F6-3652-AD33-778FD1EAB196" {
 		return nil, errFailedToFindDSYM
@@ -215,7 +217,7 @@ func TestProcessMetricKit(t *testing.T) {
 			log.SetEventName("metrickit.diagnostic.crash")
 			log.Attributes().PutEmpty(cfg.MetricKitStackTraceAttributeKey).SetStr(jsonstr)
 
-			processor.processMetricKitAttributes(ctx, log.Attributes())
+			processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
 
 			symbolicated, found := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
 			assert.True(t, found)
@@ -329,7 +331,7 @@ func TestProcessOTelMetricKit(t *testing.T) {
 			log.SetEventName("metrickit.diagnostic.crash")
 			log.Attributes().PutEmpty(cfg.MetricKitStackTraceAttributeKey).SetStr(jsonstr)
 
-			processor.processMetricKitAttributes(ctx, log.Attributes())
+			processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
 
 			symbolicated, found := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
 			assert.True(t, found)
@@ -377,6 +379,210 @@ func TestProcessOTelMetricKit(t *testing.T) {
 	}
 }
 
+func TestProcessMetricKitCallStackTree(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+	s := &testSymbolicator{}
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, s, tb, attributes)
+
+	// A hang diagnostic where the top frame was sampled from two different callers,
+	// and one sample ended inside the app itself.
+	jsonstr := `{
+		"callStacks": [
+			{
+				"threadAttributed": true,
+				"callStackRootFrames": [
+					{
+						"binaryUUID": "6527276E-A3D1-30FB-BA68-ACA33324D618",
+						"offsetIntoBinaryTextSegment": 933484,
+						"sampleCount": 10,
+						"binaryName": "SwiftUI",
+						"subFrames": [
+							{
+								"binaryUUID": "6527276E-A3D1-30FB-BA68-ACA33324D618",
+								"offsetIntoBinaryTextSegment": 933200,
+								"sampleCount": 3,
+								"binaryName": "SwiftUI"
+							},
+							{
+								"binaryUUID": "6A8CB813-45F6-3652-AD33-778FD1EAB196",
+								"offsetIntoBinaryTextSegment": 100436,
+								"sampleCount": 7,
+								"binaryName": "Chateaux Bufeaux",
+								"subFrames": [
+									{
+										"binaryUUID": "189FE480-5D5B-3B89-9289-58BC88624420",
+										"offsetIntoBinaryTextSegment": 68312,
+										"sampleCount": 6,
+										"binaryName": "dyld"
+									}
+								]
+							}
+						]
+					}
+				]
+			}
+		]
+	}`
+
+	logs := plog.NewLogs()
+	resourceLog := logs.ResourceLogs().AppendEmpty()
+	scopeLog := resourceLog.ScopeLogs().AppendEmpty()
+
+	log := scopeLog.LogRecords().AppendEmpty()
+	log.SetEventName("metrickit.diagnostic.hang")
+	log.Attributes().PutEmpty(cfg.MetricKitStackTraceAttributeKey).SetStr(jsonstr)
+
+	processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
+
+	// the stack trace follows the heaviest branch
+	expected := `SwiftUI(6527276E-A3D1-30FB-BA68-ACA33324D618) +933484
+    Chateaux Bufeaux			0x18854 main (MyFile.swift:1) + 1
+    dyld(189FE480-5D5B-3B89-9289-58BC88624420) +68312`
+
+	symbolicated, found := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
+	assert.True(t, found)
+	assert.Equal(t, expected, symbolicated.Str())
+
+	heaviest, found := log.Attributes().Get(cfg.OutputMetricKitHeaviestStackTraceAttributeKey)
+	assert.True(t, found)
+	assert.Equal(t, expected, heaviest.Str())
+
+	// every branch is kept with its own sample count, outermost frame first
+	expectedFolded := `SwiftUI+0xe3d50;SwiftUI+0xe3e6c 3
+dyld+0x10ad8;Chateaux Bufeaux` + "`" + `main;SwiftUI+0xe3e6c 6
+Chateaux Bufeaux` + "`" + `main;SwiftUI+0xe3e6c 1`
+
+	folded, found := log.Attributes().Get(cfg.OutputMetricKitFoldedStacksAttributeKey)
+	assert.True(t, found)
+	assert.Equal(t, expectedFolded, folded.Str())
+
+	hasFailure, hasFailureAttr := log.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.True(t, hasFailureAttr)
+	assert.False(t, hasFailure.Bool())
+}
+
+// failingUUIDSymbolicator fails to symbolicate any frame of one binary, e.g. because
+// of a corrupt dSYM, and otherwise behaves like testSymbolicator.
+type failingUUIDSymbolicator struct {
+	testSymbolicator
+	failUUID string
+}
+
+func (fs *failingUUIDSymbolicator) symbolicateFrame(ctx context.Context, debugId, binaryName string, addr uint64) ([]*mappedDSYMStackFrame, error) {
+	if debugId == fs.failUUID {
+		return nil, errors.New("corrupt symcache")
+	}
+	return fs.testSymbolicator.symbolicateFrame(ctx, debugId, binaryName, addr)
+}
+
+func TestProcessMetricKitPartialSymbolication(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+	s := &failingUUIDSymbolicator{failUUID: "6527276E-A3D1-30FB-BA68-ACA33324D618"}
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, s, tb, attributes)
+
+	jsonstr := `{
+		"callStacks": [
+			{
+				"threadAttributed": true,
+				"callStackFrames": [
+					{
+						"binaryUUID": "6527276E-A3D1-30FB-BA68-ACA33324D618",
+						"offsetAddress": 933484,
+						"binaryName": "SwiftUI"
+					},
+					{
+						"binaryUUID": "6A8CB813-45F6-3652-AD33-778FD1EAB196",
+						"offsetAddress": 100436,
+						"binaryName": "Chateaux Bufeaux"
+					},
+					{
+						"binaryUUID": "189FE480-5D5B-3B89-9289-58BC88624420",
+						"offsetAddress": 68312,
+						"binaryName": "dyld"
+					}
+				]
+			}
+		]
+	}`
+
+	logs := plog.NewLogs()
+	resourceLog := logs.ResourceLogs().AppendEmpty()
+	scopeLog := resourceLog.ScopeLogs().AppendEmpty()
+
+	log := scopeLog.LogRecords().AppendEmpty()
+	log.SetEventName("metrickit.diagnostic.crash")
+	log.Attributes().PutEmpty(cfg.MetricKitStackTraceAttributeKey).SetStr(jsonstr)
+
+	processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
+
+	// the failed frame is kept raw and every other frame is still symbolicated
+	symbolicated, found := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
+	assert.True(t, found)
+	assert.Equal(t, `SwiftUI(6527276E-A3D1-30FB-BA68-ACA33324D618) +933484
+    Chateaux Bufeaux			0x18854 main (MyFile.swift:1) + 1
+    dyld(189FE480-5D5B-3B89-9289-58BC88624420) +68312`, symbolicated.Str())
+
+	statuses, found := log.Attributes().Get(cfg.OutputMetricKitFrameStatusesAttributeKey)
+	assert.True(t, found)
+	assert.Equal(t, []any{"failed", "symbolicated", "missing_dsym"}, statuses.Slice().AsRaw())
+
+	hasFailure, hasFailureAttr := log.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.True(t, hasFailureAttr)
+	assert.True(t, hasFailure.Bool())
+	errorMessage, hasErrorMessage := log.Attributes().Get(cfg.SymbolicatorErrorAttributeKey)
+	assert.True(t, hasErrorMessage)
+	assert.Equal(t, errPartialSymbolication.Error(), errorMessage.Str())
+
+	exceptionType, found := log.Attributes().Get(cfg.OutputMetricKitExceptionTypeAttributeKey)
+	assert.True(t, found)
+	assert.Equal(t, "Unknown Error", exceptionType.Str())
+}
+
+func TestHeaviestCallTreePath(t *testing.T) {
+	leafA := &metricKitCallTreeNode{line: "a", sampleCount: 2}
+	leafB := &metricKitCallTreeNode{line: "b", sampleCount: 2}
+	root := &metricKitCallTreeNode{line: "root", sampleCount: 4, subNodes: []*metricKitCallTreeNode{leafA, leafB}}
+	other := &metricKitCallTreeNode{line: "other", sampleCount: 1}
+
+	// ties go to the first frame
+	assert.Equal(t, []*metricKitCallTreeNode{root, leafA}, heaviestCallTreePath([]*metricKitCallTreeNode{other, root}))
+
+	leafB.sampleCount = 3
+	assert.Equal(t, []*metricKitCallTreeNode{root, leafB}, heaviestCallTreePath([]*metricKitCallTreeNode{root}))
+
+	assert.Empty(t, heaviestCallTreePath(nil))
+}
+
+func TestHeavierCallTreePath(t *testing.T) {
+	// a lightly sampled stack with one hot leaf, and a heavily sampled stack
+	// whose samples are spread over its leaves
+	light := []*metricKitCallTreeNode{{line: "light", sampleCount: 5}, {line: "hot", sampleCount: 5}}
+	heavy := []*metricKitCallTreeNode{{line: "heavy", sampleCount: 20}, {line: "cold", sampleCount: 4}}
+
+	assert.True(t, heavierCallTreePath(heavy, light))
+	assert.False(t, heavierCallTreePath(light, heavy))
+	assert.True(t, heavierCallTreePath(light, nil))
+	assert.False(t, heavierCallTreePath(nil, light))
+}
+
 func TestMetricKitExceptionAttrs(t *testing.T) {
 	ctx := context.Background()
 	cfg := createDefaultConfig().(*Config)
@@ -403,7 +609,7 @@ func TestMetricKitExceptionAttrs(t *testing.T) {
 	log.Attributes().PutEmpty("metrickit.diagnostic.crash.exception.mach_exception.name").SetStr("exception type")
 	log.Attributes().PutEmpty("metrickit.diagnostic.crash.exception.mach_exception.description").SetStr("message")
 
-	processor.processMetricKitAttributes(ctx, log.Attributes())
+	processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
 
 	exceptionType, found := log.Attributes().Get(cfg.OutputMetricKitExceptionTypeAttributeKey)
 	assert.True(t, found)
@@ -417,7 +623,7 @@ func TestMetricKitExceptionAttrs(t *testing.T) {
 	log.Attributes().PutEmpty("metrickit.diagnostic.crash.exception.objc.type").SetStr("objc exception type")
 	log.Attributes().PutEmpty("metrickit.diagnostic.crash.exception.objc.message").SetStr("objc message")
 
-	processor.processMetricKitAttributes(ctx, log.Attributes())
+	processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
 
 	exceptionType, found = log.Attributes().Get(cfg.OutputMetricKitExceptionTypeAttributeKey)
 	assert.True(t, found)
@@ -496,7 +702,7 @@ func TestProcessFailure_WrongKey(t *testing.T) {
 	log.SetEventName("metrickit.diagnostic.crash")
 	log.Attributes().PutEmpty("incorrect.attribute.key").SetStr(jsonstr)
 
-	processor.processMetricKitAttributes(ctx, log.Attributes())
+	processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
 
 	_, found := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
 	assert.False(t, found)
@@ -536,7 +742,7 @@ func TestProcessFailure_InvalidJson(t *testing.T) {
 	log.SetEventName("metrickit.diagnostic.crash")
 	log.Attributes().PutEmpty("incorrect.attribute.key").SetStr(jsonstr)
 
-	processor.processMetricKitAttributes(ctx, log.Attributes())
+	processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
 
 	_, found := log.Attributes().Get(cfg.OutputMetricKitStackTraceAttributeKey)
 	assert.False(t, found)
@@ -557,6 +763,8 @@ type testSymbolicatorWithErrors struct {
 	err              error
 }
 
+func (m *testSymbolicatorWithErrors) indexBuild(ctx context.Context, debugId string) {}
+
 func (m *testSymbolicatorWithErrors) symbolicateFrame(ctx context.Context, debugId, binaryName string, addr uint64) ([]*mappedDSYMStackFrame, error) {
 	m.callCount++
 	if m.err != nil {
@@ -678,7 +886,7 @@ func TestErrorCaching_MetricKit(t *testing.T) {
 	log := scopeLog.LogRecords().AppendEmpty()
 	log.Attributes().PutStr(cfg.MetricKitStackTraceAttributeKey, metrickitJSON)
 
-	processor.processMetricKitAttributes(ctx, log.Attributes())
+	processor.processMetricKitAttributes(ctx, log.Attributes(), log.Attributes())
 
 	// Should only call symbolicate ONCE for the first frame, then reuse cached error
 	// for the remaining 3 frames (75% reduction: 1 call instead of 4)
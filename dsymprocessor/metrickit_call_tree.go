@@ -0,0 +1,174 @@
+package dsymprocessor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// metricKitCallTreeNode is a symbolicated frame of a MetricKit call stack tree.
+// Crash diagnostics only ever have a single path through the tree, but hang,
+// CPU exception and disk-write exception diagnostics aggregate many samples, so
+// a frame may branch into several sub frames, each seen a number of times.
+type metricKitCallTreeNode struct {
+	// line is the symbolicated frame formatted for the text stack trace.
+	line string
+	// foldedName is the compact frame name used in folded stacks.
+	foldedName string
+	// sampleCount is the number of samples that included this frame.
+	sampleCount uint64
+	// status records whether this frame was symbolicated.
+	status   frameStatus
+	subNodes []*metricKitCallTreeNode
+}
+
+// frameStatus records the outcome of symbolicating a single frame.
+type frameStatus string
+
+const (
+	// The frame was symbolicated.
+	frameStatusSymbolicated frameStatus = "symbolicated"
+	// No dSYM is available for the frame's binary, e.g. a system framework.
+	frameStatusMissingDSYM frameStatus = "missing_dsym"
+	// Symbolication failed, e.g. a corrupt dSYM or no symbol at the address.
+	frameStatusFailed frameStatus = "failed"
+)
+
+// symbolicateCallTree symbolicates a frame and every frame below it, preserving
+// sibling sub frames and their sample counts.
+func (sp *symbolicatorProcessor) symbolicateCallTree(ctx context.Context, frame MetricKitCallStackFrame, images func() (binaryImages, error), fetchErrorCache map[string]error) *metricKitCallTreeNode {
+	node := sp.symbolicateCallTreeNode(ctx, frame, images, fetchErrorCache)
+
+	if frame.SubFrames != nil {
+		for _, subFrame := range *frame.SubFrames {
+			node.subNodes = append(node.subNodes, sp.symbolicateCallTree(ctx, subFrame, images, fetchErrorCache))
+		}
+	}
+
+	return node
+}
+
+// symbolicateCallTreeNode symbolicates a single frame, without its sub frames.
+// A frame that fails to symbolicate keeps its raw binary and offset, so one bad
+// frame never costs the rest of the report.
+func (sp *symbolicatorProcessor) symbolicateCallTreeNode(ctx context.Context, frame MetricKitCallStackFrame, images func() (binaryImages, error), fetchErrorCache map[string]error) *metricKitCallTreeNode {
+	frame, imagesErr := resolveMetricKitFrameAddress(frame, images)
+
+	// Frames without a sample count come from crash reports, where every frame
+	// was seen exactly once.
+	var sampleCount uint64 = 1
+	if frame.SampleCount != nil {
+		sampleCount = *frame.SampleCount
+	}
+
+	node := &metricKitCallTreeNode{
+		sampleCount: sampleCount,
+	}
+
+	var line string
+	var locations []*mappedDSYMStackFrame
+	err := imagesErr
+	if err == nil {
+		line, locations, err = sp.symbolicateFrame(ctx, frame, fetchErrorCache)
+	} else {
+		sp.telemetryBuilder.ProcessorTotalFailedFrames.Add(ctx, 1, sp.attributes)
+	}
+	switch {
+	case err != nil:
+		sp.logger.Debug("could not symbolicate frame", zap.String("binary", frame.BinaryName), zap.Error(err))
+		node.line = formatUnsymbolicatedMetricKitFrame(frame)
+		node.status = frameStatusFailed
+	case len(locations) == 0:
+		node.line = line
+		node.status = frameStatusMissingDSYM
+	default:
+		node.line = line
+		node.status = frameStatusSymbolicated
+	}
+	node.foldedName = foldedFrameName(frame, locations)
+
+	return node
+}
+
+// callTreeHasFailures reports whether any frame in the trees failed to symbolicate.
+func callTreeHasFailures(nodes []*metricKitCallTreeNode) bool {
+	for _, node := range nodes {
+		if node.status == frameStatusFailed || callTreeHasFailures(node.subNodes) {
+			return true
+		}
+	}
+	return false
+}
+
+// heaviestCallTreePath follows the frames with the highest sample count from
+// the roots down to a leaf. Ties go to the first frame, so a single path tree
+// (e.g. a crash) is returned unchanged.
+func heaviestCallTreePath(roots []*metricKitCallTreeNode) []*metricKitCallTreeNode {
+	path := make([]*metricKitCallTreeNode, 0)
+
+	nodes := roots
+	for len(nodes) > 0 {
+		heaviest := nodes[0]
+		for _, node := range nodes[1:] {
+			if node.sampleCount > heaviest.sampleCount {
+				heaviest = node
+			}
+		}
+
+		path = append(path, heaviest)
+		nodes = heaviest.subNodes
+	}
+
+	return path
+}
+
+// heavierCallTreePath reports whether a path from heaviestCallTreePath has more
+// samples than another. A call stack's samples are those of its root, as a leaf
+// only has the samples in which it was the innermost frame.
+func heavierCallTreePath(path, than []*metricKitCallTreeNode) bool {
+	return len(path) > 0 && (len(than) == 0 || path[0].sampleCount > than[0].sampleCount)
+}
+
+// appendFoldedCallTree appends the tree below node in the folded stack format
+// used by flame graph tools: one line per distinct path, outermost frame first,
+// separated by semicolons and followed by the number of samples in which that
+// path was the full stack.
+func appendFoldedCallTree(folded []string, node *metricKitCallTreeNode, path []string) []string {
+	path = append(path, node.foldedName)
+
+	var subSamples uint64
+	for _, subNode := range node.subNodes {
+		subSamples += subNode.sampleCount
+		folded = appendFoldedCallTree(folded, subNode, path)
+	}
+
+	// Samples that were not attributed to any sub frame ended at this frame.
+	if node.sampleCount > subSamples {
+		frames := slices.Clone(path)
+		slices.Reverse(frames)
+		folded = append(folded, strings.Join(frames, ";")+" "+strconv.FormatUint(node.sampleCount-subSamples, 10))
+	}
+
+	return folded
+}
+
+// foldedFrameName returns a compact `binary`symbol` name for a frame, falling
+// back to the binary and offset when the frame could not be symbolicated.
+func foldedFrameName(frame MetricKitCallStackFrame, locations []*mappedDSYMStackFrame) string {
+	if len(locations) > 0 {
+		symbols := make([]string, len(locations))
+		for i, loc := range locations {
+			// semicolons separate frames in the folded format
+			symbols[i] = frame.BinaryName + "`" + strings.ReplaceAll(loc.symbol, ";", ":")
+		}
+		// Locations are ordered innermost inline frame first.
+		slices.Reverse(symbols)
+		return strings.Join(symbols, ";")
+	}
+
+	return fmt.Sprintf("%s+0x%x", frame.BinaryName, metricKitFrameOffset(frame))
+}
@@ -0,0 +1,121 @@
+package dsymprocessor
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// groups: thread index, " Crashed" if it crashed
+	plCrashThreadRegex = regexp.MustCompile(`^Thread (\d+)( Crashed)?:\s*$`)
+	// groups: thread index, thread name
+	plCrashThreadNameRegex = regexp.MustCompile(`^Thread (\d+) name:\s+(.*)$`)
+	// groups: frame index, binary name, hex address, load address or symbol, offset
+	plCrashFrameRegex = regexp.MustCompile(`^(\d+)\s+(.+?)\s+(0x[\da-fA-F]+)\s+(.+?) \+ (\d+)\s*$`)
+	// groups: load address, end address, binary name, architecture, uuid, path
+	plCrashBinaryImageRegex = regexp.MustCompile(`^\s*(0x[\da-fA-F]+)\s*-\s*(0x[\da-fA-F]+)\s+\+?(.+?)\s+(\S+)\s+<([\da-fA-F\-]+)>\s+(.+)$`)
+	// groups: exception name, reason
+	plCrashUncaughtExceptionRegex = regexp.MustCompile(`Terminating app due to uncaught exception '([^']+)', reason: '(.*)'`)
+)
+
+type plCrashFrame struct {
+	binaryName string
+	address    uint64
+	// loadAddress is only known when the frame is unsymbolicated, e.g. 0x100b74000 + 40008
+	loadAddress uint64
+}
+
+// parsePLCrashReport parses the Apple-compatible text format produced by
+// PLCrashReporter's PLCrashReportTextFormatter, which is also what its
+// protobuf reports are converted to before being sent.
+func parsePLCrashReport(raw string) (*crashReport, error) {
+	report := &crashReport{}
+	threadNames := make(map[int]string)
+	threadFrames := make([][]plCrashFrame, 0)
+	images := make([]binaryImage, 0)
+
+	var exceptionCodes string
+	inThread := false
+	inBinaryImages := false
+
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(raw)+1)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		switch {
+		case strings.HasPrefix(line, "Binary Images:"):
+			inBinaryImages = true
+			inThread = false
+		case inBinaryImages:
+			if matches := plCrashBinaryImageRegex.FindStringSubmatch(line); matches != nil {
+				start, _ := strconv.ParseUint(matches[1], 0, 64)
+				end, _ := strconv.ParseUint(matches[2], 0, 64)
+				images = append(images, binaryImage{
+					name:        matches[3],
+					uuid:        matches[5],
+					loadAddress: start,
+					size:        end - start + 1,
+					arch:        matches[4],
+				})
+			}
+		case strings.HasPrefix(line, "Exception Type:"):
+			report.exceptionType = strings.TrimSpace(strings.TrimPrefix(line, "Exception Type:"))
+		case strings.HasPrefix(line, "Exception Codes:"):
+			exceptionCodes = strings.TrimSpace(strings.TrimPrefix(line, "Exception Codes:"))
+		case plCrashUncaughtExceptionRegex.MatchString(line):
+			matches := plCrashUncaughtExceptionRegex.FindStringSubmatch(line)
+			report.exceptionType = matches[1]
+			report.exceptionMessage = matches[2]
+		case plCrashThreadNameRegex.MatchString(line):
+			matches := plCrashThreadNameRegex.FindStringSubmatch(line)
+			index, _ := strconv.Atoi(matches[1])
+			threadNames[index] = strings.TrimSpace(matches[2])
+		case strings.HasPrefix(line, "Last Exception Backtrace:"):
+			report.threads = append(report.threads, crashThread{lastExceptionBacktrace: true})
+			threadFrames = append(threadFrames, nil)
+			inThread = true
+		case plCrashThreadRegex.MatchString(line):
+			matches := plCrashThreadRegex.FindStringSubmatch(line)
+			index, _ := strconv.Atoi(matches[1])
+			report.threads = append(report.threads, crashThread{index: index, crashed: matches[2] != ""})
+			threadFrames = append(threadFrames, nil)
+			inThread = true
+		case inThread && plCrashFrameRegex.MatchString(line):
+			matches := plCrashFrameRegex.FindStringSubmatch(line)
+			frame := plCrashFrame{binaryName: matches[2]}
+			frame.address, _ = strconv.ParseUint(matches[3], 0, 64)
+			if strings.HasPrefix(matches[4], "0x") {
+				frame.loadAddress, _ = strconv.ParseUint(matches[4], 0, 64)
+			}
+			threadFrames[len(threadFrames)-1] = append(threadFrames[len(threadFrames)-1], frame)
+		case strings.TrimSpace(line) == "":
+			inThread = false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if report.exceptionMessage == "" {
+		report.exceptionMessage = exceptionCodes
+	}
+
+	// Frames can only be resolved once the binary images, listed at the end of
+	// the report, are known.
+	report.images = newBinaryImages(images)
+	for i := range report.threads {
+		if !report.threads[i].lastExceptionBacktrace {
+			report.threads[i].name = threadNames[report.threads[i].index]
+		}
+		for _, frame := range threadFrames[i] {
+			report.threads[i].frames = append(report.threads[i].frames, report.frameAt(frame.address, frame.binaryName, frame.loadAddress))
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,120 @@
+package dsymprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+)
+
+var errInvalidReturnAddresses = errors.New("invalid return addresses")
+
+// parseReturnAddresses parses NSException.callStackReturnAddresses, either as a
+// slice of integers or strings, or as a single string of decimal or hex
+// addresses such as "(0x1835df228, 0x180a79abc)".
+func parseReturnAddresses(value pcommon.Value) ([]uint64, error) {
+	if value.Type() == pcommon.ValueTypeSlice {
+		slice := value.Slice()
+		addresses := make([]uint64, slice.Len())
+		for i := 0; i < slice.Len(); i++ {
+			item := slice.At(i)
+			if item.Type() == pcommon.ValueTypeInt {
+				addresses[i] = uint64(item.Int())
+				continue
+			}
+
+			addr, err := parseReturnAddress(item.AsString())
+			if err != nil {
+				return nil, err
+			}
+			addresses[i] = addr
+		}
+		return addresses, nil
+	}
+
+	raw := strings.FieldsFunc(value.AsString(), func(r rune) bool {
+		return strings.ContainsRune(",()[]\" \t\n", r)
+	})
+
+	addresses := make([]uint64, len(raw))
+	for i, s := range raw {
+		addr, err := parseReturnAddress(s)
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = addr
+	}
+
+	return addresses, nil
+}
+
+func parseReturnAddress(s string) (uint64, error) {
+	addr, err := strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errInvalidReturnAddresses, s)
+	}
+	return addr, nil
+}
+
+// newReturnAddressesReport builds a crash report with a single, untitled thread
+// from a list of return addresses and the images they were loaded from.
+func newReturnAddressesReport(addresses []uint64, images binaryImages) *crashReport {
+	report := &crashReport{images: images}
+
+	thread := crashThread{frames: make([]crashFrame, len(addresses))}
+	for i, addr := range addresses {
+		thread.frames[i] = report.frameAt(addr, unknownBinaryName, 0)
+	}
+	report.threads = []crashThread{thread}
+
+	return report
+}
+
+func (sp *symbolicatorProcessor) processReturnAddressesAttributes(ctx context.Context, attributes, resourceAttributes pcommon.Map) {
+	// Start timing symbolication only when we actually perform it
+	// End timing deferred to after processing is done
+	startTime := time.Now()
+	defer func() {
+		sp.telemetryBuilder.ProcessorSymbolicationDuration.Record(ctx, time.Since(startTime).Seconds(), sp.attributes)
+	}()
+
+	// Add processor type and version as attributes
+	attributes.PutStr("honeycomb.processor_type", typeStr.String())
+	attributes.PutStr("honeycomb.processor_version", processorVersion)
+
+	err := sp.processReturnAddressesAttributesThrows(ctx, attributes, resourceAttributes)
+	if err != nil {
+		attributes.PutBool(sp.cfg.SymbolicatorFailureAttributeKey, true)
+		attributes.PutStr("exception.symbolicator.error", err.Error())
+		sp.logger.Debug("Error processing span", zap.Error(err))
+	} else {
+		attributes.PutBool(sp.cfg.SymbolicatorFailureAttributeKey, false)
+	}
+}
+
+func (sp *symbolicatorProcessor) processReturnAddressesAttributesThrows(ctx context.Context, attributes, resourceAttributes pcommon.Map) error {
+	var ok bool
+	var returnAddressesValue pcommon.Value
+
+	if returnAddressesValue, ok = attributes.Get(sp.cfg.ReturnAddressesAttributeKey); !ok {
+		// we should never get here (our caller checks this)
+		return fmt.Errorf("Invalid state! Called processReturnAddressesAttributes while missing %s attribute", sp.cfg.ReturnAddressesAttributeKey)
+	}
+
+	addresses, err := parseReturnAddresses(returnAddressesValue)
+	if err != nil {
+		return err
+	}
+
+	images, err := sp.binaryImages(attributes, resourceAttributes)
+	if err != nil {
+		return err
+	}
+
+	return sp.symbolicateCrashReport(ctx, attributes, newReturnAddressesReport(addresses, images))
+}
@@ -0,0 +1,106 @@
+package dsymprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zaptest"
+)
+
+const testBinaryImages = `[
+  {"name": "/private/var/containers/Bundle/Application/3F2C/Chateaux Bufeaux.app/Chateaux Bufeaux", "uuid": "6A8CB813-45F6-3652-AD33-778FD1EAB196", "load_address": "0x102500000", "size": 65536},
+  {"name": "/usr/lib/libobjc.A.dylib", "uuid": "7821F73C-378B-3A10-BE90-EF526B7DBA93", "load_address": "0x180a79a64"}
+]`
+
+func TestParseReturnAddresses(t *testing.T) {
+	addresses, err := parseReturnAddresses(pcommon.NewValueStr("(\n    0x102507548,\n    6452379736\n)"))
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0x102507548, 6452379736}, addresses)
+
+	slice := pcommon.NewValueSlice()
+	slice.Slice().AppendEmpty().SetInt(4333794632)
+	slice.Slice().AppendEmpty().SetStr("0x180a79abc")
+	addresses, err = parseReturnAddresses(slice)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4333794632, 0x180a79abc}, addresses)
+
+	_, err = parseReturnAddresses(pcommon.NewValueStr("(0x102507548, main)"))
+	assert.ErrorIs(t, err, errInvalidReturnAddresses)
+}
+
+func TestProcessReturnAddresses(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	log := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.ReturnAddressesAttributeKey, "(0x102507548, 0x180a79abc, 0x1)")
+	log.Attributes().PutStr(cfg.BinaryImagesAttributeKey, testBinaryImages)
+
+	_, err := processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	stackTrace, ok := log.Attributes().Get(cfg.StackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, `0   Chateaux Bufeaux                    0x0000000102507548 main (in Chateaux Bufeaux) (MyFile.swift:1) + 30024
+1   libobjc.A.dylib                     0x0000000180a79abc libobjc.A.dylib + 88
+2   ???                                 0x0000000000000001 ??? + 0`, stackTrace.Str())
+
+	statuses, _ := log.Attributes().Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "missing_dsym", "missing_dsym"}, statuses.Slice().AsRaw())
+
+	failed, _ := log.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+}
+
+func TestProcessCallStackSymbols(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &testSymbolicator{}, tb, attributes)
+
+	stacktrace := `0   CoreFoundation                      0x00000001835df228 __exceptionPreprocess + 164
+1   libobjc.A.dylib                     0x0000000180a79abc objc_exception_throw + 88
+2   Chateaux Bufeaux                    0x0000000102507548 $s16Chateaux_Bufeaux11ContentViewV4bodyQrvg + 120
+3   Chateaux Bufeaux                    0x0000000102507648 -[CBViewController viewDidLoad:] + 40`
+
+	logs := plog.NewLogs()
+	resourceLog := logs.ResourceLogs().AppendEmpty()
+	resourceLog.Resource().Attributes().PutStr(cfg.BuildUUIDAttributeKey, "6A8CB813-45F6-3652-AD33-778FD1EAB196")
+	resourceLog.Resource().Attributes().PutStr(cfg.AppExecutableAttributeKey, "Chateaux Bufeaux")
+	log := resourceLog.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.StackTraceAttributeKey, stacktrace)
+	log.Attributes().PutStr(cfg.BinaryImagesAttributeKey, testBinaryImages)
+
+	_, err := processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	symbolicated, ok := log.Attributes().Get(cfg.StackTraceAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, `0   CoreFoundation                      0x00000001835df228 __exceptionPreprocess + 164
+1   libobjc.A.dylib                     0x0000000180a79abc objc_exception_throw + 88
+2   Chateaux Bufeaux                    0x0000000102507548 main (in Chateaux Bufeaux) (MyFile.swift:1) + 30024
+3   Chateaux Bufeaux                    0x0000000102507648 main (in Chateaux Bufeaux) (MyFile.swift:1) + 30280`, symbolicated.Str())
+}
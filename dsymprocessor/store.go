@@ -2,39 +2,260 @@ package dsymprocessor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	lru "github.com/hashicorp/golang-lru/v2"
 	"go.uber.org/zap"
 )
 
 var (
-	errFailedToFindDSYM = fmt.Errorf("failed to find dSYM file")
+	errFailedToFindDSYM        = fmt.Errorf("failed to find dSYM file")
+	errFailedToFindBCSymbolMap = fmt.Errorf("failed to find bcsymbolmap file")
+	errFailedToFindIL2CPPLines = fmt.Errorf("failed to find IL2CPP line mappings file")
+
+	// originalUUIDRegex matches the UUID of the original bitcode build in the
+	// <uuid>.plist that App Store Connect adds to recompiled dSYMs.
+	originalUUIDRegex = regexp.MustCompile(`<key>DBGOriginalUUID</key>\s*<string>([0-9A-Fa-f\-]+)</string>`)
 )
 
 type store struct {
 	fetch  func(ctx context.Context, key string) ([]byte, error)
 	logger *zap.Logger
 	prefix string
+
+	// layouts are the names of the storeLayouts tried, in order, to find a dSYM.
+	layouts []string
+
+	// zips holds recently downloaded dSYM zip archives by key, up to
+	// dsymZipCacheBytes. zipIndex maps every UUID they contain to that key, and
+	// outlives the archives so they can be downloaded again. zipMisses holds
+	// when the keys of archives that don't exist were last looked up.
+	mu        sync.Mutex
+	zips      *lru.Cache[string, *dsymZip]
+	zipBytes  int64
+	zipIndex  *lru.Cache[string, string]
+	zipMisses *lru.Cache[string, time.Time]
+}
+
+func newStore(logger *zap.Logger, prefix string, fetch func(ctx context.Context, key string) ([]byte, error)) *store {
+	s := &store{
+		fetch:   fetch,
+		logger:  logger,
+		prefix:  prefix,
+		layouts: []string{storeLayoutDSYM},
+	}
+
+	// the sizes are positive constants, so these can't fail. zips is bounded by
+	// dsymZipCacheBytes in addZip rather than by its number of archives.
+	s.zips, _ = lru.NewWithEvict(dsymZipIndexSize, func(_ string, z *dsymZip) {
+		s.zipBytes -= z.size
+	})
+	s.zipIndex, _ = lru.New[string, string](dsymZipIndexSize)
+	s.zipMisses, _ = lru.New[string, time.Time](dsymZipIndexSize)
+
+	return s
 }
 
+// GetDSYM fetches the DWARF file of a binary. It is looked up in the zip
+// archives already indexed first, then in each of the store's layouts in order.
 func (s *store) GetDSYM(ctx context.Context, debugId, binaryName string) ([]byte, error) {
-	path := filepath.Join(s.prefix, fmt.Sprintf("%s.dSYM", debugId), "Contents", "Resources", "DWARF", binaryName)
-	dsymBytes, err := s.fetch(ctx, path)
+	if dsymBytes, ok := s.getFromZips(ctx, debugId, binaryName); ok {
+		return dsymBytes, nil
+	}
+
+	paths := make([]string, 0, len(s.layouts))
+	for _, layout := range s.layouts {
+		path := filepath.Join(s.prefix, storeLayouts[layout](debugId, binaryName))
+		paths = append(paths, path)
+
+		if dsymBytes, err := s.fetch(ctx, path); err == nil {
+			return dsymBytes, nil
+		}
+
+		if layout == storeLayoutDSYM {
+			zipPath := s.zipPath(debugId)
+			paths = append(paths, zipPath)
 
+			if z, ok := s.getZip(ctx, zipPath); ok {
+				if dsymBytes, ok := z.get(debugId, binaryName); ok {
+					return dsymBytes, nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", errFailedToFindDSYM, strings.Join(paths, ", "))
+}
+
+// IndexDSYMZip downloads and indexes the zip archive named after the UUID of
+// an app build, if the store has one, so that the frameworks zipped with the
+// app are found whichever binary is looked up first.
+func (s *store) IndexDSYMZip(ctx context.Context, debugId string) {
+	if !slices.Contains(s.layouts, storeLayoutDSYM) || s.zipIndex.Contains(strings.ToUpper(debugId)) {
+		return
+	}
+
+	s.getZip(ctx, s.zipPath(debugId))
+}
+
+func (s *store) zipPath(debugId string) string {
+	return filepath.Join(s.prefix, fmt.Sprintf("%s.dSYM.zip", debugId))
+}
+
+func (s *store) getFromZips(ctx context.Context, debugId, binaryName string) ([]byte, bool) {
+	key, ok := s.zipIndex.Get(strings.ToUpper(debugId))
+	if !ok {
+		return nil, false
+	}
+
+	z, ok := s.getZip(ctx, key)
+	if !ok {
+		return nil, false
+	}
+
+	return z.get(debugId, binaryName)
+}
+
+// getZip returns a dSYM zip archive, downloading and indexing it if it isn't
+// cached. Archives that don't exist or can't be read are not downloaded
+// again for dsymZipMissTTL.
+func (s *store) getZip(ctx context.Context, zipPath string) (*dsymZip, bool) {
+	if z, ok := s.zips.Get(zipPath); ok {
+		return z, true
+	}
+	if missed, ok := s.zipMisses.Get(zipPath); ok && time.Since(missed) < dsymZipMissTTL {
+		return nil, false
+	}
+
+	zipBytes, err := s.fetch(ctx, zipPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			s.zipMisses.Add(zipPath, time.Now())
+		}
+		return nil, false
+	}
+
+	z, err := newDSYMZip(zipBytes)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", errFailedToFindDSYM, path)
+		s.logger.Debug("could not read dSYM zip archive", zap.String("path", zipPath), zap.Error(err))
+		s.zipMisses.Add(zipPath, time.Now())
+		return nil, false
+	}
+
+	s.addZip(zipPath, z)
+	return z, true
+}
+
+// addZip indexes a downloaded zip archive and caches it, evicting the least
+// recently used archives beyond dsymZipCacheBytes.
+func (s *store) addZip(zipPath string, z *dsymZip) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, uuid := range z.uuids() {
+		s.zipIndex.Add(uuid, zipPath)
+	}
+
+	// another lookup may have downloaded the same archive meanwhile
+	if s.zips.Contains(zipPath) {
+		return
+	}
+
+	s.zips.Add(zipPath, z)
+	s.zipBytes += z.size
+	for s.zipBytes > dsymZipCacheBytes && s.zips.Len() > 1 {
+		s.zips.RemoveOldest()
+	}
+}
+
+// GetBCSymbolMap fetches the bcsymbolmap of a dSYM built from bitcode, trying
+// each of the store's layouts that can hold one in order. Most dSYMs have none,
+// so errFailedToFindBCSymbolMap is only returned when none of them had it;
+// any other error means it couldn't be fetched.
+func (s *store) GetBCSymbolMap(ctx context.Context, debugId string) ([]byte, error) {
+	var paths []string
+	var fetchErr error
+
+	for _, layout := range s.layouts {
+		l, ok := bcSymbolMapLayouts[layout]
+		if !ok {
+			continue
+		}
+
+		// dSYMs recompiled from bitcode by App Store Connect have a new UUID, but
+		// the symbol map is named after the UUID of the original build.
+		mapId := debugId
+		if plist, err := s.fetch(ctx, filepath.Join(s.prefix, l.uuidMap(debugId))); err == nil {
+			if matches := originalUUIDRegex.FindSubmatch(plist); matches != nil {
+				mapId = string(matches[1])
+			}
+		}
+
+		path := filepath.Join(s.prefix, l.symbolMap(mapId))
+		paths = append(paths, path)
+
+		mapBytes, err := s.fetch(ctx, path)
+		if err == nil {
+			return mapBytes, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			fetchErr = err
+		}
 	}
 
-	return dsymBytes, nil
+	if fetchErr != nil {
+		return nil, fmt.Errorf("failed to fetch bcsymbolmap file: %w", fetchErr)
+	}
+	return nil, fmt.Errorf("%w: %s", errFailedToFindBCSymbolMap, strings.Join(paths, ", "))
+}
 
+// GetIL2CPPLineMappings fetches the LineNumberMappings.json that Unity writes
+// for IL2CPP builds, stored under the UUID of the binary it was built into, eg.
+// 6A8CB813-45F6-3652-AD33-778FD1EAB196/LineNumberMappings.json, trying each of
+// the store's layouts that can hold one in order. Most C++ binaries aren't
+// Unity builds, so errFailedToFindIL2CPPLines is only returned when none of
+// them had it; any other error means it couldn't be fetched.
+func (s *store) GetIL2CPPLineMappings(ctx context.Context, debugId string) ([]byte, error) {
+	var paths []string
+	var fetchErr error
+
+	for _, layout := range s.layouts {
+		l, ok := il2cppLineMappingsLayouts[layout]
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(s.prefix, l(debugId))
+		paths = append(paths, path)
+
+		mappingBytes, err := s.fetch(ctx, path)
+		if err == nil {
+			return mappingBytes, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			fetchErr = err
+		}
+	}
+
+	if fetchErr != nil {
+		return nil, fmt.Errorf("failed to fetch IL2CPP line mappings file: %w", fetchErr)
+	}
+	return nil, fmt.Errorf("%w: %s", errFailedToFindIL2CPPLines, strings.Join(paths, ", "))
 }
 
 func newFileStore(_ context.Context, logger *zap.Logger, cfg *LocalDSYMConfiguration) (*store, error) {
@@ -42,13 +263,9 @@ func newFileStore(_ context.Context, logger *zap.Logger, cfg *LocalDSYMConfigura
 		return nil, fmt.Errorf("no file configuration provided")
 	}
 
-	return &store{
-		fetch: func(ctx context.Context, key string) ([]byte, error) {
-			return os.ReadFile(key)
-		},
-		logger: logger,
-		prefix: cfg.Path,
-	}, nil
+	return newStore(logger, cfg.Path, func(ctx context.Context, key string) ([]byte, error) {
+		return os.ReadFile(key)
+	}), nil
 }
 
 func newS3Store(ctx context.Context, logger *zap.Logger, cfg *S3DSYMConfiguration) (*store, error) {
@@ -70,26 +287,26 @@ func newS3Store(ctx context.Context, logger *zap.Logger, cfg *S3DSYMConfiguratio
 
 	client := s3.NewFromConfig(awsConfig)
 
-	return &store{
-		fetch: func(ctx context.Context, key string) ([]byte, error) {
-			key = strings.TrimPrefix(key, "/")
+	return newStore(logger, cfg.Prefix, func(ctx context.Context, key string) ([]byte, error) {
+		key = strings.TrimPrefix(key, "/")
 
-			result, err := client.GetObject(ctx, &s3.GetObjectInput{
-				Bucket: aws.String(cfg.BucketName),
-				Key:    aws.String(key),
-			})
+		result, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(cfg.BucketName),
+			Key:    aws.String(key),
+		})
 
-			if err != nil {
-				return nil, err
+		if err != nil {
+			var noSuchKey *types.NoSuchKey
+			if errors.As(err, &noSuchKey) {
+				return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
 			}
+			return nil, err
+		}
 
-			defer result.Body.Close()
+		defer result.Body.Close()
 
-			return io.ReadAll(result.Body)
-		},
-		logger: logger,
-		prefix: cfg.Prefix,
-	}, nil
+		return io.ReadAll(result.Body)
+	}), nil
 }
 
 func newGCSStore(ctx context.Context, logger *zap.Logger, cfg *GCSDSYMConfiguration) (*store, error) {
@@ -105,22 +322,21 @@ func newGCSStore(ctx context.Context, logger *zap.Logger, cfg *GCSDSYMConfigurat
 
 	bucket := client.Bucket(cfg.BucketName)
 
-	return &store{
-		fetch: func(ctx context.Context, key string) ([]byte, error) {
-			// GCS keys can't start with a slash
-			key = strings.TrimPrefix(key, "/")
+	return newStore(logger, cfg.Prefix, func(ctx context.Context, key string) ([]byte, error) {
+		// GCS keys can't start with a slash
+		key = strings.TrimPrefix(key, "/")
 
-			r, err := bucket.Object(key).NewReader(ctx)
+		r, err := bucket.Object(key).NewReader(ctx)
 
-			if err != nil {
-				return nil, err
-			}
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+		}
+		if err != nil {
+			return nil, err
+		}
 
-			defer r.Close()
+		defer r.Close()
 
-			return io.ReadAll(r)
-		},
-		logger: logger,
-		prefix: cfg.Prefix,
-	}, nil
+		return io.ReadAll(r)
+	}), nil
 }
@@ -0,0 +1,107 @@
+package dsymprocessor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// storeLayoutDSYM is <debugId>.dSYM/Contents/Resources/DWARF/<binaryName>,
+	// falling back to a <debugId>.dSYM.zip archive.
+	storeLayoutDSYM = "dsym"
+	// storeLayoutSymsorter is the symsorter (unified) layout used by Sentry's
+	// tools and symbol servers: ab/cdef.../debuginfo.
+	storeLayoutSymsorter = "symsorter"
+	// storeLayoutSymStore is the Microsoft SymStore layout for Mach-O debug
+	// companions: _.dwarf/mach-uuid-sym-<uuid>/_.dwarf.
+	storeLayoutSymStore = "symstore"
+	// storeLayoutBreakpad is the Breakpad symbol server layout:
+	// <binaryName>/<ID>/<binaryName>.sym.
+	storeLayoutBreakpad = "breakpad"
+)
+
+// storeLayouts resolve the key of a debug file, relative to the store prefix,
+// for each supported layout.
+var storeLayouts = map[string]func(debugId, binaryName string) string{
+	storeLayoutDSYM: func(debugId, binaryName string) string {
+		return filepath.Join(fmt.Sprintf("%s.dSYM", debugId), "Contents", "Resources", "DWARF", binaryName)
+	},
+	storeLayoutSymsorter: func(debugId, _ string) string {
+		return symsorterPath(debugId, "debuginfo")
+	},
+	storeLayoutSymStore: func(debugId, _ string) string {
+		id := strings.ToLower(strings.ReplaceAll(debugId, "-", ""))
+		return filepath.Join("_.dwarf", "mach-uuid-sym-"+id, "_.dwarf")
+	},
+	storeLayoutBreakpad: func(debugId, binaryName string) string {
+		// Breakpad IDs are the uppercase UUID followed by the age, which is
+		// always 0 for Mach-O files.
+		id := strings.ToUpper(strings.ReplaceAll(debugId, "-", "")) + "0"
+		return filepath.Join(binaryName, id, binaryName+".sym")
+	},
+}
+
+// symsorterPath is the key of one of the files of a debug ID in the symsorter
+// layout, eg. ab/cdef.../debuginfo.
+func symsorterPath(debugId, file string) string {
+	id := strings.ToLower(strings.ReplaceAll(debugId, "-", ""))
+	if len(id) < 3 {
+		return filepath.Join(id, file)
+	}
+	return filepath.Join(id[:2], id[2:], file)
+}
+
+// bcSymbolMapLayout resolves the keys of the files of a build from bitcode,
+// relative to the store prefix.
+type bcSymbolMapLayout struct {
+	// uuidMap is the plist that maps the UUID of a dSYM recompiled by App Store
+	// Connect to the UUID of the original build.
+	uuidMap func(debugId string) string
+	// symbolMap is the bcsymbolmap of the original build.
+	symbolMap func(debugId string) string
+}
+
+// bcSymbolMapLayouts are the store layouts that can hold bcsymbolmaps. The
+// symstore and breakpad layouts only hold debug files.
+var bcSymbolMapLayouts = map[string]bcSymbolMapLayout{
+	storeLayoutDSYM: {
+		uuidMap: func(debugId string) string {
+			return filepath.Join(fmt.Sprintf("%s.dSYM", debugId), "Contents", "Resources", fmt.Sprintf("%s.plist", debugId))
+		},
+		symbolMap: func(debugId string) string {
+			return fmt.Sprintf("%s.bcsymbolmap", debugId)
+		},
+	},
+	storeLayoutSymsorter: {
+		uuidMap: func(debugId string) string {
+			return symsorterPath(debugId, "uuidmap")
+		},
+		symbolMap: func(debugId string) string {
+			return symsorterPath(debugId, "bcsymbolmap")
+		},
+	},
+}
+
+// il2cppLineMappingsLayouts resolve the key of the LineNumberMappings.json of an
+// IL2CPP build, relative to the store prefix, for the store layouts that can
+// hold one. The symstore and breakpad layouts only hold debug files.
+var il2cppLineMappingsLayouts = map[string]func(debugId string) string{
+	storeLayoutDSYM: func(debugId string) string {
+		return filepath.Join(debugId, "LineNumberMappings.json")
+	},
+	storeLayoutSymsorter: func(debugId string) string {
+		return symsorterPath(debugId, "il2cpp")
+	},
+}
+
+// validateStoreLayouts checks that every layout is supported.
+func validateStoreLayouts(layouts []string) error {
+	for _, layout := range layouts {
+		if _, ok := storeLayouts[layout]; !ok {
+			return fmt.Errorf("unknown dsym store layout %q, must be one of %q, %q, %q or %q",
+				layout, storeLayoutDSYM, storeLayoutSymsorter, storeLayoutSymStore, storeLayoutBreakpad)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package dsymprocessor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestStoreLayouts(t *testing.T) {
+	debugId := "6A8CB813-45F6-3652-AD33-778FD1EAB196"
+
+	tests := []struct {
+		layout   string
+		expected string
+	}{
+		{storeLayoutDSYM, "6A8CB813-45F6-3652-AD33-778FD1EAB196.dSYM/Contents/Resources/DWARF/Chateaux Bufeaux"},
+		{storeLayoutSymsorter, "6a/8cb81345f63652ad33778fd1eab196/debuginfo"},
+		{storeLayoutSymStore, "_.dwarf/mach-uuid-sym-6a8cb81345f63652ad33778fd1eab196/_.dwarf"},
+		{storeLayoutBreakpad, "Chateaux Bufeaux/6A8CB81345F63652AD33778FD1EAB1960/Chateaux Bufeaux.sym"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			assert.Equal(t, tt.expected, storeLayouts[tt.layout](debugId, "Chateaux Bufeaux"))
+		})
+	}
+}
+
+func TestValidateStoreLayouts(t *testing.T) {
+	assert.NoError(t, validateStoreLayouts([]string{storeLayoutSymsorter, storeLayoutDSYM}))
+	assert.Error(t, validateStoreLayouts([]string{"flat"}))
+}
+
+func TestFileStoreLayoutOrder(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "6a", "8cb81345f63652ad33778fd1eab196", "debuginfo")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte("debuginfo"), 0o600))
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDSYMConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	_, err = fs.GetDSYM(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196", "Chateaux Bufeaux")
+	assert.ErrorIs(t, err, errFailedToFindDSYM)
+
+	fs.layouts = []string{storeLayoutDSYM, storeLayoutSymsorter}
+
+	source, err := fs.GetDSYM(ctx, "6A8CB813-45F6-3652-AD33-778FD1EAB196", "Chateaux Bufeaux")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("debuginfo"), source)
+}
@@ -0,0 +1,64 @@
+package dsymprocessor
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// structuredStackTrace is the symbolicated stack trace written as parallel
+// slice attributes, one entry per symbolicated location. An address that maps
+// to several inlined locations gets one entry for each of them, innermost
+// first, followed by the real function they were inlined into.
+type structuredStackTrace struct {
+	binaries  pcommon.Slice
+	functions pcommon.Slice
+	files     pcommon.Slice
+	lines     pcommon.Slice
+	statuses  pcommon.Slice
+	// inlineDepths is 0 for real functions, and the number of functions an
+	// inlined location is nested in otherwise.
+	inlineDepths pcommon.Slice
+	// inlineParents is the index of the entry an inlined location was inlined
+	// into, or -1 for real functions.
+	inlineParents pcommon.Slice
+}
+
+func (sp *symbolicatorProcessor) newStructuredStackTrace(attributes pcommon.Map) *structuredStackTrace {
+	return &structuredStackTrace{
+		binaries:      attributes.PutEmptySlice(sp.cfg.OutputStackTraceBinariesAttributeKey),
+		functions:     attributes.PutEmptySlice(sp.cfg.OutputStackTraceFunctionsAttributeKey),
+		files:         attributes.PutEmptySlice(sp.cfg.OutputStackTraceFilesAttributeKey),
+		lines:         attributes.PutEmptySlice(sp.cfg.OutputStackTraceLinesAttributeKey),
+		statuses:      attributes.PutEmptySlice(sp.cfg.OutputStackTraceFrameStatusesAttributeKey),
+		inlineDepths:  attributes.PutEmptySlice(sp.cfg.OutputStackTraceInlineDepthsAttributeKey),
+		inlineParents: attributes.PutEmptySlice(sp.cfg.OutputStackTraceInlineParentsAttributeKey),
+	}
+}
+
+// appendFrame appends a frame's locations. A frame that wasn't symbolicated
+// gets a single entry with just its binary.
+func (s *structuredStackTrace) appendFrame(binaryName string, locations []*mappedDSYMStackFrame, status frameStatus) {
+	if len(locations) == 0 {
+		s.appendLocation(binaryName, "", "", 0, status, 0, -1)
+		return
+	}
+
+	base := s.binaries.Len()
+	for i, loc := range locations {
+		depth := len(locations) - 1 - i
+		parent := -1
+		if depth > 0 {
+			parent = base + i + 1
+		}
+		s.appendLocation(binaryName, loc.symbol, loc.path, loc.line, status, depth, parent)
+	}
+}
+
+func (s *structuredStackTrace) appendLocation(binaryName, function, file string, line uint32, status frameStatus, inlineDepth, inlineParent int) {
+	s.binaries.AppendEmpty().SetStr(binaryName)
+	s.functions.AppendEmpty().SetStr(function)
+	s.files.AppendEmpty().SetStr(file)
+	s.lines.AppendEmpty().SetInt(int64(line))
+	s.statuses.AppendEmpty().SetStr(string(status))
+	s.inlineDepths.AppendEmpty().SetInt(int64(inlineDepth))
+	s.inlineParents.AppendEmpty().SetInt(int64(inlineParent))
+}
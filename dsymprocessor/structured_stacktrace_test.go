@@ -0,0 +1,69 @@
+package dsymprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zaptest"
+)
+
+// inliningSymbolicator symbolicates every address to a function with two
+// levels of inlined functions, innermost first, as symCache.Lookup does.
+type inliningSymbolicator struct{}
+
+func (is *inliningSymbolicator) indexBuild(ctx context.Context, debugId string) {}
+
+func (is *inliningSymbolicator) symbolicateFrame(ctx context.Context, debugId, binaryName string, addr uint64) ([]*mappedDSYMStackFrame, error) {
+	if debugId != "6A8CB813-45F6-3652-AD33-778FD1EAB196" {
+		return nil, errFailedToFindDSYM
+	}
+	return []*mappedDSYMStackFrame{
+		{path: "Array.swift", line: 12, symbol: "Array.subscript.getter"},
+		{path: "Model.swift", line: 40, symbol: "Model.first()"},
+		{path: "ContentView.swift", line: 7, symbol: "ContentView.body.getter"},
+	}, nil
+}
+
+func TestFormatStackFramesInlined(t *testing.T) {
+	locations, err := (&inliningSymbolicator{}).symbolicateFrame(context.Background(), "6A8CB813-45F6-3652-AD33-778FD1EAB196", "Chateaux Bufeaux", 30024)
+	require.NoError(t, err)
+
+	assert.Equal(t, `3   Chateaux Bufeaux 0x0000000102507548 Array.subscript.getter (in Chateaux Bufeaux) (Array.swift:12) + 30024 [inlined]
+3   Chateaux Bufeaux 0x0000000102507548 Model.first() (in Chateaux Bufeaux) (Model.swift:40) + 30024 [inlined]
+3   Chateaux Bufeaux 0x0000000102507548 ContentView.body.getter (in Chateaux Bufeaux) (ContentView.swift:7) + 30024`,
+		formatStackFrames("3   Chateaux Bufeaux 0x0000000102507548", "Chateaux Bufeaux", 30024, locations))
+}
+
+func TestProcessCrashReportInlined(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, &inliningSymbolicator{}, tb, attributes)
+
+	logs := plog.NewLogs()
+	log := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	log.Attributes().PutStr(cfg.ReturnAddressesAttributeKey, "(0x180a79abc, 0x102507548)")
+	log.Attributes().PutStr(cfg.BinaryImagesAttributeKey, testBinaryImages)
+
+	_, err := processor.processLogs(ctx, logs)
+	require.NoError(t, err)
+
+	functions, _ := log.Attributes().Get(cfg.OutputStackTraceFunctionsAttributeKey)
+	assert.Equal(t, []any{"", "Array.subscript.getter", "Model.first()", "ContentView.body.getter"}, functions.Slice().AsRaw())
+	depths, _ := log.Attributes().Get(cfg.OutputStackTraceInlineDepthsAttributeKey)
+	assert.Equal(t, []any{int64(0), int64(2), int64(1), int64(0)}, depths.Slice().AsRaw())
+	parents, _ := log.Attributes().Get(cfg.OutputStackTraceInlineParentsAttributeKey)
+	assert.Equal(t, []any{int64(-1), int64(2), int64(3), int64(-1)}, parents.Slice().AsRaw())
+}
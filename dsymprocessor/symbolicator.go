@@ -2,6 +2,7 @@ package dsymprocessor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -30,20 +31,44 @@ func (e *FetchError) Unwrap() error {
 
 type dsymStore interface {
 	GetDSYM(ctx context.Context, debugId, binaryName string) ([]byte, error)
+	GetBCSymbolMap(ctx context.Context, debugId string) ([]byte, error)
+	GetIL2CPPLineMappings(ctx context.Context, debugId string) ([]byte, error)
+	IndexDSYMZip(ctx context.Context, debugId string)
+}
+
+// dsymArchive is a cached dSYM, along with the bcsymbolmap that resolves its
+// hidden symbols if it was built from bitcode, and the line mappings of its
+// IL2CPP generated code if it is a Unity build.
+type dsymArchive struct {
+	archive *symbolic.Archive
+
+	// symbolMap is only fetched the first time a hidden symbol is found, and
+	// is nil if the dSYM has no hidden symbols or no bcsymbolmap is available.
+	symbolMap        *bcSymbolMap
+	symbolMapFetched bool
+
+	// il2cppLines is only fetched the first time a C++ location is found, and
+	// is nil if no line mappings are available.
+	il2cppLines        *il2cppLineMappings
+	il2cppLinesFetched bool
 }
 
 type basicSymbolicator struct {
 	store   dsymStore
 	timeout time.Duration
 	ch      chan struct{}
-	cache   *lru.Cache[string, *symbolic.Archive]
+	cache   *lru.Cache[string, *dsymArchive]
+
+	// il2cppLineMappings enables mapping IL2CPP generated C++ locations back
+	// to the C# they were generated from.
+	il2cppLineMappings bool
 
 	telemetryBuilder *metadata.TelemetryBuilder
 	attributes       metric.MeasurementOption
 }
 
 func newBasicSymbolicator(_ context.Context, timeout time.Duration, cacheSize int, store dsymStore, tb *metadata.TelemetryBuilder, attributes attribute.Set) (*basicSymbolicator, error) {
-	cache, err := lru.New[string, *symbolic.Archive](cacheSize)
+	cache, err := lru.New[string, *dsymArchive](cacheSize)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +105,7 @@ func (ns *basicSymbolicator) symbolicateFrame(ctx context.Context, debugId, bina
 	}()
 
 	cacheKey := debugId + "/" + binaryName
-	archive, ok := ns.cache.Get(cacheKey)
+	dsym, ok := ns.cache.Get(cacheKey)
 	ns.telemetryBuilder.ProcessorDsymCacheSize.Record(ctx, int64(ns.cache.Len()), ns.attributes)
 
 	if !ok {
@@ -89,19 +114,20 @@ func (ns *basicSymbolicator) symbolicateFrame(ctx context.Context, debugId, bina
 			ns.telemetryBuilder.ProcessorTotalDsymFetchFailures.Add(ctx, 1, ns.attributes)
 			return nil, &FetchError{DebugID: debugId, Err: err}
 		}
-		archive, err = symbolic.NewArchiveFromBytes(dSYMbytes)
+		archive, err := symbolic.NewArchiveFromBytes(dSYMbytes)
 
 		if err != nil {
 			return nil, err
 		}
 
-		ns.cache.Add(cacheKey, archive)
+		dsym = &dsymArchive{archive: archive}
+		ns.cache.Add(cacheKey, dsym)
 	}
 
 	// If the cache size has changed, we should record the new size
 	ns.telemetryBuilder.ProcessorDsymCacheSize.Record(ctx, int64(ns.cache.Len()), ns.attributes)
 
-	symCache, ok := archive.SymCaches[strings.ToLower(debugId)]
+	symCache, ok := dsym.archive.SymCaches[strings.ToLower(debugId)]
 	if !ok {
 		return nil, fmt.Errorf("could not find symcache for uuid %s", debugId)
 	}
@@ -126,5 +152,96 @@ func (ns *basicSymbolicator) symbolicateFrame(ctx context.Context, debugId, bina
 			symbol:    loc.Symbol,
 		}
 	}
+
+	if hasHiddenSymbols(res) {
+		ns.resolveHiddenSymbols(ctx, dsym, debugId, res)
+	}
+
+	if ns.il2cppLineMappings && hasIL2CPPFrames(res) {
+		ns.resolveIL2CPPLines(ctx, dsym, debugId, res)
+	}
+
 	return res, nil
 }
+
+// indexBuild looks up the zipped dSYMs of an app build before any of its
+// frames, so the frameworks zipped with the app are found in any order.
+func (ns *basicSymbolicator) indexBuild(ctx context.Context, debugId string) {
+	select {
+	case ns.ch <- struct{}{}:
+	case <-time.After(ns.timeout):
+		return
+	}
+
+	defer func() {
+		<-ns.ch
+	}()
+
+	ns.store.IndexDSYMZip(ctx, debugId)
+}
+
+// resolveHiddenSymbols replaces the hidden symbol and file names of a dSYM built
+// from bitcode using its bcsymbolmap. The symbol map is fetched once per cached
+// dSYM; if it can't be found the hidden names are left as-is.
+func (ns *basicSymbolicator) resolveHiddenSymbols(ctx context.Context, dsym *dsymArchive, debugId string, frames []*mappedDSYMStackFrame) {
+	if !dsym.symbolMapFetched {
+		dsym.symbolMapFetched = true
+
+		mapBytes, err := ns.store.GetBCSymbolMap(ctx, debugId)
+		if err != nil {
+			// most dSYMs aren't built from bitcode, so not having a symbol map
+			// isn't a failure
+			if !errors.Is(err, errFailedToFindBCSymbolMap) {
+				ns.telemetryBuilder.ProcessorTotalDsymFetchFailures.Add(ctx, 1, ns.attributes)
+			}
+			return
+		}
+
+		dsym.symbolMap, err = parseBCSymbolMap(mapBytes)
+		if err != nil {
+			return
+		}
+	}
+
+	if dsym.symbolMap == nil {
+		return
+	}
+
+	for _, frame := range frames {
+		frame.symbol = dsym.symbolMap.resolve(frame.symbol)
+		frame.path = dsym.symbolMap.resolve(frame.path)
+	}
+}
+
+// resolveIL2CPPLines replaces the C++ locations of a Unity IL2CPP build with the
+// C# method, file and line they were generated from. The line mappings are
+// fetched once per cached dSYM; if they can't be found the C++ locations are
+// left as-is.
+func (ns *basicSymbolicator) resolveIL2CPPLines(ctx context.Context, dsym *dsymArchive, debugId string, frames []*mappedDSYMStackFrame) {
+	if !dsym.il2cppLinesFetched {
+		dsym.il2cppLinesFetched = true
+
+		mappingBytes, err := ns.store.GetIL2CPPLineMappings(ctx, debugId)
+		if err != nil {
+			// most C++ binaries aren't Unity builds, so not having line
+			// mappings isn't a failure
+			if !errors.Is(err, errFailedToFindIL2CPPLines) {
+				ns.telemetryBuilder.ProcessorTotalDsymFetchFailures.Add(ctx, 1, ns.attributes)
+			}
+			return
+		}
+
+		dsym.il2cppLines, err = parseIL2CPPLineMappings(mappingBytes)
+		if err != nil {
+			return
+		}
+	}
+
+	if dsym.il2cppLines == nil {
+		return
+	}
+
+	for _, frame := range frames {
+		dsym.il2cppLines.resolve(frame)
+	}
+}
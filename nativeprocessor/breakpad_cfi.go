@@ -0,0 +1,275 @@
+package nativeprocessor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	cfiInitPrefix  = "STACK CFI INIT "
+	cfiDeltaPrefix = "STACK CFI "
+	// cfiCFA is the canonical frame address, the caller's stack pointer.
+	cfiCFA = ".cfa"
+	// cfiRA is the return address, the caller's program counter.
+	cfiRA = ".ra"
+)
+
+// breakpadCFI is the call frame information of a Breakpad symbol file, which
+// describes how to recover the caller's registers at every address, eg.
+//
+//	STACK CFI INIT 1026 10 .cfa: $rsp 8 + .ra: .cfa -8 + ^
+//	STACK CFI 102a .cfa: $rsp 16 +
+type breakpadCFI struct {
+	// entries are sorted by start address.
+	entries []cfiEntry
+}
+
+type cfiEntry struct {
+	start uint64
+	size  uint64
+	rules string
+	// deltas change the rules from their address to the end of the entry.
+	deltas []cfiDelta
+}
+
+type cfiDelta struct {
+	addr  uint64
+	rules string
+}
+
+// parseBreakpadCFI reads the STACK CFI records of a Breakpad symbol file.
+func parseBreakpadCFI(sym []byte) *breakpadCFI {
+	cfi := &breakpadCFI{}
+	scanner := bufio.NewScanner(bytes.NewReader(sym))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, cfiInitPrefix):
+			fields := strings.SplitN(strings.TrimPrefix(line, cfiInitPrefix), " ", 3)
+			if len(fields) < 3 {
+				continue
+			}
+			start, err := strconv.ParseUint(fields[0], 16, 64)
+			if err != nil {
+				continue
+			}
+			size, err := strconv.ParseUint(fields[1], 16, 64)
+			if err != nil {
+				continue
+			}
+			cfi.entries = append(cfi.entries, cfiEntry{start: start, size: size, rules: fields[2]})
+		case strings.HasPrefix(line, cfiDeltaPrefix):
+			fields := strings.SplitN(strings.TrimPrefix(line, cfiDeltaPrefix), " ", 2)
+			if len(fields) < 2 || len(cfi.entries) == 0 {
+				continue
+			}
+			addr, err := strconv.ParseUint(fields[0], 16, 64)
+			if err != nil {
+				continue
+			}
+			// deltas always follow the INIT record they belong to
+			entry := &cfi.entries[len(cfi.entries)-1]
+			entry.deltas = append(entry.deltas, cfiDelta{addr: addr, rules: fields[1]})
+		}
+	}
+
+	sort.Slice(cfi.entries, func(i, j int) bool {
+		return cfi.entries[i].start < cfi.entries[j].start
+	})
+
+	return cfi
+}
+
+// rules returns the rules that recover each of the caller's registers at an
+// address relative to the module, keyed by register name, or false if the
+// address has no call frame information.
+func (c *breakpadCFI) rules(addr uint64) (map[string]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	idx := sort.Search(len(c.entries), func(i int) bool {
+		return c.entries[i].start > addr
+	}) - 1
+	if idx < 0 || addr >= c.entries[idx].start+c.entries[idx].size {
+		return nil, false
+	}
+
+	entry := c.entries[idx]
+	rules := parseCFIRules(entry.rules)
+	for _, delta := range entry.deltas {
+		if delta.addr > addr {
+			break
+		}
+		for register, rule := range parseCFIRules(delta.rules) {
+			rules[register] = rule
+		}
+	}
+
+	return rules, true
+}
+
+// parseCFIRules splits rules such as ".cfa: $rsp 8 + .ra: .cfa -8 + ^" into
+// the postfix expression of each register.
+func parseCFIRules(s string) map[string]string {
+	rules := make(map[string]string)
+	var register string
+	var expr []string
+
+	flush := func() {
+		if register != "" {
+			rules[register] = strings.Join(expr, " ")
+		}
+	}
+
+	for _, token := range strings.Fields(s) {
+		if strings.HasSuffix(token, ":") {
+			flush()
+			register = strings.TrimPrefix(strings.TrimSuffix(token, ":"), "$")
+			expr = nil
+			continue
+		}
+		expr = append(expr, token)
+	}
+	flush()
+
+	return rules
+}
+
+// unwindCFI recovers the caller's registers from the callee's with the given
+// rules, reading saved values from the stack memory in the minidump. The
+// caller's program counter and stack pointer are set from .ra and .cfa.
+func unwindCFI(md *minidump, rules map[string]string, callee registers) (registers, error) {
+	cfaRule, ok := rules[cfiCFA]
+	if !ok {
+		return nil, fmt.Errorf("no %s rule", cfiCFA)
+	}
+	if _, ok := rules[cfiRA]; !ok {
+		return nil, fmt.Errorf("no %s rule", cfiRA)
+	}
+
+	cfa, err := evalCFIRule(md, cfaRule, callee)
+	if err != nil {
+		return nil, err
+	}
+
+	// the other rules can refer to the CFA, but not to each other
+	withCFA := make(registers, len(callee)+1)
+	for register, value := range callee {
+		withCFA[register] = value
+	}
+	withCFA[cfiCFA] = cfa
+
+	caller := make(registers, len(callee))
+	for register, value := range callee {
+		caller[register] = value
+	}
+
+	for register, rule := range rules {
+		if register == cfiCFA {
+			continue
+		}
+		value, err := evalCFIRule(md, rule, withCFA)
+		if err != nil {
+			return nil, err
+		}
+		if register == cfiRA {
+			register = md.pcRegister()
+		}
+		caller[register] = value
+	}
+	caller[md.spRegister()] = cfa
+
+	return caller, nil
+}
+
+// evalCFIRule evaluates a postfix CFI expression, eg. ".cfa -8 + ^". Operands
+// are numbers or registers, ^ dereferences the address on top of the stack,
+// and @ aligns the value below it to the value on top.
+func evalCFIRule(md *minidump, rule string, regs registers) (uint64, error) {
+	stack := make([]uint64, 0, 4)
+
+	pop := func() (uint64, error) {
+		if len(stack) == 0 {
+			return 0, fmt.Errorf("stack underflow evaluating %q", rule)
+		}
+		value := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return value, nil
+	}
+
+	for _, token := range strings.Fields(rule) {
+		switch token {
+		case "^":
+			addr, err := pop()
+			if err != nil {
+				return 0, err
+			}
+			value, ok := md.readU64(addr)
+			if !ok {
+				return 0, fmt.Errorf("address %#x is not in the minidump", addr)
+			}
+			stack = append(stack, value)
+		case "+", "-", "*", "/", "%", "@":
+			b, err := pop()
+			if err != nil {
+				return 0, err
+			}
+			a, err := pop()
+			if err != nil {
+				return 0, err
+			}
+			value, err := applyCFIOperator(token, a, b)
+			if err != nil {
+				return 0, err
+			}
+			stack = append(stack, value)
+		default:
+			if value, ok := regs[strings.TrimPrefix(token, "$")]; ok {
+				stack = append(stack, value)
+				continue
+			}
+			value, err := strconv.ParseInt(token, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("unknown register or value %q", token)
+			}
+			stack = append(stack, uint64(value))
+		}
+	}
+
+	if len(stack) != 1 {
+		return 0, fmt.Errorf("invalid rule %q", rule)
+	}
+	return stack[0], nil
+}
+
+func applyCFIOperator(op string, a, b uint64) (uint64, error) {
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%", "@":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+	}
+
+	switch op {
+	case "/":
+		return uint64(int64(a) / int64(b)), nil
+	case "%":
+		return uint64(int64(a) % int64(b)), nil
+	default:
+		return a &^ (b - 1), nil
+	}
+}
@@ -0,0 +1,64 @@
+package nativeprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBreakpadCFI = `MODULE Linux x86_64 3D2C1B0A5F4E71608293A4B5C6D7E8F90 libnative.so
+FUNC 1026 10 0 on_event
+STACK CFI INIT 1026 10 .cfa: $rsp 8 + .ra: .cfa -8 + ^
+STACK CFI 102a .cfa: $rsp 16 + $rbx: .cfa -16 + ^
+STACK CFI 1035 .cfa: $rsp 8 +
+STACK CFI INIT 1020 6 .cfa: $rsp 8 + .ra: .cfa -8 + ^`
+
+func TestBreakpadCFIRules(t *testing.T) {
+	cfi := parseBreakpadCFI([]byte(testBreakpadCFI))
+	require.Len(t, cfi.entries, 2)
+	assert.Equal(t, uint64(0x1020), cfi.entries[0].start)
+
+	rules, ok := cfi.rules(0x1026)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{".cfa": "$rsp 8 +", ".ra": ".cfa -8 + ^"}, rules)
+
+	rules, ok = cfi.rules(0x102e)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{".cfa": "$rsp 16 +", ".ra": ".cfa -8 + ^", "rbx": ".cfa -16 + ^"}, rules)
+
+	rules, ok = cfi.rules(0x1035)
+	require.True(t, ok)
+	assert.Equal(t, "$rsp 8 +", rules[".cfa"])
+
+	_, ok = cfi.rules(0x1036)
+	assert.False(t, ok)
+	_, ok = cfi.rules(0x1000)
+	assert.False(t, ok)
+}
+
+func TestUnwindCFI(t *testing.T) {
+	md := &minidump{
+		arch: minidumpCPUAMD64,
+		memory: []minidumpMemory{{
+			start: 0x1000,
+			data:  []byte{0x11, 0, 0, 0, 0, 0, 0, 0, 0x22, 0, 0, 0, 0, 0, 0, 0},
+		}},
+	}
+	rules := parseCFIRules(".cfa: $rsp 16 + .ra: .cfa -8 + ^ $rbx: .cfa -16 + ^")
+
+	caller, err := unwindCFI(md, rules, registers{"rip": 0x5000, "rsp": 0x1000, "rbx": 1, "rbp": 2})
+	require.NoError(t, err)
+	assert.Equal(t, registers{"rip": 0x22, "rsp": 0x1010, "rbx": 0x11, "rbp": 2}, caller)
+
+	// saved registers must be in the minidump's memory
+	_, err = unwindCFI(md, rules, registers{"rip": 0x5000, "rsp": 0x2000})
+	assert.Error(t, err)
+
+	value, err := evalCFIRule(md, "rsp 31 + 16 @", registers{"rsp": 0x1000})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x1010), value)
+
+	_, err = evalCFIRule(md, "rsp +", registers{"rsp": 0x1000})
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package nativeprocessor
+
+import (
+	"time"
+)
+
+// Config defines configuration for the native symbolicator processor.
+type Config struct {
+	// SymbolicatorFailureAttributeKey is the attribute key that will be set to
+	// true if the symbolicator fails to fully symbolicate a stack trace.
+	SymbolicatorFailureAttributeKey string `mapstructure:"symbolicator_failure_attribute_key"`
+
+	// SymbolicatorErrorAttributeKey is the attribute key that will be set to
+	// the error message if the symbolicator fails to fully symbolicate a stack trace.
+	SymbolicatorErrorAttributeKey string `mapstructure:"symbolicator_error_attribute_key"`
+
+	// StackTraceAttributeKey is the attribute key that contains the native
+	// backtrace, Windows or .NET stack trace, or obfuscated Dart stack trace,
+	// and that the symbolicated stack trace is populated into.
+	StackTraceAttributeKey string `mapstructure:"stack_trace_attribute_key"`
+
+	// MinidumpAttributeKey is the attribute key that contains a base64 encoded
+	// minidump, which is unwound and symbolicated into the stack trace attribute.
+	MinidumpAttributeKey string `mapstructure:"minidump_attribute_key"`
+
+	// ModulesAttributeKey is the attribute key that contains a JSON array of the
+	// Windows modules loaded into the process, with their name and PDB identifiers.
+	ModulesAttributeKey string `mapstructure:"modules_attribute_key"`
+
+	// ExceptionTypeAttributeKey is the attribute key that the exception type of
+	// a native crash, eg. its signal, is populated into.
+	ExceptionTypeAttributeKey string `mapstructure:"exception_type_attribute_key"`
+
+	// ExceptionMessageAttributeKey is the attribute key that the exception message
+	// of a native crash is populated into.
+	ExceptionMessageAttributeKey string `mapstructure:"exception_message_attribute_key"`
+
+	// OutputStackTraceBinariesAttributeKey is the attribute key that contains the
+	// binary of each symbolicated frame.
+	OutputStackTraceBinariesAttributeKey string `mapstructure:"output_stack_trace_binaries_attribute_key"`
+
+	// OutputStackTraceFunctionsAttributeKey is the attribute key that contains the
+	// function of each symbolicated frame.
+	OutputStackTraceFunctionsAttributeKey string `mapstructure:"output_stack_trace_functions_attribute_key"`
+
+	// OutputStackTraceFilesAttributeKey is the attribute key that contains the
+	// source file of each symbolicated frame.
+	OutputStackTraceFilesAttributeKey string `mapstructure:"output_stack_trace_files_attribute_key"`
+
+	// OutputStackTraceLinesAttributeKey is the attribute key that contains the
+	// line of each symbolicated frame.
+	OutputStackTraceLinesAttributeKey string `mapstructure:"output_stack_trace_lines_attribute_key"`
+
+	// OutputStackTraceFrameStatusesAttributeKey is the attribute key that contains the
+	// symbolication status of each frame.
+	OutputStackTraceFrameStatusesAttributeKey string `mapstructure:"output_stack_trace_frame_statuses_attribute_key"`
+
+	// ClassesAttributeKey is the attribute key that contains the class of each
+	// .NET frame, as the proguard processor writes them for Java frames.
+	ClassesAttributeKey string `mapstructure:"classes_attribute_key"`
+
+	// MethodsAttributeKey is the attribute key that contains the method of each
+	// .NET frame.
+	MethodsAttributeKey string `mapstructure:"methods_attribute_key"`
+
+	// SourceFilesAttributeKey is the attribute key that contains the source file
+	// of each .NET frame. Their lines are written to OutputStackTraceLinesAttributeKey.
+	SourceFilesAttributeKey string `mapstructure:"source_files_attribute_key"`
+
+	// PreserveStackTrace is a config option that determines whether to keep the
+	// original stack trace, or minidump, in the output.
+	PreserveStackTrace bool `mapstructure:"preserve_stack_trace"`
+
+	// OriginalStackTraceAttributeKey is the attribute key that preserves the original stack
+	// trace.
+	OriginalStackTraceAttributeKey string `mapstructure:"original_stack_trace_attribute_key"`
+
+	// OriginalClassesAttributeKey is the attribute key that preserves the original
+	// class of each .NET frame.
+	OriginalClassesAttributeKey string `mapstructure:"original_classes_attribute_key"`
+
+	// OriginalMethodsAttributeKey is the attribute key that preserves the original
+	// method of each .NET frame.
+	OriginalMethodsAttributeKey string `mapstructure:"original_methods_attribute_key"`
+
+	// OriginalLinesAttributeKey is the attribute key that preserves the original
+	// line of each .NET frame.
+	OriginalLinesAttributeKey string `mapstructure:"original_lines_attribute_key"`
+
+	// OriginalSourceFilesAttributeKey is the attribute key that preserves the
+	// original source file of each .NET frame.
+	OriginalSourceFilesAttributeKey string `mapstructure:"original_source_files_attribute_key"`
+
+	DebugFileStoreKey string `mapstructure:"debug_file_store"`
+
+	// DebugFileStoreLayouts are the layouts used to look up debug files in the
+	// store by build ID, tried in order: "build_id" or "debuginfod".
+	DebugFileStoreLayouts []string `mapstructure:"debug_file_store_layouts"`
+
+	// LocalDebugFileConfiguration is the configuration for sourcing debug files on a local volume.
+	LocalDebugFileConfiguration *LocalDebugFileConfiguration `mapstructure:"local_debug_files"`
+
+	// S3DebugFileConfiguration is the configuration for sourcing debug files from S3.
+	S3DebugFileConfiguration *S3DebugFileConfiguration `mapstructure:"s3_debug_files"`
+
+	// GCSDebugFileConfiguration is the configuration for sourcing debug files from GCS.
+	GCSDebugFileConfiguration *GCSDebugFileConfiguration `mapstructure:"gcs_debug_files"`
+
+	// Timeout is the maximum time to wait for a response from the symbolicator.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// DebugFileCacheSize is the maximum number of debug files to cache.
+	DebugFileCacheSize int `mapstructure:"debug_file_cache_size"`
+
+	// LanguageAttributeKey is the attribute key that contains the programming language
+	// or SDK language of the telemetry signal (e.g., "telemetry.sdk.language").
+	// This is used to determine if this processor should handle the signal.
+	LanguageAttributeKey string `mapstructure:"language_attribute_key"`
+
+	// AllowedLanguages is a list of language values that this processor will handle.
+	// If the signal's language attribute matches any value in this list, the processor will run.
+	// If empty (default), the processor will process all signals regardless of language.
+	AllowedLanguages []string `mapstructure:"allowed_languages"`
+}
+
+type LocalDebugFileConfiguration struct {
+	// Path is a file path to where the debug files are stored on disk.
+	Path string `mapstructure:"path"`
+}
+
+type S3DebugFileConfiguration struct {
+	// Region is the AWS region where the S3 bucket is located.
+	Region string `mapstructure:"region"`
+	// BucketName is the name of the S3 bucket.
+	BucketName string `mapstructure:"bucket"`
+	// Prefix is the prefix to use when looking for debug files.
+	Prefix string `mapstructure:"prefix"`
+}
+
+type GCSDebugFileConfiguration struct {
+	// BucketName is the name of the GCS bucket.
+	BucketName string `mapstructure:"bucket"`
+	// Prefix is the prefix to use when looking for debug files.
+	Prefix string `mapstructure:"prefix"`
+}
+
+// Validate checks the configuration for any issues.
+func (c *Config) Validate() error {
+	return validateStoreLayouts(c.DebugFileStoreLayouts)
+}
@@ -0,0 +1,226 @@
+package nativeprocessor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	// dartHeaderMarker starts the header of a non-symbolic Dart stack trace.
+	dartHeaderMarker = "*** *** ***"
+	// dartWarningPrefix starts the warning the Dart VM prints before the header.
+	dartWarningPrefix = "Warning: This VM has been configured to produce stack traces that violate the Dart standard."
+	// dartBinaryName is the binary of Dart frames, which are all in the app's
+	// AOT snapshot, named libapp.so on Android and App.framework on iOS.
+	dartBinaryName = "app"
+)
+
+var (
+	// groups: build id
+	dartBuildIDRegex = regexp.MustCompile(`build_id: '([\da-fA-F]+)'`)
+	// groups: isolate DSO base
+	dartIsolateDSOBaseRegex = regexp.MustCompile(`isolate_dso_base: ([\da-fA-F]+)`)
+	// groups: frame index, absolute address, virtual address, symbol
+	dartFrameRegex = regexp.MustCompile(`^\s*#(\d+)\s+abs\s+([\da-fA-F]+)(?:\s+virt\s+([\da-fA-F]+))?\s*(.*)$`)
+)
+
+// dartStackTrace is a stack trace of an app built with --obfuscate and
+// --split-debug-info. Its frames are addresses in the app's snapshot, which
+// is symbolicated with the ELF debug file written by the build.
+type dartStackTrace struct {
+	// preamble are the lines before the header, usually the exception.
+	preamble []string
+	// header are the warning and header lines, only kept in the output if a frame
+	// can't be symbolicated.
+	header         []string
+	buildID        string
+	isolateDSOBase uint64
+	// lines are the frames, and any other lines between them such as
+	// <asynchronous suspension>, which are copied as-is.
+	lines []dartLine
+}
+
+type dartLine struct {
+	raw   string
+	frame *dartFrame
+}
+
+type dartFrame struct {
+	absolute uint64
+	// virtual is the address relative to the snapshot's load address, or 0 if
+	// the frame only has its absolute address.
+	virtual uint64
+	symbol  string
+}
+
+// isDartStackTrace reports whether a stack trace is a non-symbolic Dart stack trace.
+func isDartStackTrace(raw string) bool {
+	return strings.Contains(raw, dartHeaderMarker) && strings.Contains(raw, "build_id:")
+}
+
+// parseDartStackTrace parses a non-symbolic Dart stack trace, eg.
+//
+//	*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+//	pid: 19226, tid: 6103134208, name io.flutter.ui
+//	build_id: 'e1d41ac1c3ed1c0b23b3c61bfe50e3ab'
+//	isolate_dso_base: 10fa20000, vm_dso_base: 10fa20000
+//	    #00 abs 000000010fa2d4c7 virt 00000000000094c7 _kDartIsolateSnapshotInstructions+0x6457
+func parseDartStackTrace(raw string) (*dartStackTrace, error) {
+	trace := &dartStackTrace{}
+	inHeader := false
+	inFrames := false
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		switch {
+		case inFrames:
+			trace.lines = append(trace.lines, parseDartLine(line))
+		case strings.HasPrefix(strings.TrimSpace(line), dartWarningPrefix):
+			trace.header = append(trace.header, line)
+		case strings.HasPrefix(strings.TrimSpace(line), dartHeaderMarker):
+			trace.header = append(trace.header, line)
+			inHeader = true
+		case inHeader && dartFrameRegex.MatchString(line):
+			inFrames = true
+			trace.lines = append(trace.lines, parseDartLine(line))
+		case inHeader:
+			trace.header = append(trace.header, line)
+			if matches := dartBuildIDRegex.FindStringSubmatch(line); matches != nil {
+				trace.buildID = strings.ToLower(matches[1])
+			}
+			if matches := dartIsolateDSOBaseRegex.FindStringSubmatch(line); matches != nil {
+				trace.isolateDSOBase, _ = strconv.ParseUint(matches[1], 16, 64)
+			}
+		default:
+			trace.preamble = append(trace.preamble, line)
+		}
+	}
+
+	if trace.buildID == "" {
+		return nil, fmt.Errorf("%w: build_id", errMissingAttribute)
+	}
+
+	return trace, nil
+}
+
+func parseDartLine(line string) dartLine {
+	matches := dartFrameRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return dartLine{raw: line}
+	}
+
+	frame := &dartFrame{symbol: matches[4]}
+	frame.absolute, _ = strconv.ParseUint(matches[2], 16, 64)
+	if matches[3] != "" {
+		frame.virtual, _ = strconv.ParseUint(matches[3], 16, 64)
+	}
+
+	return dartLine{raw: line, frame: frame}
+}
+
+// address returns the address of a frame relative to the snapshot's load
+// address, which is what its debug file is symbolicated with.
+func (t *dartStackTrace) address(frame *dartFrame) (uint64, bool) {
+	if frame.virtual != 0 {
+		return frame.virtual, true
+	}
+	if t.isolateDSOBase == 0 || frame.absolute < t.isolateDSOBase {
+		return 0, false
+	}
+	return frame.absolute - t.isolateDSOBase, true
+}
+
+// formatDartFrame formats a symbolicated location as a standard Dart stack frame.
+func formatDartFrame(idx int, loc *mappedNativeStackFrame) string {
+	return fmt.Sprintf("%-8s%s (%s:%d)", fmt.Sprintf("#%d", idx), loc.symbol, loc.path, loc.line)
+}
+
+// renumberDartLine replaces the index of an unsymbolicated frame, keeping its
+// zero padding, so it follows on from the frames before it, which may have
+// been symbolicated to several inlined locations.
+func renumberDartLine(raw string, idx int) string {
+	loc := dartFrameRegex.FindStringSubmatchIndex(raw)
+	if loc == nil {
+		return raw
+	}
+	return raw[:loc[2]] + fmt.Sprintf("%0*d", loc[3]-loc[2], idx) + raw[loc[3]:]
+}
+
+func (sp *symbolicatorProcessor) processDartStackTraceThrows(ctx context.Context, attributes pcommon.Map, raw string) error {
+	trace, err := parseDartStackTrace(raw)
+	if err != nil {
+		return err
+	}
+
+	structured := sp.newStructuredStackTrace(attributes)
+	lines := make([]string, 0, len(trace.lines))
+	symbolicationFailed := false
+	unsymbolicated := false
+	idx := 0
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	for _, line := range trace.lines {
+		if line.frame == nil {
+			lines = append(lines, line.raw)
+			continue
+		}
+
+		var locations []*mappedNativeStackFrame
+		status := frameStatusFailed
+		if addr, ok := trace.address(line.frame); ok {
+			locations, err = sp.lookupFrame(ctx, trace.buildID, addr, fetchErrorCache)
+			switch {
+			case err != nil:
+				status = frameStatusFailed
+			case len(locations) == 0:
+				status = frameStatusMissingDebugFile
+			default:
+				status = frameStatusSymbolicated
+			}
+		}
+
+		if status == frameStatusFailed {
+			symbolicationFailed = true
+		}
+		structured.appendFrame(dartBinaryName, locations, status)
+
+		if len(locations) == 0 {
+			unsymbolicated = true
+			lines = append(lines, renumberDartLine(line.raw, idx))
+			idx++
+			continue
+		}
+
+		for _, loc := range locations {
+			lines = append(lines, formatDartFrame(idx, loc))
+			idx++
+		}
+	}
+
+	output := make([]string, 0, len(trace.preamble)+len(trace.header)+len(lines))
+	output = append(output, trace.preamble...)
+	// unsymbolicated frames can only be symbolicated later with the header
+	if unsymbolicated {
+		output = append(output, trace.header...)
+	}
+	output = append(output, lines...)
+
+	if sp.cfg.PreserveStackTrace {
+		attributes.PutStr(sp.cfg.OriginalStackTraceAttributeKey, raw)
+	}
+	attributes.PutStr(sp.cfg.StackTraceAttributeKey, strings.Join(output, "\n"))
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package nativeprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const testDartStackTrace = `Exception: Something went wrong
+Warning: This VM has been configured to produce stack traces that violate the Dart standard.
+*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+pid: 19226, tid: 6103134208, name io.flutter.ui
+os: android arch: arm64 comp: yes sim: no
+build_id: '0a1b2c3d4e5f60718293a4b5c6d7e8f9'
+isolate_dso_base: 7b2c400000, vm_dso_base: 7b2c400000
+isolate_instructions: 7b2c407070, vm_instructions: 7b2c401e20
+    #00 abs 0000007b2c4094c7 virt 00000000000094c7 _kDartIsolateSnapshotInstructions+0x2457
+    #01 abs 0000007b2c56b0a3 _kDartIsolateSnapshotInstructions+0x164033
+    <asynchronous suspension>`
+
+func TestParseDartStackTrace(t *testing.T) {
+	trace, err := parseDartStackTrace(testDartStackTrace)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Exception: Something went wrong"}, trace.preamble)
+	assert.Len(t, trace.header, 7)
+	assert.Equal(t, testBuildID, trace.buildID)
+	assert.Equal(t, uint64(0x7b2c400000), trace.isolateDSOBase)
+	require.Len(t, trace.lines, 3)
+
+	addr, ok := trace.address(trace.lines[0].frame)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x94c7), addr)
+
+	// without a virtual address the absolute address is relative to the isolate
+	addr, ok = trace.address(trace.lines[1].frame)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x16b0a3), addr)
+
+	assert.Nil(t, trace.lines[2].frame)
+	assert.Equal(t, "    <asynchronous suspension>", trace.lines[2].raw)
+
+	_, err = parseDartStackTrace("*** *** ***\n    #00 abs 0000007b2c4094c7")
+	assert.ErrorIs(t, err, errMissingAttribute)
+
+	assert.True(t, isDartStackTrace(testDartStackTrace))
+	assert.False(t, isDartStackTrace("#0      main (package:my_app/main.dart:5:3)"))
+}
+
+func TestProcessDartStackTrace(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestProcessor(t, cfg)
+	defer cleanup()
+
+	attributes := pcommon.NewMap()
+	attributes.PutStr(cfg.StackTraceAttributeKey, testDartStackTrace)
+
+	processor.processStackTraceAttributes(context.Background(), attributes, testDartStackTrace, processor.processDartStackTraceThrows)
+
+	stackTrace, _ := attributes.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `Exception: Something went wrong
+#0      _MyHomePageState._throwError (package:my_app/main.dart:58)
+#1      _MyHomePageState._incrementCounter (package:my_app/main.dart:64)
+#2      _InkResponseState.handleTap (package:flutter/src/material/ink_well.dart:1154)
+    <asynchronous suspension>`, stackTrace.Str())
+
+	original, _ := attributes.Get(cfg.OriginalStackTraceAttributeKey)
+	assert.Equal(t, testDartStackTrace, original.Str())
+
+	failed, _ := attributes.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+
+	functions, _ := attributes.Get(cfg.OutputStackTraceFunctionsAttributeKey)
+	assert.Equal(t, []any{"_MyHomePageState._throwError", "_MyHomePageState._incrementCounter", "_InkResponseState.handleTap"}, functions.Slice().AsRaw())
+	lines, _ := attributes.Get(cfg.OutputStackTraceLinesAttributeKey)
+	assert.Equal(t, []any{int64(58), int64(64), int64(1154)}, lines.Slice().AsRaw())
+	statuses, _ := attributes.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "symbolicated", "symbolicated"}, statuses.Slice().AsRaw())
+}
+
+func TestProcessDartStackTracePartial(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestProcessor(t, cfg)
+	defer cleanup()
+
+	raw := `*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+build_id: '0a1b2c3d4e5f60718293a4b5c6d7e8f9'
+isolate_dso_base: 7b2c400000, vm_dso_base: 7b2c400000
+    #00 abs 0000007b2c4094c7 virt 00000000000094c7 _kDartIsolateSnapshotInstructions+0x2457
+    #01 abs 0000007b2c400100 virt 0000000000000100 _kDartVmSnapshotInstructions+0x100`
+
+	attributes := pcommon.NewMap()
+	attributes.PutStr(cfg.StackTraceAttributeKey, raw)
+
+	processor.processStackTraceAttributes(context.Background(), attributes, raw, processor.processDartStackTraceThrows)
+
+	// the header is kept so the unsymbolicated frame can still be symbolicated
+	stackTrace, _ := attributes.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+build_id: '0a1b2c3d4e5f60718293a4b5c6d7e8f9'
+isolate_dso_base: 7b2c400000, vm_dso_base: 7b2c400000
+#0      _MyHomePageState._throwError (package:my_app/main.dart:58)
+#1      _MyHomePageState._incrementCounter (package:my_app/main.dart:64)
+    #02 abs 0000007b2c400100 virt 0000000000000100 _kDartVmSnapshotInstructions+0x100`, stackTrace.Str())
+
+	failed, _ := attributes.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.True(t, failed.Bool())
+	symbolicatorError, _ := attributes.Get(cfg.SymbolicatorErrorAttributeKey)
+	assert.Equal(t, errPartialSymbolication.Error(), symbolicatorError.Str())
+
+	statuses, _ := attributes.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "symbolicated", "failed"}, statuses.Slice().AsRaw())
+}
+
+func TestRenumberDartLine(t *testing.T) {
+	assert.Equal(t, "    #02 abs 0000007b2c400100 virt 0000000000000100", renumberDartLine("    #01 abs 0000007b2c400100 virt 0000000000000100", 2))
+	assert.Equal(t, "    #12 abs 0000007b2c400100", renumberDartLine("    #9 abs 0000007b2c400100", 12))
+	assert.Equal(t, "    <asynchronous suspension>", renumberDartLine("    <asynchronous suspension>", 3))
+}
+
+func TestProcessDartStackTraceMissingDebugFile(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestProcessor(t, cfg)
+	defer cleanup()
+
+	raw := `*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+build_id: 'ffffffffffffffffffffffffffffffff'
+    #00 abs 0000007b2c4094c7 virt 00000000000094c7 _kDartIsolateSnapshotInstructions+0x2457`
+
+	attributes := pcommon.NewMap()
+	attributes.PutStr(cfg.StackTraceAttributeKey, raw)
+
+	processor.processStackTraceAttributes(context.Background(), attributes, raw, processor.processDartStackTraceThrows)
+
+	stackTrace, _ := attributes.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, raw, stackTrace.Str())
+
+	failed, _ := attributes.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+	statuses, _ := attributes.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"missing_debug_file"}, statuses.Slice().AsRaw())
+}
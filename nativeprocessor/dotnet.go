@@ -0,0 +1,226 @@
+package nativeprocessor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+var (
+	// groups: line prefix, method, arguments, metadata token, IL offset, location, MVID, PDB ID, line, the rest of the line
+	dotnetFrameRegex = regexp.MustCompile(`^(\s*at\s+)(.+?)\s*(\(.*\))\s*<0x([\da-fA-F]+)\s*\+\s*0x([\da-fA-F]+)>\s+in\s+(<([\da-fA-F]{32})(?:#([\da-fA-F]+))?>):(\d+)(.*)$`)
+)
+
+// dotnetFrame is a managed frame of a .NET release build without line numbers,
+// eg. at MyApp.Foo.Bar () <0x6000001 + 0x00021> in <c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>:0.
+// The frame has the MethodDef metadata token of its method and the IL offset
+// in it, and the assembly's module version ID and the ID of its portable PDB.
+type dotnetFrame struct {
+	prefix    string
+	method    string
+	arguments string
+	token     uint32
+	ilOffset  uint32
+	mvid      string
+	// debugID is the PDB ID, the GUID and stamp of the portable PDB, or empty
+	// if the runtime only printed the MVID.
+	debugID string
+	// location and line are where the runtime printed the frame to be, eg.
+	// <c50de4b196cc418b8cadb011134bfc5c> and 0.
+	location string
+	line     int64
+	suffix   string
+}
+
+// isDotnetStackTrace reports whether a stack trace contains .NET frames with
+// metadata tokens and IL offsets.
+func isDotnetStackTrace(raw string) bool {
+	for _, line := range strings.Split(raw, "\n") {
+		if parseDotnetFrame(strings.TrimRight(line, "\r")) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDotnetFrame(line string) *dotnetFrame {
+	matches := dotnetFrameRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+
+	token, err := strconv.ParseUint(matches[4], 16, 32)
+	if err != nil {
+		return nil
+	}
+	// AOT compiled frames have a native address and offset instead, which
+	// the portable PDB can't map
+	if token>>24 != methodDefTable {
+		return nil
+	}
+
+	ilOffset, err := strconv.ParseUint(matches[5], 16, 32)
+	if err != nil {
+		return nil
+	}
+
+	lineNumber, err := strconv.ParseInt(matches[9], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &dotnetFrame{
+		prefix:    matches[1],
+		method:    matches[2],
+		arguments: matches[3],
+		token:     uint32(token),
+		ilOffset:  uint32(ilOffset),
+		mvid:      strings.ToLower(matches[7]),
+		debugID:   strings.ToLower(matches[8]),
+		location:  matches[6],
+		line:      lineNumber,
+		suffix:    matches[10],
+	}
+}
+
+// classAndMethod splits the method of a frame into its class and method, eg.
+// MyApp.Foo.Bar into MyApp.Foo and Bar, keeping the leading dot of
+// constructors, eg. MyApp.Foo..ctor into MyApp.Foo and .ctor.
+func (frame *dotnetFrame) classAndMethod() (string, string) {
+	name := frame.method
+	idx := strings.LastIndex(name, ".")
+	if idx > 0 && name[idx-1] == '.' {
+		idx--
+	}
+	if idx <= 0 {
+		return "", name
+	}
+	return name[:idx], name[idx+1:]
+}
+
+// dotnetStructuredStackTrace is the symbolicated stack trace of .NET frames,
+// written as the same parallel slice attributes as the proguard processor writes
+// Java frames, with the original values of each frame when the stack trace is
+// preserved.
+type dotnetStructuredStackTrace struct {
+	classes     pcommon.Slice
+	methods     pcommon.Slice
+	sourceFiles pcommon.Slice
+	lines       pcommon.Slice
+	statuses    pcommon.Slice
+
+	preserve            bool
+	originalClasses     pcommon.Slice
+	originalMethods     pcommon.Slice
+	originalSourceFiles pcommon.Slice
+	originalLines       pcommon.Slice
+}
+
+func (sp *symbolicatorProcessor) newDotnetStructuredStackTrace(attributes pcommon.Map) *dotnetStructuredStackTrace {
+	s := &dotnetStructuredStackTrace{
+		classes:     attributes.PutEmptySlice(sp.cfg.ClassesAttributeKey),
+		methods:     attributes.PutEmptySlice(sp.cfg.MethodsAttributeKey),
+		sourceFiles: attributes.PutEmptySlice(sp.cfg.SourceFilesAttributeKey),
+		lines:       attributes.PutEmptySlice(sp.cfg.OutputStackTraceLinesAttributeKey),
+		statuses:    attributes.PutEmptySlice(sp.cfg.OutputStackTraceFrameStatusesAttributeKey),
+		preserve:    sp.cfg.PreserveStackTrace,
+	}
+
+	if s.preserve {
+		s.originalClasses = attributes.PutEmptySlice(sp.cfg.OriginalClassesAttributeKey)
+		s.originalMethods = attributes.PutEmptySlice(sp.cfg.OriginalMethodsAttributeKey)
+		s.originalSourceFiles = attributes.PutEmptySlice(sp.cfg.OriginalSourceFilesAttributeKey)
+		s.originalLines = attributes.PutEmptySlice(sp.cfg.OriginalLinesAttributeKey)
+	}
+
+	return s
+}
+
+// appendFrame appends a frame's locations. A frame that wasn't symbolicated
+// gets a single entry with its class and method, and no source file.
+func (s *dotnetStructuredStackTrace) appendFrame(frame *dotnetFrame, locations []*mappedNativeStackFrame, status frameStatus) {
+	if len(locations) == 0 {
+		s.appendLocation(frame, "", 0, status)
+		return
+	}
+
+	for _, loc := range locations {
+		s.appendLocation(frame, loc.path, int64(loc.line), status)
+	}
+}
+
+func (s *dotnetStructuredStackTrace) appendLocation(frame *dotnetFrame, sourceFile string, line int64, status frameStatus) {
+	class, method := frame.classAndMethod()
+
+	s.classes.AppendEmpty().SetStr(class)
+	s.methods.AppendEmpty().SetStr(method)
+	s.sourceFiles.AppendEmpty().SetStr(sourceFile)
+	s.lines.AppendEmpty().SetInt(line)
+	s.statuses.AppendEmpty().SetStr(string(status))
+
+	if s.preserve {
+		s.originalClasses.AppendEmpty().SetStr(class)
+		s.originalMethods.AppendEmpty().SetStr(method)
+		s.originalSourceFiles.AppendEmpty().SetStr(frame.location)
+		s.originalLines.AppendEmpty().SetInt(frame.line)
+	}
+}
+
+// formatDotnetFrame formats a frame the way Mono does when it has debug symbols,
+// eg. at MyApp.Foo.Bar () [0x00021] in /src/MyApp/Foo.cs:14.
+func formatDotnetFrame(frame *dotnetFrame, loc *mappedNativeStackFrame) string {
+	return fmt.Sprintf("%s%s %s [0x%05x] in %s:%d%s", frame.prefix, frame.method, frame.arguments, frame.ilOffset, loc.path, loc.line, frame.suffix)
+}
+
+func (sp *symbolicatorProcessor) processDotnetStackTraceThrows(ctx context.Context, attributes pcommon.Map, raw string) error {
+	structured := sp.newDotnetStructuredStackTrace(attributes)
+	lines := make([]string, 0)
+	symbolicationFailed := false
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		frame := parseDotnetFrame(line)
+		if frame == nil {
+			lines = append(lines, line)
+			continue
+		}
+
+		locations, err := sp.lookupDotnetFrame(ctx, frame, fetchErrorCache)
+		status := frameStatusMissingDebugFile
+		switch {
+		case err != nil:
+			status = frameStatusFailed
+			symbolicationFailed = true
+		case len(locations) > 0:
+			status = frameStatusSymbolicated
+		}
+
+		structured.appendFrame(frame, locations, status)
+
+		if len(locations) == 0 {
+			lines = append(lines, line)
+			continue
+		}
+
+		lines = append(lines, formatDotnetFrame(frame, locations[0]))
+	}
+
+	if sp.cfg.PreserveStackTrace {
+		attributes.PutStr(sp.cfg.OriginalStackTraceAttributeKey, raw)
+	}
+	attributes.PutStr(sp.cfg.StackTraceAttributeKey, strings.Join(lines, "\n"))
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package nativeprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const testDotnetStackTrace = `System.InvalidOperationException: boom
+  at MyApp.Foo.Bar () <0x6000001 + 0x00021> in <c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>:0
+  at MyApp.Foo.Baz () <0x6000002 + 0x0000e> in <c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>:0
+  at MyApp.Program.Main () <0x6000004 + 0x00005> in <c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>:0
+  at MyLib.Client.Send (System.String body) <0x6000010 + 0x00007> in <0f1e2d3c4b5a69788796a5b4c3d2e1f0#0f1e2d3c4b5a69788796a5b4c3d2e1f0ffffffff>:0`
+
+func TestParseDotnetFrame(t *testing.T) {
+	frame := parseDotnetFrame("  at MyLib.Client.Send (System.String body) <0x6000010 + 0x00007> in <0F1E2D3C4B5A69788796A5B4C3D2E1F0>:0")
+	require.NotNil(t, frame)
+	assert.Equal(t, &dotnetFrame{
+		prefix:    "  at ",
+		method:    "MyLib.Client.Send",
+		arguments: "(System.String body)",
+		token:     0x6000010,
+		ilOffset:  7,
+		mvid:      "0f1e2d3c4b5a69788796a5b4c3d2e1f0",
+		location:  "<0F1E2D3C4B5A69788796A5B4C3D2E1F0>",
+	}, frame)
+
+	for method, expected := range map[string][]string{
+		"MyApp.Foo.Bar":             {"MyApp.Foo", "Bar"},
+		"MyApp.Foo..ctor":           {"MyApp.Foo", ".ctor"},
+		"MyApp.Foo+<>c.<Run>b__0_0": {"MyApp.Foo+<>c", "<Run>b__0_0"},
+		"Main":                      {"", "Main"},
+	} {
+		class, name := (&dotnetFrame{method: method}).classAndMethod()
+		assert.Equal(t, expected, []string{class, name}, method)
+	}
+
+	// frames of AOT compiled code have a native address instead of a metadata token
+	assert.Nil(t, parseDotnetFrame("  at MyApp.Foo.Bar () <0x7f4a12c0 + 0x00042> in <c50de4b196cc418b8cadb011134bfc5c>:0"))
+	assert.Nil(t, parseDotnetFrame("  at MyApp.Foo.Bar () [0x00021] in /src/MyApp/Foo.cs:14"))
+
+	assert.True(t, isDotnetStackTrace(testDotnetStackTrace))
+	assert.False(t, isDotnetStackTrace(testWindowsStackTrace))
+}
+
+func TestProcessDotnetStackTrace(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestMinidumpProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.StackTraceAttributeKey, testDotnetStackTrace)
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	attrs := record.Attributes()
+
+	stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `System.InvalidOperationException: boom
+  at MyApp.Foo.Bar () [0x00021] in /src/MyApp/Foo.cs:14
+  at MyApp.Foo.Baz () [0x0000e] in /src/MyApp/Foo.cs:22
+  at MyApp.Program.Main () [0x00005] in /src/MyApp/Foo.cs:32
+  at MyLib.Client.Send (System.String body) <0x6000010 + 0x00007> in <0f1e2d3c4b5a69788796a5b4c3d2e1f0#0f1e2d3c4b5a69788796a5b4c3d2e1f0ffffffff>:0`, stackTrace.Str())
+
+	original, _ := attrs.Get(cfg.OriginalStackTraceAttributeKey)
+	assert.Equal(t, testDotnetStackTrace, original.Str())
+
+	failed, _ := attrs.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+
+	classes, _ := attrs.Get(cfg.ClassesAttributeKey)
+	assert.Equal(t, []any{"MyApp.Foo", "MyApp.Foo", "MyApp.Program", "MyLib.Client"}, classes.Slice().AsRaw())
+	methods, _ := attrs.Get(cfg.MethodsAttributeKey)
+	assert.Equal(t, []any{"Bar", "Baz", "Main", "Send"}, methods.Slice().AsRaw())
+	sourceFiles, _ := attrs.Get(cfg.SourceFilesAttributeKey)
+	assert.Equal(t, []any{"/src/MyApp/Foo.cs", "/src/MyApp/Foo.cs", "/src/MyApp/Foo.cs", ""}, sourceFiles.Slice().AsRaw())
+	lines, _ := attrs.Get(cfg.OutputStackTraceLinesAttributeKey)
+	assert.Equal(t, []any{int64(14), int64(22), int64(32), int64(0)}, lines.Slice().AsRaw())
+
+	// the frames as the runtime printed them
+	originalClasses, _ := attrs.Get(cfg.OriginalClassesAttributeKey)
+	assert.Equal(t, classes.Slice().AsRaw(), originalClasses.Slice().AsRaw())
+	originalMethods, _ := attrs.Get(cfg.OriginalMethodsAttributeKey)
+	assert.Equal(t, methods.Slice().AsRaw(), originalMethods.Slice().AsRaw())
+	originalSourceFiles, _ := attrs.Get(cfg.OriginalSourceFilesAttributeKey)
+	assert.Equal(t, []any{
+		"<c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>",
+		"<c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>",
+		"<c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>",
+		"<0f1e2d3c4b5a69788796a5b4c3d2e1f0#0f1e2d3c4b5a69788796a5b4c3d2e1f0ffffffff>",
+	}, originalSourceFiles.Slice().AsRaw())
+	originalLines, _ := attrs.Get(cfg.OriginalLinesAttributeKey)
+	assert.Equal(t, []any{int64(0), int64(0), int64(0), int64(0)}, originalLines.Slice().AsRaw())
+
+	// native binaries and functions aren't written for managed frames
+	_, ok := attrs.Get(cfg.OutputStackTraceBinariesAttributeKey)
+	assert.False(t, ok)
+	statuses, _ := attrs.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "symbolicated", "symbolicated", "missing_debug_file"}, statuses.Slice().AsRaw())
+}
+
+func TestProcessDotnetStackTraceInvalidOffset(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestMinidumpProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.StackTraceAttributeKey, "  at MyApp.Foo.Gone () <0x6000099 + 0x00000> in <c50de4b196cc418b8cadb011134bfc5c#b256021a9e734ae5ac782c7668e319abb423a98e>:0")
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	attrs := record.Attributes()
+	failed, _ := attrs.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.True(t, failed.Bool())
+	statuses, _ := attrs.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"failed"}, statuses.Slice().AsRaw())
+}
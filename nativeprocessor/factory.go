@@ -0,0 +1,118 @@
+package nativeprocessor
+
+import (
+	"context"
+	"time"
+
+	"github.com/honeycombio/opentelemetry-collector-symbolicator/nativeprocessor/internal/metadata"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	typeStr = component.MustNewType("native_symbolicator")
+)
+
+const (
+	processorVersion = "0.1.0"
+)
+
+// createDefaultConfig creates the default configuration for the processor.
+func createDefaultConfig() component.Config {
+	return &Config{
+		SymbolicatorFailureAttributeKey:           "exception.symbolicator.failed",
+		SymbolicatorErrorAttributeKey:             "exception.symbolicator.error",
+		StackTraceAttributeKey:                    "exception.stacktrace",
+		MinidumpAttributeKey:                      "exception.minidump",
+		ModulesAttributeKey:                       "app.modules",
+		ExceptionTypeAttributeKey:                 "exception.type",
+		ExceptionMessageAttributeKey:              "exception.message",
+		OutputStackTraceBinariesAttributeKey:      "exception.structured_stacktrace.binaries",
+		OutputStackTraceFunctionsAttributeKey:     "exception.structured_stacktrace.functions",
+		OutputStackTraceFilesAttributeKey:         "exception.structured_stacktrace.files",
+		OutputStackTraceLinesAttributeKey:         "exception.structured_stacktrace.lines",
+		OutputStackTraceFrameStatusesAttributeKey: "exception.structured_stacktrace.frame_statuses",
+		ClassesAttributeKey:                       "exception.structured_stacktrace.classes",
+		MethodsAttributeKey:                       "exception.structured_stacktrace.methods",
+		SourceFilesAttributeKey:                   "exception.structured_stacktrace.source_files",
+		PreserveStackTrace:                        true,
+		OriginalStackTraceAttributeKey:            "exception.stacktrace.original",
+		OriginalClassesAttributeKey:               "exception.structured_stacktrace.classes.original",
+		OriginalMethodsAttributeKey:               "exception.structured_stacktrace.methods.original",
+		OriginalLinesAttributeKey:                 "exception.structured_stacktrace.lines.original",
+		OriginalSourceFilesAttributeKey:           "exception.structured_stacktrace.source_files.original",
+		DebugFileStoreKey:                         "file_store",
+		DebugFileStoreLayouts:                     []string{storeLayoutBuildID},
+		LocalDebugFileConfiguration: &LocalDebugFileConfiguration{
+			Path: ".",
+		},
+		Timeout:              5 * time.Second,
+		DebugFileCacheSize:   64,
+		LanguageAttributeKey: "telemetry.sdk.language",
+		AllowedLanguages:     []string{}, // Empty by default, processes all signals
+	}
+}
+
+// createLogsProcessor creates a logs processor
+func createLogsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Logs) (processor.Logs, error) {
+	symCfg := cfg.(*Config)
+	var s *store
+	var err error
+
+	switch symCfg.DebugFileStoreKey {
+	case "file_store":
+		s, err = newFileStore(ctx, set.Logger, symCfg.LocalDebugFileConfiguration)
+	case "s3_store":
+		s, err = newS3Store(ctx, set.Logger, symCfg.S3DebugFileConfiguration)
+	case "gcs_store":
+		s, err = newGCSStore(ctx, set.Logger, symCfg.GCSDebugFileConfiguration)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s != nil && len(symCfg.DebugFileStoreLayouts) > 0 {
+		s.layouts = symCfg.DebugFileStoreLayouts
+	}
+
+	tb, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+	// Set up resource attributes for telemetry
+	attributeSet := setUpResourceAttributes()
+	sym, err := newBasicSymbolicator(ctx, symCfg.Timeout, symCfg.DebugFileCacheSize, s, tb, attributeSet)
+	if err != nil {
+		return nil, err
+	}
+
+	processor := newSymbolicatorProcessor(ctx, symCfg, set, sym, tb, attributeSet)
+	return processorhelper.NewLogs(ctx, set, cfg, next, processor.processLogs, processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+}
+
+func setUpResourceAttributes() attribute.Set {
+	attributes := []attribute.KeyValue{}
+	config := metadata.DefaultResourceAttributesConfig()
+
+	if config.ProcessorType.Enabled {
+		attributes = append(attributes, attribute.String("otelcol_processor_type", typeStr.String()))
+	}
+	if config.ProcessorVersion.Enabled {
+		attributes = append(attributes, attribute.String("otelcol_processor_version", processorVersion))
+	}
+
+	return attribute.NewSet(attributes...)
+}
+
+// NewFactory creates a factory for the symbolicator processor
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		typeStr,
+		createDefaultConfig,
+		processor.WithLogs(createLogsProcessor, component.StabilityLevelAlpha),
+	)
+}
@@ -0,0 +1,246 @@
+package nativeprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/honeycombio/opentelemetry-collector-symbolicator/nativeprocessor/internal/metadata"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var (
+	errMissingAttribute     = errors.New("missing attribute")
+	errPartialSymbolication = errors.New("symbolication failed for some stack frames")
+)
+
+// symbolicator interface is used to symbolicate stack traces.
+type symbolicator interface {
+	symbolicateFrame(ctx context.Context, buildID string, addr uint64) ([]*mappedNativeStackFrame, error)
+	symbolicateBreakpadFrame(ctx context.Context, debugFile, debugID string, addr uint64) ([]*mappedNativeStackFrame, error)
+	symbolicatePDBFrame(ctx context.Context, debugFile, debugID string, addr uint64) ([]*mappedNativeStackFrame, error)
+	symbolicateDotnetFrame(ctx context.Context, debugID, mvid string, token, ilOffset uint32) ([]*mappedNativeStackFrame, error)
+	breakpadCFI(ctx context.Context, debugFile, debugID string) (*breakpadCFI, error)
+}
+
+// symbolicatorProcessor is a processor that finds and symbolicates native, Windows,
+// .NET and obfuscated Dart stack traces, and minidumps, that it finds in the attributes of logs.
+type symbolicatorProcessor struct {
+	logger *zap.Logger
+
+	cfg *Config
+
+	symbolicator symbolicator
+
+	telemetryBuilder *metadata.TelemetryBuilder
+	attributes       metric.MeasurementOption
+}
+
+// newSymbolicatorProcessor creates a new symbolicatorProcessor.
+func newSymbolicatorProcessor(_ context.Context, cfg *Config, set processor.Settings, symbolicator symbolicator, tb *metadata.TelemetryBuilder, attributes attribute.Set) *symbolicatorProcessor {
+	return &symbolicatorProcessor{
+		cfg:              cfg,
+		logger:           set.Logger,
+		symbolicator:     symbolicator,
+		telemetryBuilder: tb,
+		attributes:       metric.WithAttributeSet(attributes),
+	}
+}
+
+// processLogs processes the received logs. It is the function configured
+// in the processorhelper.NewLogs call in factory.go
+func (sp *symbolicatorProcessor) processLogs(ctx context.Context, logs plog.Logs) (plog.Logs, error) {
+	sp.logger.Debug("Processing logs")
+
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		rl := logs.ResourceLogs().At(i)
+		sp.processResourceLogs(ctx, rl)
+	}
+
+	return logs, nil
+}
+
+// processResourceLogs takes resource logs and processes the attributes
+// found on the log records.
+func (sp *symbolicatorProcessor) processResourceLogs(ctx context.Context, rl plog.ResourceLogs) {
+	for i := 0; i < rl.ScopeLogs().Len(); i++ {
+		sl := rl.ScopeLogs().At(i)
+
+		for j := 0; j < sl.LogRecords().Len(); j++ {
+			log := sl.LogRecords().At(j)
+			attributes := log.Attributes()
+			resourceAttrs := rl.Resource().Attributes()
+
+			// Check language filtering if configured
+			if len(sp.cfg.AllowedLanguages) > 0 {
+				// Get language attribute from log attributes or resource attributes
+				languageValue, ok := attributes.Get(sp.cfg.LanguageAttributeKey)
+				if !ok {
+					languageValue, ok = resourceAttrs.Get(sp.cfg.LanguageAttributeKey)
+				}
+
+				// If language attribute exists, check if it matches allowed languages
+				if ok {
+					language := languageValue.Str()
+					if !isLanguageAllowed(language, sp.cfg.AllowedLanguages) {
+						continue
+					}
+				} else { // Language attribute not found, skip processing
+					continue
+				}
+			}
+
+			// a minidump is unwound and symbolicated into the stack trace attribute
+			if minidumpValue, ok := attributes.Get(sp.cfg.MinidumpAttributeKey); ok {
+				sp.processStackTraceAttributes(ctx, attributes, minidumpValue.Str(), sp.processMinidumpThrows)
+				continue
+			}
+
+			stackTraceValue, ok := attributes.Get(sp.cfg.StackTraceAttributeKey)
+			if !ok {
+				err := fmt.Errorf("%w: %s", errMissingAttribute, sp.cfg.StackTraceAttributeKey)
+				sp.logger.Debug("Error processing log", zap.Error(err))
+				continue
+			}
+
+			// stack traces in formats this processor doesn't handle are left to other processors
+			switch raw := stackTraceValue.Str(); {
+			case isDartStackTrace(raw):
+				sp.processStackTraceAttributes(ctx, attributes, raw, sp.processDartStackTraceThrows)
+			case isTombstone(raw):
+				sp.processStackTraceAttributes(ctx, attributes, raw, sp.processTombstoneThrows)
+			case isWindowsStackTrace(raw) && sp.hasPDBModules(attributes, resourceAttrs):
+				sp.processStackTraceAttributes(ctx, attributes, raw, func(ctx context.Context, attributes pcommon.Map, raw string) error {
+					return sp.processWindowsStackTraceThrows(ctx, attributes, resourceAttrs, raw)
+				})
+			case isDotnetStackTrace(raw):
+				sp.processStackTraceAttributes(ctx, attributes, raw, sp.processDotnetStackTraceThrows)
+			}
+		}
+	}
+}
+
+// processStackTraceAttributes symbolicates a stack trace or minidump with the given
+// format's process function, recording its timing and outcome.
+func (sp *symbolicatorProcessor) processStackTraceAttributes(ctx context.Context, attributes pcommon.Map, raw string, process func(context.Context, pcommon.Map, string) error) {
+	// Start timing symbolication only when we actually perform it
+	// End timing deferred to after processing is done
+	startTime := time.Now()
+	defer func() {
+		sp.telemetryBuilder.ProcessorSymbolicationDuration.Record(ctx, time.Since(startTime).Seconds(), sp.attributes)
+	}()
+
+	// Add processor type and version as attributes
+	attributes.PutStr("honeycomb.processor_type", typeStr.String())
+	attributes.PutStr("honeycomb.processor_version", processorVersion)
+
+	err := process(ctx, attributes, raw)
+	if err != nil {
+		attributes.PutBool(sp.cfg.SymbolicatorFailureAttributeKey, true)
+		attributes.PutStr(sp.cfg.SymbolicatorErrorAttributeKey, err.Error())
+		sp.logger.Debug("Error processing log", zap.Error(err))
+	} else {
+		attributes.PutBool(sp.cfg.SymbolicatorFailureAttributeKey, false)
+	}
+}
+
+// lookupFrame symbolicates an address in the debug file with the given build ID.
+// A missing debug file isn't an error: it returns no locations.
+func (sp *symbolicatorProcessor) lookupFrame(ctx context.Context, buildID string, addr uint64, fetchErrorCache map[string]error) ([]*mappedNativeStackFrame, error) {
+	return sp.lookup(ctx, buildID, fetchErrorCache, func() ([]*mappedNativeStackFrame, error) {
+		return sp.symbolicator.symbolicateFrame(ctx, buildID, addr)
+	})
+}
+
+// lookupPDBFrame symbolicates an address in a Windows module with its PDB.
+func (sp *symbolicatorProcessor) lookupPDBFrame(ctx context.Context, debugFile, debugID string, addr uint64, fetchErrorCache map[string]error) ([]*mappedNativeStackFrame, error) {
+	return sp.lookup(ctx, pdbPath(debugFile, debugID), fetchErrorCache, func() ([]*mappedNativeStackFrame, error) {
+		return sp.symbolicator.symbolicatePDBFrame(ctx, debugFile, debugID, addr)
+	})
+}
+
+// lookupDotnetFrame maps a .NET frame's IL offset to its source location with
+// the assembly's portable PDB.
+func (sp *symbolicatorProcessor) lookupDotnetFrame(ctx context.Context, frame *dotnetFrame, fetchErrorCache map[string]error) ([]*mappedNativeStackFrame, error) {
+	return sp.lookup(ctx, frame.mvid, fetchErrorCache, func() ([]*mappedNativeStackFrame, error) {
+		return sp.symbolicator.symbolicateDotnetFrame(ctx, frame.debugID, frame.mvid, frame.token, frame.ilOffset)
+	})
+}
+
+// lookupModuleFrame symbolicates an address in a minidump module with its
+// Breakpad symbol file, falling back to the module's PDB or the ELF debug file
+// with its build ID if there is no symbol file.
+func (sp *symbolicatorProcessor) lookupModuleFrame(ctx context.Context, module *minidumpModule, addr uint64, fetchErrorCache map[string]error) ([]*mappedNativeStackFrame, error) {
+	if module.debugID == "" {
+		return nil, nil
+	}
+
+	locations, err := sp.lookup(ctx, breakpadSymbolsPath(module.debugFile, module.debugID), fetchErrorCache, func() ([]*mappedNativeStackFrame, error) {
+		return sp.symbolicator.symbolicateBreakpadFrame(ctx, module.debugFile, module.debugID, addr)
+	})
+	if err != nil || len(locations) > 0 {
+		return locations, err
+	}
+
+	switch {
+	case strings.EqualFold(path.Ext(module.debugFile), ".pdb"):
+		return sp.lookupPDBFrame(ctx, module.debugFile, module.debugID, addr, fetchErrorCache)
+	case module.codeID != "":
+		return sp.lookupFrame(ctx, module.codeID, addr, fetchErrorCache)
+	}
+
+	return nil, nil
+}
+
+// lookup symbolicates a frame in the debug file with the given identifier.
+func (sp *symbolicatorProcessor) lookup(ctx context.Context, id string, fetchErrorCache map[string]error, symbolicate func() ([]*mappedNativeStackFrame, error)) ([]*mappedNativeStackFrame, error) {
+	var locations []*mappedNativeStackFrame
+	var err error
+
+	// Check if we have a cached fetch error for this debug file
+	if cachedError, exists := fetchErrorCache[id]; exists {
+		err = cachedError
+	} else {
+		locations, err = symbolicate()
+		sp.telemetryBuilder.ProcessorTotalProcessedFrames.Add(ctx, 1, sp.attributes)
+
+		// Only cache FetchErrors (404, timeout, etc.) - not parse errors
+		if err != nil {
+			var fetchErr *FetchError
+			if errors.As(err, &fetchErr) {
+				fetchErrorCache[id] = err
+			}
+		}
+	}
+
+	if errors.Is(err, errFailedToFindDebugFile) {
+		return nil, nil
+	}
+	if err != nil {
+		sp.telemetryBuilder.ProcessorTotalFailedFrames.Add(ctx, 1, sp.attributes)
+		sp.logger.Debug("could not symbolicate frame", zap.String("debug_file", id), zap.Error(err))
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// isLanguageAllowed checks if the given language matches any of the allowed languages.
+// Comparison is case insensitive.
+func isLanguageAllowed(language string, allowedLanguages []string) bool {
+	language = strings.ToLower(language)
+	for _, allowed := range allowedLanguages {
+		if strings.ToLower(allowed) == language {
+			return true
+		}
+	}
+	return false
+}
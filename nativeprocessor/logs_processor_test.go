@@ -0,0 +1,131 @@
+package nativeprocessor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/honeycombio/opentelemetry-collector-symbolicator/nativeprocessor/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap/zaptest"
+)
+
+const testBuildID = "0a1b2c3d4e5f60718293a4b5c6d7e8f9"
+
+// testSymbolicator symbolicates addresses in the debug file testBuildID from a
+// fixed table, returning inlined locations innermost first.
+type testSymbolicator struct {
+	frames map[uint64][]*mappedNativeStackFrame
+}
+
+func newTestSymbolicator() *testSymbolicator {
+	return &testSymbolicator{frames: map[uint64][]*mappedNativeStackFrame{
+		0x94c7: {
+			{symbol: "_MyHomePageState._throwError", path: "package:my_app/main.dart", line: 58},
+			{symbol: "_MyHomePageState._incrementCounter", path: "package:my_app/main.dart", line: 64},
+		},
+		0x16b0a3: {
+			{symbol: "_InkResponseState.handleTap", path: "package:flutter/src/material/ink_well.dart", line: 1154},
+		},
+	}}
+}
+
+func (ts *testSymbolicator) symbolicateFrame(_ context.Context, buildID string, addr uint64) ([]*mappedNativeStackFrame, error) {
+	if buildID != testBuildID {
+		return nil, &FetchError{BuildID: buildID, Err: errFailedToFindDebugFile}
+	}
+	frames, ok := ts.frames[addr]
+	if !ok {
+		return nil, fmt.Errorf("could not find symbol at location %d", addr)
+	}
+	return frames, nil
+}
+
+func (ts *testSymbolicator) symbolicateBreakpadFrame(_ context.Context, debugFile, _ string, _ uint64) ([]*mappedNativeStackFrame, error) {
+	return nil, &FetchError{BuildID: debugFile, Err: errFailedToFindDebugFile}
+}
+
+func (ts *testSymbolicator) symbolicatePDBFrame(_ context.Context, debugFile, _ string, _ uint64) ([]*mappedNativeStackFrame, error) {
+	return nil, &FetchError{BuildID: debugFile, Err: errFailedToFindDebugFile}
+}
+
+func (ts *testSymbolicator) symbolicateDotnetFrame(_ context.Context, _, mvid string, _, _ uint32) ([]*mappedNativeStackFrame, error) {
+	return nil, &FetchError{BuildID: mvid, Err: errFailedToFindDebugFile}
+}
+
+func (ts *testSymbolicator) breakpadCFI(_ context.Context, debugFile, _ string) (*breakpadCFI, error) {
+	return nil, &FetchError{BuildID: debugFile, Err: errFailedToFindDebugFile}
+}
+
+func createTestTelemetry(t *testing.T) (*metadata.TelemetryBuilder, attribute.Set, func()) {
+	testTel := componenttest.NewTelemetry()
+	tb, err := metadata.NewTelemetryBuilder(testTel.NewTelemetrySettings())
+	assert.NoError(t, err)
+
+	attributes := attribute.NewSet(
+		attribute.String("processor_type", "native_symbolicator"),
+	)
+
+	return tb, attributes, tb.Shutdown
+}
+
+func newTestProcessor(t *testing.T, cfg *Config) (*symbolicatorProcessor, func()) {
+	tb, attributes, cleanup := createTestTelemetry(t)
+
+	return newSymbolicatorProcessor(context.Background(), cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, newTestSymbolicator(), tb, attributes), cleanup
+}
+
+func TestProcessLogsIgnoresOtherStackTraces(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.StackTraceAttributeKey, "java.lang.RuntimeException: boom\n\tat a.b.c(Unknown Source:1)")
+
+	_, err := processor.processLogs(context.Background(), logs)
+	assert.NoError(t, err)
+
+	attributes := record.Attributes()
+	_, ok := attributes.Get("honeycomb.processor_type")
+	assert.False(t, ok)
+	_, ok = attributes.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, ok)
+}
+
+func TestProcessLogsLanguageFiltering(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.AllowedLanguages = []string{"dart"}
+	processor, cleanup := newTestProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+
+	dart := records.AppendEmpty()
+	dart.Attributes().PutStr(cfg.LanguageAttributeKey, "Dart")
+	dart.Attributes().PutStr(cfg.StackTraceAttributeKey, testDartStackTrace)
+
+	swift := records.AppendEmpty()
+	swift.Attributes().PutStr(cfg.LanguageAttributeKey, "swift")
+	swift.Attributes().PutStr(cfg.StackTraceAttributeKey, testDartStackTrace)
+
+	_, err := processor.processLogs(context.Background(), logs)
+	assert.NoError(t, err)
+
+	_, ok := dart.Attributes().Get("honeycomb.processor_type")
+	assert.True(t, ok)
+	_, ok = swift.Attributes().Get("honeycomb.processor_type")
+	assert.False(t, ok)
+}
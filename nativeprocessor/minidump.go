@@ -0,0 +1,627 @@
+package nativeprocessor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf16"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+var errInvalidMinidump = errors.New("invalid minidump")
+
+const (
+	minidumpSignature = 0x504d444d // MDMP
+
+	minidumpStreamThreadList   = 3
+	minidumpStreamModuleList   = 4
+	minidumpStreamMemoryList   = 5
+	minidumpStreamException    = 6
+	minidumpStreamSystemInfo   = 7
+	minidumpStreamMemory64List = 9
+
+	minidumpDirectorySize = 12
+	minidumpThreadSize    = 48
+	minidumpModuleSize    = 108
+	minidumpMemorySize    = 16
+	minidumpMemory64Size  = 16
+
+	cvSignaturePDB70 = 0x53445352 // RSDS
+	cvSignatureELF   = 0x4c457042 // BpEL
+
+	minidumpCPUAMD64    = 9
+	minidumpCPUARM64    = 12
+	minidumpCPUARM64Old = 0x8003
+
+	minidumpOSWindows = 2
+	minidumpOSMacOS   = 0x8101
+	minidumpOSIOS     = 0x8102
+	minidumpOSLinux   = 0x8201
+	minidumpOSAndroid = 0x8203
+)
+
+// minidump is the subset of a Windows or Breakpad/Crashpad minidump that is
+// needed to unwind and symbolicate its threads.
+type minidump struct {
+	arch     uint16
+	platform uint32
+	// exception is nil if the process didn't crash, eg. a hang report.
+	exception *minidumpException
+	threads   []minidumpThread
+	modules   []minidumpModule
+	memory    []minidumpMemory
+}
+
+type minidumpException struct {
+	threadID uint32
+	code     uint32
+	flags    uint32
+	address  uint64
+	// registers is the context of the crashing thread when the exception was
+	// raised, which takes precedence over the thread's own context.
+	registers registers
+}
+
+type minidumpThread struct {
+	id        uint32
+	registers registers
+}
+
+type minidumpModule struct {
+	base uint64
+	size uint64
+	name string
+	// debugFile and debugID identify the module's Breakpad symbol file, eg.
+	// libnative.so and 3D2C1B0A5F4E71608293A4B5C6D7E8F90.
+	debugFile string
+	debugID   string
+	// codeID is the module's ELF build ID, if it has one.
+	codeID string
+}
+
+type minidumpMemory struct {
+	start uint64
+	data  []byte
+}
+
+// minidumpStream is the location of a stream in the minidump.
+type minidumpStream struct {
+	rva  uint64
+	size uint64
+}
+
+// count caps the entry count read from the header of a list stream by the
+// number of entries that fit in the rest of the stream, so that a malformed
+// count can't drive billions of iterations.
+func (s minidumpStream) count(count, headerSize, entrySize uint64) uint64 {
+	if s.size < headerSize {
+		return 0
+	}
+	return min(count, (s.size-headerSize)/entrySize)
+}
+
+// registers are the values of a thread's registers, named the way Breakpad
+// CFI rules refer to them, eg. rip and rsp or pc and sp.
+type registers map[string]uint64
+
+// parseMinidump parses a minidump.
+func parseMinidump(data []byte) (*minidump, error) {
+	r := minidumpReader(data)
+	if len(data) < 32 || r.u32(0) != minidumpSignature {
+		return nil, fmt.Errorf("%w: bad signature", errInvalidMinidump)
+	}
+
+	md := &minidump{}
+	// streams are the offsets of the streams in the minidump, by stream type.
+	// Offsets within a stream are added to them, since the location descriptors
+	// inside streams are relative to the start of the minidump.
+	streams := make(map[uint32]minidumpStream)
+	directory := uint64(r.u32(12))
+	count := uint64(0)
+	if r.has(directory, 0) {
+		// the directory has no size of its own, so it may take up the rest of the minidump
+		count = minidumpStream{rva: directory, size: uint64(len(r)) - directory}.count(uint64(r.u32(8)), 0, minidumpDirectorySize)
+	}
+
+	for i := uint64(0); i < count; i++ {
+		entry := directory + i*minidumpDirectorySize
+		if _, err := r.location(entry + 4); err != nil {
+			continue
+		}
+		streams[r.u32(entry)] = minidumpStream{rva: uint64(r.u32(entry + 8)), size: uint64(r.u32(entry + 4))}
+	}
+
+	if info, ok := streams[minidumpStreamSystemInfo]; ok {
+		md.arch = r.u16(info.rva)
+		md.platform = r.u32(info.rva + 20)
+	}
+
+	if list, ok := streams[minidumpStreamMemoryList]; ok {
+		md.memory = append(md.memory, parseMemoryList(r, list)...)
+	}
+	if list, ok := streams[minidumpStreamMemory64List]; ok {
+		md.memory = append(md.memory, parseMemory64List(r, list)...)
+	}
+
+	if list, ok := streams[minidumpStreamModuleList]; ok {
+		md.modules = parseModuleList(r, list)
+	}
+
+	if list, ok := streams[minidumpStreamThreadList]; ok {
+		count := uint64(r.u32(list.rva))
+		if list.count(count, 4, minidumpThreadSize) < count {
+			return nil, fmt.Errorf("%w: truncated thread list", errInvalidMinidump)
+		}
+
+		for i := uint64(0); i < count; i++ {
+			entry := list.rva + 4 + i*minidumpThreadSize
+
+			thread := minidumpThread{id: r.u32(entry)}
+			if stack, err := r.location(entry + 32); err == nil {
+				md.memory = append(md.memory, minidumpMemory{start: r.u64(entry + 24), data: stack})
+			}
+			if context, err := r.location(entry + 40); err == nil {
+				thread.registers = md.parseContext(context)
+			}
+			md.threads = append(md.threads, thread)
+		}
+	}
+
+	if exception, ok := streams[minidumpStreamException]; ok {
+		md.exception = &minidumpException{
+			threadID: r.u32(exception.rva),
+			code:     r.u32(exception.rva + 8),
+			flags:    r.u32(exception.rva + 12),
+			address:  r.u64(exception.rva + 24),
+		}
+		if context, err := r.location(exception.rva + 160); err == nil {
+			md.exception.registers = md.parseContext(context)
+		}
+	}
+
+	return md, nil
+}
+
+func parseMemoryList(r minidumpReader, list minidumpStream) []minidumpMemory {
+	memory := make([]minidumpMemory, 0)
+	count := list.count(uint64(r.u32(list.rva)), 4, minidumpMemorySize)
+	for i := uint64(0); i < count; i++ {
+		entry := list.rva + 4 + i*minidumpMemorySize
+		data, err := r.location(entry + 8)
+		if err != nil {
+			continue
+		}
+		memory = append(memory, minidumpMemory{start: r.u64(entry), data: data})
+	}
+	return memory
+}
+
+// parseMemory64List parses the memory of a full memory dump, whose ranges are
+// stored one after the other from a single base offset.
+func parseMemory64List(r minidumpReader, list minidumpStream) []minidumpMemory {
+	memory := make([]minidumpMemory, 0)
+	offset := r.u64(list.rva + 8)
+	count := list.count(r.u64(list.rva), 16, minidumpMemory64Size)
+	for i := uint64(0); i < count; i++ {
+		entry := list.rva + 16 + i*minidumpMemory64Size
+		start, size := r.u64(entry), r.u64(entry+8)
+		if !r.has(offset, size) {
+			break
+		}
+		memory = append(memory, minidumpMemory{start: start, data: r[offset : offset+size]})
+		offset += size
+	}
+	return memory
+}
+
+func parseModuleList(r minidumpReader, list minidumpStream) []minidumpModule {
+	modules := make([]minidumpModule, 0)
+	count := list.count(uint64(r.u32(list.rva)), 4, minidumpModuleSize)
+	for i := uint64(0); i < count; i++ {
+		entry := list.rva + 4 + i*minidumpModuleSize
+
+		module := minidumpModule{
+			base: r.u64(entry),
+			size: uint64(r.u32(entry + 8)),
+			name: r.string(r.u32(entry + 20)),
+		}
+		module.debugFile = module.fileName()
+
+		if cv, err := r.location(entry + 76); err == nil {
+			module.parseCodeView(cv)
+		}
+		// the debug file is part of the symbol file's path in the store, so
+		// modules whose file would reach outside of it aren't looked up
+		if module.debugFile == "." || module.debugFile == ".." || module.debugFile == "/" {
+			module.debugID = ""
+		}
+
+		modules = append(modules, module)
+	}
+	return modules
+}
+
+// parseCodeView reads the debug identifiers of a module from its CodeView
+// record: a PDB 7.0 GUID and age, or a Breakpad ELF build ID.
+func (m *minidumpModule) parseCodeView(cv minidumpReader) {
+	if len(cv) < 4 {
+		return
+	}
+
+	switch cv.u32(0) {
+	case cvSignaturePDB70:
+		if len(cv) < 24 {
+			return
+		}
+		m.debugID = breakpadGUID(cv[4:20]) + fmt.Sprintf("%X", cv.u32(20))
+		if name := strings.TrimRight(string(cv[24:]), "\x00"); name != "" {
+			m.debugFile = path.Base(strings.ReplaceAll(name, `\`, "/"))
+		}
+	case cvSignatureELF:
+		buildID := cv[4:]
+		m.codeID = hex.EncodeToString(buildID)
+		guid := make([]byte, 16)
+		copy(guid, buildID)
+		m.debugID = breakpadGUID(guid) + "0"
+	}
+}
+
+// breakpadGUID formats a little-endian GUID the way Breakpad debug IDs are
+// written, as uppercase hex without dashes.
+func breakpadGUID(b []byte) string {
+	r := minidumpReader(b)
+	return fmt.Sprintf("%08X%04X%04X%X", r.u32(0), r.u16(4), r.u16(6), b[8:16])
+}
+
+// parseContext reads the registers from a thread context of the minidump's
+// architecture. Other architectures aren't supported, and have no registers.
+func (md *minidump) parseContext(context minidumpReader) registers {
+	regs := make(registers)
+
+	switch md.arch {
+	case minidumpCPUAMD64:
+		if len(context) < 256 {
+			return regs
+		}
+		names := []string{"rax", "rcx", "rdx", "rbx", "rsp", "rbp", "rsi", "rdi", "r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15", "rip"}
+		for i, name := range names {
+			regs[name] = context.u64(120 + uint64(i)*8)
+		}
+	case minidumpCPUARM64, minidumpCPUARM64Old:
+		if len(context) < 272 {
+			return regs
+		}
+		for i := uint64(0); i < 31; i++ {
+			regs[fmt.Sprintf("x%d", i)] = context.u64(8 + i*8)
+		}
+		regs["sp"] = context.u64(256)
+		regs["pc"] = context.u64(264)
+	}
+
+	return regs
+}
+
+// pcRegister returns the instruction pointer register of the minidump's architecture.
+func (md *minidump) pcRegister() string {
+	if md.arch == minidumpCPUAMD64 {
+		return "rip"
+	}
+	return "pc"
+}
+
+// spRegister returns the stack pointer register of the minidump's architecture.
+func (md *minidump) spRegister() string {
+	if md.arch == minidumpCPUAMD64 {
+		return "rsp"
+	}
+	return "sp"
+}
+
+// module returns the module that contains an address.
+func (md *minidump) module(addr uint64) (*minidumpModule, bool) {
+	for i := range md.modules {
+		if addr >= md.modules[i].base && addr-md.modules[i].base < md.modules[i].size {
+			return &md.modules[i], true
+		}
+	}
+	return nil, false
+}
+
+// readU64 reads a 64-bit value from the process's memory captured in the minidump.
+func (md *minidump) readU64(addr uint64) (uint64, bool) {
+	for _, region := range md.memory {
+		if addr >= region.start && minidumpReader(region.data).has(addr-region.start, 8) {
+			return binary.LittleEndian.Uint64(region.data[addr-region.start:]), true
+		}
+	}
+	return 0, false
+}
+
+// minidumpReader reads little-endian values from a minidump, returning zero
+// for anything out of bounds.
+type minidumpReader []byte
+
+// has reports whether size bytes at offset are within the minidump. Offsets
+// and sizes are read from the minidump, so this subtracts rather than adds
+// them to avoid overflowing.
+func (r minidumpReader) has(offset, size uint64) bool {
+	return offset <= uint64(len(r)) && size <= uint64(len(r))-offset
+}
+
+func (r minidumpReader) u16(offset uint64) uint16 {
+	if !r.has(offset, 2) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(r[offset:])
+}
+
+func (r minidumpReader) u32(offset uint64) uint32 {
+	if !r.has(offset, 4) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(r[offset:])
+}
+
+func (r minidumpReader) u64(offset uint64) uint64 {
+	if !r.has(offset, 8) {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(r[offset:])
+}
+
+// location returns the data referenced by the location descriptor (a size
+// followed by an offset) at the given offset.
+func (r minidumpReader) location(offset uint64) (minidumpReader, error) {
+	size, rva := uint64(r.u32(offset)), uint64(r.u32(offset+4))
+	if size == 0 || !r.has(rva, size) {
+		return nil, fmt.Errorf("%w: bad location at %d", errInvalidMinidump, offset)
+	}
+	return r[rva : rva+size], nil
+}
+
+// string returns the UTF-16 string at the given offset.
+func (r minidumpReader) string(rva uint32) string {
+	size := uint64(r.u32(uint64(rva)))
+	start := uint64(rva) + 4
+	if !r.has(start, size) {
+		return ""
+	}
+
+	chars := make([]uint16, size/2)
+	for i := range chars {
+		chars[i] = r.u16(start + uint64(i)*2)
+	}
+	return string(utf16.Decode(chars))
+}
+
+var (
+	// signalNames are the names of the signals that crash Linux and Android
+	// processes, the exception code of their minidumps.
+	signalNames = map[uint32]string{
+		4:  "SIGILL",
+		5:  "SIGTRAP",
+		6:  "SIGABRT",
+		7:  "SIGBUS",
+		8:  "SIGFPE",
+		11: "SIGSEGV",
+	}
+	// segvCodeNames are the names of the si_code of a SIGSEGV, the exception
+	// flags of Linux and Android minidumps.
+	segvCodeNames = map[uint32]string{
+		1: "SEGV_MAPERR",
+		2: "SEGV_ACCERR",
+	}
+	// macExceptionNames are the names of Mach exceptions.
+	macExceptionNames = map[uint32]string{
+		1:  "EXC_BAD_ACCESS",
+		2:  "EXC_BAD_INSTRUCTION",
+		3:  "EXC_ARITHMETIC",
+		6:  "EXC_BREAKPOINT",
+		10: "EXC_CRASH",
+		11: "EXC_RESOURCE",
+		12: "EXC_GUARD",
+	}
+	// windowsExceptionNames are the names of Windows exception codes.
+	windowsExceptionNames = map[uint32]string{
+		0x80000003: "EXCEPTION_BREAKPOINT",
+		0xC0000005: "EXCEPTION_ACCESS_VIOLATION",
+		0xC000001D: "EXCEPTION_ILLEGAL_INSTRUCTION",
+		0xC0000094: "EXCEPTION_INT_DIVIDE_BY_ZERO",
+		0xC00000FD: "EXCEPTION_STACK_OVERFLOW",
+		0xC0000409: "STATUS_STACK_BUFFER_OVERRUN",
+		0xC0000374: "STATUS_HEAP_CORRUPTION",
+		0xE06D7363: "EXCEPTION_CPP",
+	}
+)
+
+// crashReason returns the name of the exception or signal that crashed the
+// process, and a message with its details, eg. SIGSEGV and SEGV_MAPERR fault
+// addr 0x0 like Android tombstones.
+func (md *minidump) crashReason() (string, string) {
+	if md.exception == nil {
+		return "", ""
+	}
+
+	code, flags := md.exception.code, md.exception.flags
+	var names map[uint32]string
+
+	switch md.platform {
+	case minidumpOSLinux, minidumpOSAndroid:
+		names = signalNames
+	case minidumpOSMacOS, minidumpOSIOS:
+		names = macExceptionNames
+	case minidumpOSWindows:
+		names = windowsExceptionNames
+	}
+
+	reason, ok := names[code]
+	if !ok {
+		reason = fmt.Sprintf("0x%08x", code)
+	}
+
+	message := fmt.Sprintf("fault addr 0x%x", md.exception.address)
+	if reason == "SIGSEGV" {
+		if codeName, ok := segvCodeNames[flags]; ok {
+			message = codeName + " " + message
+		}
+	}
+
+	return reason, message
+}
+
+// threadRegisters returns the registers of a thread, which for the crashing
+// thread are those it had when the exception was raised.
+func (md *minidump) threadRegisters(thread minidumpThread) registers {
+	if md.crashed(thread) && len(md.exception.registers) > 0 {
+		return md.exception.registers
+	}
+	return thread.registers
+}
+
+// crashed reports whether a thread is the one that crashed the process.
+func (md *minidump) crashed(thread minidumpThread) bool {
+	return md.exception != nil && md.exception.threadID == thread.id
+}
+
+// fileName returns the file name of a module, eg. libnative.so for
+// /data/app/.../lib/arm64/libnative.so or app.exe for C:\app\app.exe.
+func (m *minidumpModule) fileName() string {
+	return path.Base(strings.ReplaceAll(m.name, `\`, "/"))
+}
+
+// formatMinidumpFrame formats a frame in the layout of minidump_stackwalk, with
+// the module and function the frame is in and its source location, eg.
+//
+//	0  libnative.so!handle_tap + 0x2 [/src/app/native.c : 6]
+//
+// Every location but the last was inlined into the one after it.
+func formatMinidumpFrame(idx int, frame unwoundFrame, module *minidumpModule, i int, locations []*mappedNativeStackFrame) string {
+	prefix := fmt.Sprintf("%2d  ", idx)
+
+	switch {
+	case module == nil:
+		return prefix + fmt.Sprintf("0x%x", frame.pc)
+	case len(locations) == 0:
+		return prefix + fmt.Sprintf("%s + 0x%x", module.fileName(), frame.pc-module.base)
+	}
+
+	loc := locations[i]
+	function := fmt.Sprintf("%s + 0x%x", loc.symbol, frame.pc-module.base-loc.symAddr)
+	if i < len(locations)-1 {
+		function = fmt.Sprintf("%s [inlined]", loc.symbol)
+	}
+
+	return prefix + fmt.Sprintf("%s!%s [%s : %d]", module.fileName(), function, loc.path, loc.line)
+}
+
+func (sp *symbolicatorProcessor) processMinidumpThrows(ctx context.Context, attributes pcommon.Map, encoded string) error {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidMinidump, err)
+	}
+
+	md, err := parseMinidump(data)
+	if err != nil {
+		return err
+	}
+
+	// the structured stack trace is of the crashing thread, or of the first
+	// thread of a minidump that was written without a crash
+	crashedIdx := 0
+	for i, thread := range md.threads {
+		if md.crashed(thread) {
+			crashedIdx = i
+		}
+	}
+
+	structured := sp.newStructuredStackTrace(attributes)
+	lines := make([]string, 0)
+	symbolicationFailed := false
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+	// Call frame information of each module, nil for modules without a Breakpad symbol file.
+	cfiCache := make(map[*minidumpModule]*breakpadCFI)
+	cfi := func(module *minidumpModule) *breakpadCFI {
+		if cached, ok := cfiCache[module]; ok {
+			return cached
+		}
+		var moduleCFI *breakpadCFI
+		if module.debugID != "" {
+			moduleCFI, _ = sp.symbolicator.breakpadCFI(ctx, module.debugFile, module.debugID)
+		}
+		cfiCache[module] = moduleCFI
+		return moduleCFI
+	}
+
+	reason, message := md.crashReason()
+	if reason != "" {
+		lines = append(lines, "Crash reason: "+reason, "Crash address: "+fmt.Sprintf("0x%x", md.exception.address), "")
+	}
+
+	for i, thread := range md.threads {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		header := fmt.Sprintf("Thread %d", i)
+		if md.crashed(thread) {
+			header += " (crashed)"
+		}
+		lines = append(lines, header)
+
+		for idx, frame := range unwindThread(md, md.threadRegisters(thread), cfi) {
+			module, _ := md.module(frame.address())
+
+			var locations []*mappedNativeStackFrame
+			status := frameStatusMissingDebugFile
+			binaryName := ""
+
+			if module != nil {
+				binaryName = module.fileName()
+				locations, err = sp.lookupModuleFrame(ctx, module, frame.address()-module.base, fetchErrorCache)
+				switch {
+				case err != nil:
+					status = frameStatusFailed
+					symbolicationFailed = true
+				case len(locations) > 0:
+					status = frameStatusSymbolicated
+				}
+			}
+
+			if i == crashedIdx {
+				structured.appendFrame(binaryName, locations, status)
+			}
+
+			if len(locations) == 0 {
+				lines = append(lines, formatMinidumpFrame(idx, frame, module, 0, nil))
+				continue
+			}
+			for j := range locations {
+				lines = append(lines, formatMinidumpFrame(idx, frame, module, j, locations))
+			}
+		}
+	}
+
+	// the minidump is its own original, and is only kept if asked to
+	if !sp.cfg.PreserveStackTrace {
+		attributes.Remove(sp.cfg.MinidumpAttributeKey)
+	}
+	attributes.PutStr(sp.cfg.StackTraceAttributeKey, strings.Join(lines, "\n"))
+
+	if reason != "" {
+		attributes.PutStr(sp.cfg.ExceptionTypeAttributeKey, reason)
+		attributes.PutStr(sp.cfg.ExceptionMessageAttributeKey, message)
+	}
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+
+	return nil
+}
@@ -0,0 +1,374 @@
+package nativeprocessor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zaptest"
+)
+
+const (
+	testModuleBase = 0x7f0000000000
+	testStackStart = 0x7ffe0000
+	// testCallerPC is a return address outside of any module.
+	testCallerPC = 0x401000
+)
+
+// minidumpBuilder writes AMD64 Linux minidumps for tests.
+type minidumpBuilder struct {
+	buf     []byte
+	streams [][3]uint32
+}
+
+func newMinidumpBuilder() *minidumpBuilder {
+	return &minidumpBuilder{buf: make([]byte, 32)}
+}
+
+// add appends data to the minidump and returns its location descriptor.
+func (b *minidumpBuilder) add(data []byte) []byte {
+	rva := uint32(len(b.buf))
+	b.buf = append(b.buf, data...)
+	return binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, uint32(len(data))), rva)
+}
+
+func (b *minidumpBuilder) addStream(streamType uint32, data []byte) {
+	location := b.add(data)
+	b.streams = append(b.streams, [3]uint32{streamType, binary.LittleEndian.Uint32(location), binary.LittleEndian.Uint32(location[4:])})
+}
+
+func (b *minidumpBuilder) addString(s string) uint32 {
+	chars := utf16.Encode([]rune(s))
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(chars)*2))
+	for _, c := range chars {
+		data = binary.LittleEndian.AppendUint16(data, c)
+	}
+	return binary.LittleEndian.Uint32(b.add(data)[4:])
+}
+
+func (b *minidumpBuilder) addContext(regs map[int]uint64) []byte {
+	context := make([]byte, 1232)
+	for offset, value := range regs {
+		binary.LittleEndian.PutUint64(context[offset:], value)
+	}
+	return b.add(context)
+}
+
+func (b *minidumpBuilder) bytes() []byte {
+	directory := make([]byte, 0, len(b.streams)*12)
+	for _, stream := range b.streams {
+		for _, value := range stream {
+			directory = binary.LittleEndian.AppendUint32(directory, value)
+		}
+	}
+	location := b.add(directory)
+
+	binary.LittleEndian.PutUint32(b.buf[0:], minidumpSignature)
+	binary.LittleEndian.PutUint32(b.buf[4:], 0xa793)
+	binary.LittleEndian.PutUint32(b.buf[8:], uint32(len(b.streams)))
+	copy(b.buf[12:], location[4:])
+	return b.buf
+}
+
+const (
+	amd64RSP = 152
+	amd64RIP = 248
+)
+
+// newTestMinidump returns a minidump of a process that crashed in handle_tap,
+// called from on_event, in a libnative.so with the given name and with the
+// build ID of the test fixture, and has a second thread in on_event.
+func newTestMinidump(t require.TestingT, moduleName string) []byte {
+	b := newMinidumpBuilder()
+
+	systemInfo := make([]byte, 56)
+	binary.LittleEndian.PutUint16(systemInfo[0:], minidumpCPUAMD64)
+	binary.LittleEndian.PutUint32(systemInfo[20:], minidumpOSAndroid)
+	b.addStream(minidumpStreamSystemInfo, systemInfo)
+
+	buildID, err := hex.DecodeString(testBuildID)
+	require.NoError(t, err)
+	cv := b.add(append(binary.LittleEndian.AppendUint32(nil, cvSignatureELF), buildID...))
+	name := b.addString("/data/app/~~Xy==/com.example-Ab==/lib/x86_64/" + moduleName)
+
+	modules := binary.LittleEndian.AppendUint32(nil, 1)
+	module := make([]byte, minidumpModuleSize)
+	binary.LittleEndian.PutUint64(module[0:], testModuleBase)
+	binary.LittleEndian.PutUint32(module[8:], 0x2000)
+	binary.LittleEndian.PutUint32(module[20:], name)
+	copy(module[76:], cv)
+	b.addStream(minidumpStreamModuleList, append(modules, module...))
+
+	// handle_tap's return address into on_event, then on_event's, which
+	// pushed a register first
+	stack := make([]byte, 32)
+	binary.LittleEndian.PutUint64(stack[0:], testModuleBase+0x102f)
+	binary.LittleEndian.PutUint64(stack[16:], testCallerPC)
+	stackLocation := b.add(stack)
+
+	crashedContext := b.addContext(map[int]uint64{amd64RIP: testModuleBase + 0x1022, amd64RSP: testStackStart})
+	otherContext := b.addContext(map[int]uint64{amd64RIP: testModuleBase + 0x1030, amd64RSP: 0x7ffd0000})
+
+	threads := binary.LittleEndian.AppendUint32(nil, 2)
+	for i, context := range [][]byte{crashedContext, otherContext} {
+		thread := make([]byte, minidumpThreadSize)
+		binary.LittleEndian.PutUint32(thread[0:], uint32(100+i))
+		if i == 0 {
+			binary.LittleEndian.PutUint64(thread[24:], testStackStart)
+			copy(thread[32:], stackLocation)
+		}
+		copy(thread[40:], context)
+		threads = append(threads, thread...)
+	}
+	b.addStream(minidumpStreamThreadList, threads)
+
+	exception := make([]byte, 168)
+	binary.LittleEndian.PutUint32(exception[0:], 100)
+	binary.LittleEndian.PutUint32(exception[8:], 11)
+	binary.LittleEndian.PutUint32(exception[12:], 1)
+	copy(exception[160:], crashedContext)
+	b.addStream(minidumpStreamException, exception)
+
+	return b.bytes()
+}
+
+func TestParseMinidump(t *testing.T) {
+	md, err := parseMinidump(newTestMinidump(t, "libnative.so"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint16(minidumpCPUAMD64), md.arch)
+	require.Len(t, md.modules, 1)
+	assert.Equal(t, "libnative.so", md.modules[0].debugFile)
+	assert.Equal(t, "3D2C1B0A5F4E71608293A4B5C6D7E8F90", md.modules[0].debugID)
+	assert.Equal(t, testBuildID, md.modules[0].codeID)
+
+	require.Len(t, md.threads, 2)
+	assert.True(t, md.crashed(md.threads[0]))
+	assert.False(t, md.crashed(md.threads[1]))
+	assert.Equal(t, uint64(testModuleBase+0x1022), md.threadRegisters(md.threads[0])["rip"])
+
+	value, ok := md.readU64(testStackStart + 16)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(testCallerPC), value)
+
+	reason, message := md.crashReason()
+	assert.Equal(t, "SIGSEGV", reason)
+	assert.Equal(t, "SEGV_MAPERR fault addr 0x0", message)
+
+	_, err = parseMinidump([]byte("not a minidump"))
+	assert.ErrorIs(t, err, errInvalidMinidump)
+}
+
+func TestParseMinidump_Malformed(t *testing.T) {
+	valid := newTestMinidump(t, "libnative.so")
+
+	t.Run("truncated", func(t *testing.T) {
+		for i := range valid {
+			assert.NotPanics(t, func() { _, _ = parseMinidump(valid[:i]) })
+		}
+	})
+
+	t.Run("module outside the store", func(t *testing.T) {
+		md, err := parseMinidump(newTestMinidump(t, ".."))
+		require.NoError(t, err)
+		require.Len(t, md.modules, 1)
+		assert.Empty(t, md.modules[0].debugID)
+	})
+
+	t.Run("huge directory count", func(t *testing.T) {
+		data := append([]byte(nil), valid...)
+		binary.LittleEndian.PutUint32(data[8:], 0xffffffff)
+
+		_, err := parseMinidump(data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("huge thread count", func(t *testing.T) {
+		b := newMinidumpBuilder()
+		b.addStream(minidumpStreamThreadList, binary.LittleEndian.AppendUint32(make([]byte, 0, 4+minidumpThreadSize), 0xffffffff))
+
+		_, err := parseMinidump(b.bytes())
+		assert.ErrorIs(t, err, errInvalidMinidump)
+	})
+
+	t.Run("huge memory counts", func(t *testing.T) {
+		b := newMinidumpBuilder()
+		b.addStream(minidumpStreamMemoryList, binary.LittleEndian.AppendUint32(nil, 0xffffffff))
+		b.addStream(minidumpStreamModuleList, binary.LittleEndian.AppendUint32(nil, 0xffffffff))
+		b.addStream(minidumpStreamMemory64List, binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, 1<<63), 32))
+
+		md, err := parseMinidump(b.bytes())
+		require.NoError(t, err)
+		assert.Empty(t, md.memory)
+		assert.Empty(t, md.modules)
+	})
+
+	t.Run("overflowing memory64 range", func(t *testing.T) {
+		memory64 := binary.LittleEndian.AppendUint64(nil, 1)
+		memory64 = binary.LittleEndian.AppendUint64(memory64, 0xfffffffffffffff0)
+		memory64 = binary.LittleEndian.AppendUint64(memory64, testStackStart)
+		memory64 = binary.LittleEndian.AppendUint64(memory64, 0x20)
+
+		b := newMinidumpBuilder()
+		b.addStream(minidumpStreamMemory64List, memory64)
+
+		md, err := parseMinidump(b.bytes())
+		require.NoError(t, err)
+		assert.Empty(t, md.memory)
+	})
+}
+
+func FuzzParseMinidump(f *testing.F) {
+	f.Add(newTestMinidump(f, "libnative.so"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		md, err := parseMinidump(data)
+		if err != nil {
+			return
+		}
+
+		md.crashReason()
+		for _, thread := range md.threads {
+			unwindThread(md, md.threadRegisters(thread), func(*minidumpModule) *breakpadCFI { return nil })
+		}
+	})
+}
+
+func TestParseCodeViewPDB(t *testing.T) {
+	cv := binary.LittleEndian.AppendUint32(nil, cvSignaturePDB70)
+	guid, err := hex.DecodeString("0a1b2c3d4e5f60718293a4b5c6d7e8f9")
+	require.NoError(t, err)
+	cv = append(cv, guid...)
+	cv = binary.LittleEndian.AppendUint32(cv, 2)
+	cv = append(cv, []byte(`C:\build\app.pdb`+"\x00")...)
+
+	module := &minidumpModule{debugFile: "app.exe"}
+	module.parseCodeView(cv)
+
+	assert.Equal(t, "app.pdb", module.debugFile)
+	assert.Equal(t, "3D2C1B0A5F4E71608293A4B5C6D7E8F92", module.debugID)
+	assert.Equal(t, "", module.codeID)
+}
+
+func newTestMinidumpProcessor(t *testing.T, cfg *Config) (*symbolicatorProcessor, func()) {
+	ctx := context.Background()
+	tb, attributes, cleanup := createTestTelemetry(t)
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDebugFileConfiguration{Path: "../test_assets"})
+	require.NoError(t, err)
+	sym, err := newBasicSymbolicator(ctx, 5*time.Second, 64, fs, tb, attributes)
+	require.NoError(t, err)
+
+	return newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, sym, tb, attributes), cleanup
+}
+
+func TestProcessMinidump(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestMinidumpProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.MinidumpAttributeKey, base64.StdEncoding.EncodeToString(newTestMinidump(t, "libnative.so")))
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	attrs := record.Attributes()
+
+	// the crashing thread is unwound with the CFI of the Breakpad symbol file
+	stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `Crash reason: SIGSEGV
+Crash address: 0x0
+
+Thread 0 (crashed)
+ 0  libnative.so!handle_tap + 0x2 [/src/app/native.c : 6]
+ 1  libnative.so!on_event + 0x9 [/src/app/native.c : 10]
+ 2  0x401000
+
+Thread 1
+ 0  libnative.so!on_event + 0xa [/src/app/native.c : 11]`, stackTrace.Str())
+
+	failed, _ := attrs.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+
+	exceptionType, _ := attrs.Get(cfg.ExceptionTypeAttributeKey)
+	assert.Equal(t, "SIGSEGV", exceptionType.Str())
+	exceptionMessage, _ := attrs.Get(cfg.ExceptionMessageAttributeKey)
+	assert.Equal(t, "SEGV_MAPERR fault addr 0x0", exceptionMessage.Str())
+
+	// only the crashing thread is structured
+	binaries, _ := attrs.Get(cfg.OutputStackTraceBinariesAttributeKey)
+	assert.Equal(t, []any{"libnative.so", "libnative.so", ""}, binaries.Slice().AsRaw())
+	functions, _ := attrs.Get(cfg.OutputStackTraceFunctionsAttributeKey)
+	assert.Equal(t, []any{"handle_tap", "on_event", ""}, functions.Slice().AsRaw())
+	lines, _ := attrs.Get(cfg.OutputStackTraceLinesAttributeKey)
+	assert.Equal(t, []any{int64(6), int64(10), int64(0)}, lines.Slice().AsRaw())
+	statuses, _ := attrs.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "symbolicated", "missing_debug_file"}, statuses.Slice().AsRaw())
+
+	_, ok := attrs.Get(cfg.MinidumpAttributeKey)
+	assert.True(t, ok)
+}
+
+func TestProcessMinidumpWithDebugFile(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.PreserveStackTrace = false
+	processor, cleanup := newTestMinidumpProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.MinidumpAttributeKey, base64.StdEncoding.EncodeToString(newTestMinidump(t, "librenamed.so")))
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	attrs := record.Attributes()
+
+	// without a Breakpad symbol file, frames are symbolicated with the ELF debug
+	// file, but there is no CFI and no frame pointer to unwind with
+	stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `Crash reason: SIGSEGV
+Crash address: 0x0
+
+Thread 0 (crashed)
+ 0  librenamed.so!handle_tap + 0x2 [/src/app/native.c : 6]
+
+Thread 1
+ 0  librenamed.so!on_event + 0xa [/src/app/native.c : 10]`, stackTrace.Str())
+
+	_, ok := attrs.Get(cfg.MinidumpAttributeKey)
+	assert.False(t, ok)
+}
+
+func TestProcessInvalidMinidump(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.MinidumpAttributeKey, "not base64!")
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	failed, _ := record.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.True(t, failed.Bool())
+	errorMessage, _ := record.Attributes().Get(cfg.SymbolicatorErrorAttributeKey)
+	assert.Contains(t, errorMessage.Str(), "invalid minidump")
+}
@@ -0,0 +1,391 @@
+package nativeprocessor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidPortablePDB = errors.New("invalid portable pdb")
+
+const (
+	portablePDBSignature = 0x424A5342 // BSJB
+
+	// portablePDBDocumentTable and portablePDBMethodDebugInformationTable are the
+	// numbers of the only tables needed to map IL offsets to source lines.
+	portablePDBDocumentTable               = 0x30
+	portablePDBMethodDebugInformationTable = 0x31
+
+	// methodDefTable is the table of a MethodDef metadata token, in its top byte.
+	methodDefTable = 0x06
+)
+
+// portablePDB is a .NET portable PDB, which maps the IL offsets of each method
+// to sequence points in its source documents.
+type portablePDB struct {
+	documents []string
+	// methods are the sequence points blobs of each method, indexed by its
+	// MethodDef row, and the document of its first sequence point.
+	methods []portablePDBMethod
+	blobs   []byte
+}
+
+type portablePDBMethod struct {
+	document       uint32
+	sequencePoints uint32
+}
+
+// sequencePoint maps the IL instructions from an offset to a source line.
+type sequencePoint struct {
+	ilOffset uint32
+	document uint32
+	line     uint32
+	hidden   bool
+}
+
+// parsePortablePDB parses the metadata of a portable PDB, as described in
+// https://github.com/dotnet/runtime/blob/main/docs/design/specs/PortablePdb-Metadata.md.
+func parsePortablePDB(data []byte) (*portablePDB, error) {
+	r := minidumpReader(data)
+	if len(data) < 16 || r.u32(0) != portablePDBSignature {
+		return nil, fmt.Errorf("%w: bad signature", errInvalidPortablePDB)
+	}
+
+	versionLength := uint64(r.u32(12))
+	offset := 16 + versionLength + 2
+	streamCount := uint64(r.u16(offset))
+	offset += 2
+
+	streams := make(map[string][]byte)
+	for i := uint64(0); i < streamCount; i++ {
+		if offset+8 > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: truncated stream headers", errInvalidPortablePDB)
+		}
+		start, size := uint64(r.u32(offset)), uint64(r.u32(offset+4))
+		offset += 8
+
+		end := offset
+		for end < uint64(len(data)) && data[end] != 0 {
+			end++
+		}
+		name := string(data[offset:end])
+		// stream names are padded to 4 bytes, including their terminator
+		offset = (end + 4) &^ 3
+
+		if start+size > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: stream %s out of bounds", errInvalidPortablePDB, name)
+		}
+		streams[name] = data[start : start+size]
+	}
+
+	tables, ok := streams["#~"]
+	if !ok {
+		return nil, fmt.Errorf("%w: no tables", errInvalidPortablePDB)
+	}
+
+	pdb := &portablePDB{blobs: streams["#Blob"]}
+	if err := pdb.parseTables(tables); err != nil {
+		return nil, err
+	}
+
+	return pdb, nil
+}
+
+// parseTables reads the Document and MethodDebugInformation tables, which are
+// the first tables of a portable PDB as it has no type system tables.
+func (pdb *portablePDB) parseTables(data []byte) error {
+	r := minidumpReader(data)
+	if len(data) < 24 {
+		return fmt.Errorf("%w: truncated tables", errInvalidPortablePDB)
+	}
+
+	heapSizes := data[6]
+	valid := r.u64(8)
+	offset := uint64(24)
+
+	rows := make(map[int]uint32)
+	for table := 0; table < 64; table++ {
+		if valid&(1<<table) != 0 {
+			rows[table] = r.u32(offset)
+			offset += 4
+		}
+	}
+
+	blobIndexSize := uint64(2)
+	if heapSizes&0x04 != 0 {
+		blobIndexSize = 4
+	}
+	guidIndexSize := uint64(2)
+	if heapSizes&0x02 != 0 {
+		guidIndexSize = 4
+	}
+	documentIndexSize := uint64(2)
+	if rows[portablePDBDocumentTable] > 0xffff {
+		documentIndexSize = 4
+	}
+
+	index := func(offset, size uint64) uint32 {
+		if size == 2 {
+			return uint32(r.u16(offset))
+		}
+		return r.u32(offset)
+	}
+	// fits reports whether the rows of a table are within the data, so a
+	// corrupt row count can't make the tables below read or allocate
+	// without bound
+	fits := func(rows uint32, rowSize uint64) bool {
+		return offset <= uint64(len(data)) && uint64(rows)*rowSize <= uint64(len(data))-offset
+	}
+
+	// Document: Name (blob), HashAlgorithm (guid), Hash (blob), Language (guid)
+	documentSize := 2*blobIndexSize + 2*guidIndexSize
+	if !fits(rows[portablePDBDocumentTable], documentSize) {
+		return fmt.Errorf("%w: truncated tables", errInvalidPortablePDB)
+	}
+	for i := uint32(0); i < rows[portablePDBDocumentTable]; i++ {
+		name, err := pdb.documentName(index(offset, blobIndexSize))
+		if err != nil {
+			return err
+		}
+		pdb.documents = append(pdb.documents, name)
+		offset += documentSize
+	}
+
+	// MethodDebugInformation: Document (Document row), SequencePoints (blob)
+	if !fits(rows[portablePDBMethodDebugInformationTable], documentIndexSize+blobIndexSize) {
+		return fmt.Errorf("%w: truncated tables", errInvalidPortablePDB)
+	}
+	for i := uint32(0); i < rows[portablePDBMethodDebugInformationTable]; i++ {
+		pdb.methods = append(pdb.methods, portablePDBMethod{
+			document:       index(offset, documentIndexSize),
+			sequencePoints: index(offset+documentIndexSize, blobIndexSize),
+		})
+		offset += documentIndexSize + blobIndexSize
+	}
+
+	return nil
+}
+
+// blob returns the blob at an index of the #Blob heap.
+func (pdb *portablePDB) blob(idx uint32) ([]byte, error) {
+	if int(idx) >= len(pdb.blobs) {
+		return nil, fmt.Errorf("%w: blob %d out of bounds", errInvalidPortablePDB, idx)
+	}
+
+	b := &blobReader{data: pdb.blobs[idx:]}
+	size, err := b.unsigned()
+	if err != nil {
+		return nil, err
+	}
+	if int(size) > len(b.data) {
+		return nil, fmt.Errorf("%w: blob %d out of bounds", errInvalidPortablePDB, idx)
+	}
+
+	return b.data[:size], nil
+}
+
+// documentName joins the parts of a document name blob with its separator.
+func (pdb *portablePDB) documentName(idx uint32) (string, error) {
+	blob, err := pdb.blob(idx)
+	if err != nil {
+		return "", err
+	}
+	if len(blob) == 0 {
+		return "", nil
+	}
+
+	separator := ""
+	if blob[0] != 0 {
+		separator = string(blob[0])
+	}
+
+	b := &blobReader{data: blob[1:]}
+	parts := make([]string, 0)
+	for len(b.data) > 0 {
+		partIdx, err := b.unsigned()
+		if err != nil {
+			return "", err
+		}
+		part := []byte{}
+		if partIdx != 0 {
+			if part, err = pdb.blob(partIdx); err != nil {
+				return "", err
+			}
+		}
+		parts = append(parts, string(part))
+	}
+
+	return strings.Join(parts, separator), nil
+}
+
+// sequencePoints decodes the sequence points of a method.
+func (pdb *portablePDB) sequencePoints(method portablePDBMethod) ([]sequencePoint, error) {
+	if method.sequencePoints == 0 {
+		return nil, nil
+	}
+
+	blob, err := pdb.blob(method.sequencePoints)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &blobReader{data: blob}
+	// the local signature isn't needed
+	if _, err := b.unsigned(); err != nil {
+		return nil, err
+	}
+
+	document := method.document
+	if document == 0 {
+		if document, err = b.unsigned(); err != nil {
+			return nil, err
+		}
+	}
+
+	points := make([]sequencePoint, 0)
+	var ilOffset, line, column uint32
+	first, firstVisible := true, true
+
+	for len(b.data) > 0 {
+		deltaIL, err := b.unsigned()
+		if err != nil {
+			return nil, err
+		}
+
+		// a zero IL offset delta after the first record changes the document
+		if !first && deltaIL == 0 {
+			if document, err = b.unsigned(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		ilOffset += deltaIL
+		first = false
+
+		deltaLines, err := b.unsigned()
+		if err != nil {
+			return nil, err
+		}
+		var deltaColumns int32
+		if deltaLines == 0 {
+			u, err := b.unsigned()
+			deltaColumns = int32(u)
+			if err != nil {
+				return nil, err
+			}
+		} else if deltaColumns, err = b.signed(); err != nil {
+			return nil, err
+		}
+
+		if deltaLines == 0 && deltaColumns == 0 {
+			points = append(points, sequencePoint{ilOffset: ilOffset, document: document, hidden: true})
+			continue
+		}
+
+		if firstVisible {
+			startLine, err := b.unsigned()
+			if err != nil {
+				return nil, err
+			}
+			startColumn, err := b.unsigned()
+			if err != nil {
+				return nil, err
+			}
+			line, column = startLine, startColumn
+			firstVisible = false
+		} else {
+			deltaLine, err := b.signed()
+			if err != nil {
+				return nil, err
+			}
+			deltaColumn, err := b.signed()
+			if err != nil {
+				return nil, err
+			}
+			line, column = uint32(int32(line)+deltaLine), uint32(int32(column)+deltaColumn)
+		}
+
+		points = append(points, sequencePoint{ilOffset: ilOffset, document: document, line: line})
+	}
+
+	return points, nil
+}
+
+// lookup returns the source document and line of an IL offset in the method
+// with the given MethodDef metadata token.
+func (pdb *portablePDB) lookup(token uint32, ilOffset uint32) (string, uint32, error) {
+	row := token & 0x00ffffff
+	if token>>24 != methodDefTable || row == 0 || int(row) > len(pdb.methods) {
+		return "", 0, fmt.Errorf("invalid method token 0x%x", token)
+	}
+
+	points, err := pdb.sequencePoints(pdb.methods[row-1])
+	if err != nil {
+		return "", 0, err
+	}
+
+	// the sequence point of an offset is the last visible one at or before it
+	var match *sequencePoint
+	for i := range points {
+		if points[i].ilOffset > ilOffset {
+			break
+		}
+		if !points[i].hidden {
+			match = &points[i]
+		}
+	}
+	if match == nil || match.document == 0 || int(match.document) > len(pdb.documents) {
+		return "", 0, fmt.Errorf("could not find sequence point at IL offset 0x%x of method 0x%x", ilOffset, token)
+	}
+
+	return pdb.documents[match.document-1], match.line, nil
+}
+
+// blobReader reads the compressed integers of ECMA-335 blobs.
+type blobReader struct {
+	data []byte
+}
+
+func (b *blobReader) unsigned() (uint32, error) {
+	value, _, err := b.compressed()
+	return value, err
+}
+
+// signed reads a compressed signed integer, which is rotated left by one bit
+// so that its sign is in the lowest bit.
+func (b *blobReader) signed() (int32, error) {
+	value, bits, err := b.compressed()
+	if err != nil {
+		return 0, err
+	}
+
+	if value&1 == 0 {
+		return int32(value >> 1), nil
+	}
+	return int32(value>>1) - (1 << (bits - 1)), nil
+}
+
+// compressed reads a compressed unsigned integer of 1, 2 or 4 bytes, and
+// returns the number of bits its value is stored in.
+func (b *blobReader) compressed() (uint32, int, error) {
+	if len(b.data) == 0 {
+		return 0, 0, fmt.Errorf("%w: truncated blob", errInvalidPortablePDB)
+	}
+
+	switch first := b.data[0]; {
+	case first&0x80 == 0:
+		b.data = b.data[1:]
+		return uint32(first), 7, nil
+	case first&0xc0 == 0x80 && len(b.data) >= 2:
+		value := uint32(binary.BigEndian.Uint16(b.data)) & 0x3fff
+		b.data = b.data[2:]
+		return value, 14, nil
+	case first&0xe0 == 0xc0 && len(b.data) >= 4:
+		value := binary.BigEndian.Uint32(b.data) & 0x1fffffff
+		b.data = b.data[4:]
+		return value, 29, nil
+	}
+
+	return 0, 0, fmt.Errorf("%w: bad compressed integer", errInvalidPortablePDB)
+}
@@ -0,0 +1,98 @@
+package nativeprocessor
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPortablePDB = "../test_assets/b256021a9e734ae5ac782c7668e319abb423a98e.pdb"
+
+func TestParsePortablePDB(t *testing.T) {
+	data, err := os.ReadFile(testPortablePDB)
+	require.NoError(t, err)
+
+	pdb, err := parsePortablePDB(data)
+	require.NoError(t, err)
+	assert.Equal(t, "/src/MyApp/Foo.cs", pdb.documents[0])
+	assert.Len(t, pdb.methods, 4)
+
+	// MyApp.Foo.Bar, MyApp.Foo.Baz and MyApp.Program.Main
+	for _, tc := range []struct {
+		token, ilOffset, line uint32
+	}{
+		{0x06000001, 0x21, 14},
+		{0x06000001, 0x22, 16},
+		{0x06000002, 0x0e, 22},
+		{0x06000004, 0x05, 32},
+	} {
+		file, line, err := pdb.lookup(tc.token, tc.ilOffset)
+		require.NoError(t, err)
+		assert.Equal(t, "/src/MyApp/Foo.cs", file)
+		assert.Equal(t, tc.line, line, "method 0x%x at IL offset 0x%x", tc.token, tc.ilOffset)
+	}
+
+	// only MethodDef tokens of methods in the PDB have sequence points
+	_, _, err = pdb.lookup(0x06000099, 0)
+	assert.Error(t, err)
+	_, _, err = pdb.lookup(0x02000001, 0)
+	assert.Error(t, err)
+
+	_, err = parsePortablePDB([]byte("Microsoft C/C++ MSF 7.00"))
+	assert.ErrorIs(t, err, errInvalidPortablePDB)
+}
+
+func TestParsePortablePDB_Malformed(t *testing.T) {
+	data, err := os.ReadFile(testPortablePDB)
+	require.NoError(t, err)
+
+	for i := range data {
+		assert.NotPanics(t, func() { _, _ = parsePortablePDB(data[:i]) })
+	}
+
+	// tables with row counts far beyond the data are rejected before being read
+	for _, table := range []int{portablePDBDocumentTable, portablePDBMethodDebugInformationTable} {
+		tables := make([]byte, 24, 32)
+		binary.LittleEndian.PutUint64(tables[8:], 1<<portablePDBDocumentTable|1<<portablePDBMethodDebugInformationTable)
+		rows := map[int]uint32{portablePDBDocumentTable: 1, portablePDBMethodDebugInformationTable: 1}
+		rows[table] = 0xfffffff0
+		tables = binary.LittleEndian.AppendUint32(tables, rows[portablePDBDocumentTable])
+		tables = binary.LittleEndian.AppendUint32(tables, rows[portablePDBMethodDebugInformationTable])
+		tables = append(tables, make([]byte, 12)...)
+
+		pdb := &portablePDB{blobs: []byte{0x00}}
+		assert.ErrorIs(t, pdb.parseTables(tables), errInvalidPortablePDB)
+		assert.LessOrEqual(t, len(pdb.documents), 1)
+	}
+}
+
+func TestBlobReader(t *testing.T) {
+	b := &blobReader{data: []byte{0x03, 0x80, 0x80, 0xc0, 0x00, 0x40, 0x00, 0x7f, 0x01, 0x06}}
+
+	value, err := b.unsigned()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), value)
+	value, err = b.unsigned()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x80), value)
+	value, err = b.unsigned()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x4000), value)
+
+	// signed values are rotated so their sign is the lowest bit
+	signed, err := b.signed()
+	require.NoError(t, err)
+	assert.Equal(t, int32(-1), signed)
+	signed, err = b.signed()
+	require.NoError(t, err)
+	assert.Equal(t, int32(-64), signed)
+	signed, err = b.signed()
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), signed)
+
+	_, err = b.unsigned()
+	assert.ErrorIs(t, err, errInvalidPortablePDB)
+}
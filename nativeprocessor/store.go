@@ -0,0 +1,196 @@
+package nativeprocessor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.uber.org/zap"
+)
+
+var (
+	errFailedToFindDebugFile = fmt.Errorf("failed to find debug file")
+)
+
+type store struct {
+	fetch  func(ctx context.Context, key string) ([]byte, error)
+	logger *zap.Logger
+	prefix string
+
+	// layouts are the names of the storeLayouts tried, in order, to find a debug file.
+	layouts []string
+}
+
+func newStore(logger *zap.Logger, prefix string, fetch func(ctx context.Context, key string) ([]byte, error)) *store {
+	return &store{
+		fetch:   fetch,
+		logger:  logger,
+		prefix:  prefix,
+		layouts: []string{storeLayoutBuildID},
+	}
+}
+
+// GetDebugFile fetches the unstripped ELF file or separate debug file with the
+// given build ID, trying each of the store's layouts in order.
+func (s *store) GetDebugFile(ctx context.Context, buildID string) ([]byte, error) {
+	buildID = strings.ToLower(buildID)
+
+	paths := make([]string, 0, len(s.layouts))
+	for _, layout := range s.layouts {
+		path := filepath.Join(s.prefix, storeLayouts[layout](buildID))
+		paths = append(paths, path)
+
+		if debugFileBytes, err := s.fetch(ctx, path); err == nil {
+			return debugFileBytes, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", errFailedToFindDebugFile, strings.Join(paths, ", "))
+}
+
+// GetBreakpadSymbols fetches the Breakpad symbol file of a module, stored the way
+// symupload and dump_syms lay them out: <debug file>/<DEBUG ID>/<debug file>.sym,
+// where a .pdb extension is dropped from the symbol file's name.
+func (s *store) GetBreakpadSymbols(ctx context.Context, debugFile, debugID string) ([]byte, error) {
+	path := filepath.Join(s.prefix, breakpadSymbolsPath(debugFile, debugID))
+
+	symbolsBytes, err := s.fetch(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errFailedToFindDebugFile, path)
+	}
+
+	return symbolsBytes, nil
+}
+
+func breakpadSymbolsPath(debugFile, debugID string) string {
+	name := debugFile
+	if strings.EqualFold(filepath.Ext(name), ".pdb") {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return filepath.Join(debugFile, strings.ToUpper(debugID), name+".sym")
+}
+
+// GetPDB fetches the PDB of a Windows module, stored the way symstore and
+// symbol servers lay them out: <debug file>/<GUID><AGE>/<debug file>.
+func (s *store) GetPDB(ctx context.Context, debugFile, debugID string) ([]byte, error) {
+	path := filepath.Join(s.prefix, pdbPath(debugFile, debugID))
+
+	pdbBytes, err := s.fetch(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errFailedToFindDebugFile, path)
+	}
+
+	return pdbBytes, nil
+}
+
+func pdbPath(debugFile, debugID string) string {
+	return filepath.Join(debugFile, strings.ToUpper(debugID), debugFile)
+}
+
+// GetPortablePDB fetches the portable PDB of a .NET assembly, stored by its debug
+// ID as <debug id>.pdb or, for assemblies that don't report one, by its module
+// version ID as <mvid>.pdb.
+func (s *store) GetPortablePDB(ctx context.Context, debugID, mvid string) ([]byte, error) {
+	paths := make([]string, 0, 2)
+	for _, id := range []string{debugID, mvid} {
+		if id == "" {
+			continue
+		}
+
+		path := filepath.Join(s.prefix, portablePDBPath(id))
+		paths = append(paths, path)
+
+		if pdbBytes, err := s.fetch(ctx, path); err == nil {
+			return pdbBytes, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", errFailedToFindDebugFile, strings.Join(paths, ", "))
+}
+
+func portablePDBPath(id string) string {
+	return strings.ToLower(id) + ".pdb"
+}
+
+func newFileStore(_ context.Context, logger *zap.Logger, cfg *LocalDebugFileConfiguration) (*store, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no file configuration provided")
+	}
+
+	return newStore(logger, cfg.Path, func(ctx context.Context, key string) ([]byte, error) {
+		return os.ReadFile(key)
+	}), nil
+}
+
+func newS3Store(ctx context.Context, logger *zap.Logger, cfg *S3DebugFileConfiguration) (*store, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no S3 configuration provided")
+	}
+
+	options := make([]func(*config.LoadOptions) error, 0)
+
+	if cfg.Region != "" {
+		options = append(options, config.WithRegion(cfg.Region))
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(ctx, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(awsConfig)
+
+	return newStore(logger, cfg.Prefix, func(ctx context.Context, key string) ([]byte, error) {
+		key = strings.TrimPrefix(key, "/")
+
+		result, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(cfg.BucketName),
+			Key:    aws.String(key),
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer result.Body.Close()
+
+		return io.ReadAll(result.Body)
+	}), nil
+}
+
+func newGCSStore(ctx context.Context, logger *zap.Logger, cfg *GCSDebugFileConfiguration) (*store, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no GCS configuration provided")
+	}
+
+	client, err := storage.NewClient(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := client.Bucket(cfg.BucketName)
+
+	return newStore(logger, cfg.Prefix, func(ctx context.Context, key string) ([]byte, error) {
+		// GCS keys can't start with a slash
+		key = strings.TrimPrefix(key, "/")
+
+		r, err := bucket.Object(key).NewReader(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer r.Close()
+
+		return io.ReadAll(r)
+	}), nil
+}
@@ -0,0 +1,40 @@
+package nativeprocessor
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	// storeLayoutBuildID is the .build-id directory layout used by GDB, LLDB and
+	// most Linux distributions: .build-id/ab/cdef....debug.
+	storeLayoutBuildID = "build_id"
+	// storeLayoutDebuginfod is the layout of a debuginfod server:
+	// buildid/abcdef.../debuginfo.
+	storeLayoutDebuginfod = "debuginfod"
+)
+
+// storeLayouts resolve the key of a debug file, relative to the store prefix,
+// for each supported layout. Build IDs are always lowercase hex.
+var storeLayouts = map[string]func(buildID string) string{
+	storeLayoutBuildID: func(buildID string) string {
+		if len(buildID) < 3 {
+			return filepath.Join(".build-id", buildID+".debug")
+		}
+		return filepath.Join(".build-id", buildID[:2], buildID[2:]+".debug")
+	},
+	storeLayoutDebuginfod: func(buildID string) string {
+		return filepath.Join("buildid", buildID, "debuginfo")
+	},
+}
+
+// validateStoreLayouts checks that every layout is supported.
+func validateStoreLayouts(layouts []string) error {
+	for _, layout := range layouts {
+		if _, ok := storeLayouts[layout]; !ok {
+			return fmt.Errorf("unknown debug file store layout %q, must be one of %q or %q",
+				layout, storeLayoutBuildID, storeLayoutDebuginfod)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package nativeprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDebugFileConfiguration{Path: "../test_assets"})
+	assert.NoError(t, err)
+
+	source, err := fs.GetDebugFile(ctx, "0A1B2C3D4E5F60718293A4B5C6D7E8F9")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+
+	_, err = fs.GetDebugFile(ctx, "ffffffffffffffffffffffffffffffff")
+	assert.ErrorIs(t, err, errFailedToFindDebugFile)
+}
+
+func TestStoreLayouts(t *testing.T) {
+	assert.Equal(t, ".build-id/0a/1b2c3d4e5f60718293a4b5c6d7e8f9.debug", storeLayouts[storeLayoutBuildID]("0a1b2c3d4e5f60718293a4b5c6d7e8f9"))
+	assert.Equal(t, "buildid/0a1b2c3d4e5f60718293a4b5c6d7e8f9/debuginfo", storeLayouts[storeLayoutDebuginfod]("0a1b2c3d4e5f60718293a4b5c6d7e8f9"))
+
+	assert.NoError(t, validateStoreLayouts([]string{storeLayoutDebuginfod, storeLayoutBuildID}))
+	assert.Error(t, validateStoreLayouts([]string{"dsym"}))
+}
+
+func TestBreakpadSymbols(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDebugFileConfiguration{Path: "../test_assets"})
+	assert.NoError(t, err)
+
+	source, err := fs.GetBreakpadSymbols(ctx, "libnative.so", "3d2c1b0a5f4e71608293a4b5c6d7e8f90")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+
+	_, err = fs.GetBreakpadSymbols(ctx, "libother.so", "3D2C1B0A5F4E71608293A4B5C6D7E8F90")
+	assert.ErrorIs(t, err, errFailedToFindDebugFile)
+
+	assert.Equal(t, "app.pdb/0A1B2C3D4E5F60718293A4B5C6D7E8F91/app.sym", breakpadSymbolsPath("app.pdb", "0a1b2c3d4e5f60718293a4b5c6d7e8f91"))
+}
+
+func TestPDB(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDebugFileConfiguration{Path: "../test_assets"})
+	assert.NoError(t, err)
+
+	source, err := fs.GetPDB(ctx, "app.pdb", "3d2c1b0a5f4e71608293a4b5c6d7e8f91")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+
+	_, err = fs.GetPDB(ctx, "app.pdb", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF1")
+	assert.ErrorIs(t, err, errFailedToFindDebugFile)
+}
+
+func TestPortablePDB(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDebugFileConfiguration{Path: "../test_assets"})
+	assert.NoError(t, err)
+
+	source, err := fs.GetPortablePDB(ctx, "B256021A9E734AE5AC782C7668E319ABB423A98E", "c50de4b196cc418b8cadb011134bfc5c")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, source)
+
+	// without a PDB ID it's looked up by the MVID
+	_, err = fs.GetPortablePDB(ctx, "", "c50de4b196cc418b8cadb011134bfc5c")
+	assert.ErrorIs(t, err, errFailedToFindDebugFile)
+	assert.ErrorContains(t, err, "c50de4b196cc418b8cadb011134bfc5c.pdb")
+}
@@ -0,0 +1,59 @@
+package nativeprocessor
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// frameStatus is the outcome of symbolicating a single frame.
+type frameStatus string
+
+const (
+	// frameStatusSymbolicated means the frame was symbolicated.
+	frameStatusSymbolicated frameStatus = "symbolicated"
+	// frameStatusMissingDebugFile means no debug file was found for the frame.
+	frameStatusMissingDebugFile frameStatus = "missing_debug_file"
+	// frameStatusFailed means the frame could not be symbolicated.
+	frameStatusFailed frameStatus = "failed"
+)
+
+// structuredStackTrace is the symbolicated stack trace written as parallel
+// slice attributes, one entry per symbolicated location. An address that maps
+// to several inlined locations gets one entry for each of them, innermost first.
+type structuredStackTrace struct {
+	binaries  pcommon.Slice
+	functions pcommon.Slice
+	files     pcommon.Slice
+	lines     pcommon.Slice
+	statuses  pcommon.Slice
+}
+
+func (sp *symbolicatorProcessor) newStructuredStackTrace(attributes pcommon.Map) *structuredStackTrace {
+	return &structuredStackTrace{
+		binaries:  attributes.PutEmptySlice(sp.cfg.OutputStackTraceBinariesAttributeKey),
+		functions: attributes.PutEmptySlice(sp.cfg.OutputStackTraceFunctionsAttributeKey),
+		files:     attributes.PutEmptySlice(sp.cfg.OutputStackTraceFilesAttributeKey),
+		lines:     attributes.PutEmptySlice(sp.cfg.OutputStackTraceLinesAttributeKey),
+		statuses:  attributes.PutEmptySlice(sp.cfg.OutputStackTraceFrameStatusesAttributeKey),
+	}
+}
+
+// appendFrame appends a frame's locations. A frame that wasn't symbolicated
+// gets a single entry with just its binary.
+func (s *structuredStackTrace) appendFrame(binaryName string, locations []*mappedNativeStackFrame, status frameStatus) {
+	if len(locations) == 0 {
+		s.appendLocation(binaryName, "", "", 0, status)
+		return
+	}
+
+	for _, loc := range locations {
+		s.appendLocation(binaryName, loc.symbol, loc.path, loc.line, status)
+	}
+}
+
+func (s *structuredStackTrace) appendLocation(binaryName, function, file string, line uint32, status frameStatus) {
+	s.binaries.AppendEmpty().SetStr(binaryName)
+	s.functions.AppendEmpty().SetStr(function)
+	s.files.AppendEmpty().SetStr(file)
+	s.lines.AppendEmpty().SetInt(int64(line))
+	s.statuses.AppendEmpty().SetStr(string(status))
+}
@@ -0,0 +1,294 @@
+package nativeprocessor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/honeycombio/opentelemetry-collector-symbolicator/nativeprocessor/internal/metadata"
+	"github.com/honeycombio/symbolic-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// FetchError indicates a debug file could not be fetched from the store (e.g., 404, timeout).
+// These errors are safe to cache as they indicate the resource is unavailable.
+type FetchError struct {
+	BuildID string
+	Err     error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("failed to fetch debug file for %s: %v", e.BuildID, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+type debugFileStore interface {
+	GetDebugFile(ctx context.Context, buildID string) ([]byte, error)
+	GetBreakpadSymbols(ctx context.Context, debugFile, debugID string) ([]byte, error)
+	GetPDB(ctx context.Context, debugFile, debugID string) ([]byte, error)
+	GetPortablePDB(ctx context.Context, debugID, mvid string) ([]byte, error)
+}
+
+// debugFile is a parsed debug file, with the call frame information of
+// Breakpad symbol files. A .NET portable PDB only has its sequence points.
+type debugFile struct {
+	archive     *symbolic.Archive
+	cfi         *breakpadCFI
+	portablePDB *portablePDB
+}
+
+type basicSymbolicator struct {
+	store   debugFileStore
+	timeout time.Duration
+	ch      chan struct{}
+	cache   *lru.Cache[string, *debugFile]
+
+	telemetryBuilder *metadata.TelemetryBuilder
+	attributes       metric.MeasurementOption
+}
+
+func newBasicSymbolicator(_ context.Context, timeout time.Duration, cacheSize int, store debugFileStore, tb *metadata.TelemetryBuilder, attributes attribute.Set) (*basicSymbolicator, error) {
+	cache, err := lru.New[string, *debugFile](cacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &basicSymbolicator{
+		store:   store,
+		timeout: timeout,
+		// the channel is buffered to allow for a single request to be in progress at a time
+		ch:               make(chan struct{}, 1),
+		cache:            cache,
+		telemetryBuilder: tb,
+		attributes:       metric.WithAttributeSet(attributes),
+	}, nil
+}
+
+type mappedNativeStackFrame struct {
+	path      string
+	instrAddr uint64
+	lang      string
+	line      uint32
+	symAddr   uint64
+	symbol    string
+}
+
+// symbolicateFrame symbolicates an address, relative to the load address of the
+// ELF file with the given build ID. Inlined locations are returned innermost first.
+func (ns *basicSymbolicator) symbolicateFrame(ctx context.Context, buildID string, addr uint64) ([]*mappedNativeStackFrame, error) {
+	file, err := ns.getDebugFile(ctx, strings.ToLower(buildID), buildID, func() ([]byte, error) {
+		return ns.store.GetDebugFile(ctx, buildID)
+	}, parseArchive)
+	if err != nil {
+		return nil, err
+	}
+
+	return lookupAddress(file.archive, elfDebugID(buildID), addr)
+}
+
+// symbolicateBreakpadFrame symbolicates an address, relative to the load address
+// of a module, with the module's Breakpad symbol file.
+func (ns *basicSymbolicator) symbolicateBreakpadFrame(ctx context.Context, debugFile, debugID string, addr uint64) ([]*mappedNativeStackFrame, error) {
+	file, err := ns.getBreakpadSymbols(ctx, debugFile, debugID)
+	if err != nil {
+		return nil, err
+	}
+
+	return lookupAddress(file.archive, breakpadDebugID(debugID), addr)
+}
+
+// symbolicatePDBFrame symbolicates an address, relative to the load address of
+// a Windows module, with the module's PDB.
+func (ns *basicSymbolicator) symbolicatePDBFrame(ctx context.Context, debugFile, debugID string, addr uint64) ([]*mappedNativeStackFrame, error) {
+	file, err := ns.getDebugFile(ctx, pdbPath(debugFile, debugID), debugID, func() ([]byte, error) {
+		return ns.store.GetPDB(ctx, debugFile, debugID)
+	}, parseArchive)
+	if err != nil {
+		return nil, err
+	}
+
+	return lookupAddress(file.archive, breakpadDebugID(debugID), addr)
+}
+
+// symbolicateDotnetFrame maps an IL offset in the method with the given MethodDef
+// metadata token to its source location, with the assembly's portable PDB.
+func (ns *basicSymbolicator) symbolicateDotnetFrame(ctx context.Context, debugID, mvid string, token, ilOffset uint32) ([]*mappedNativeStackFrame, error) {
+	id := mvid
+	if debugID != "" {
+		id = debugID
+	}
+
+	file, err := ns.getDebugFile(ctx, portablePDBPath(id), id, func() ([]byte, error) {
+		return ns.store.GetPortablePDB(ctx, debugID, mvid)
+	}, parsePortablePDBFile)
+	if err != nil {
+		return nil, err
+	}
+
+	path, line, err := file.portablePDB.lookup(token, ilOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*mappedNativeStackFrame{{path: path, line: line}}, nil
+}
+
+// breakpadCFI returns the call frame information of a module's Breakpad symbol file.
+func (ns *basicSymbolicator) breakpadCFI(ctx context.Context, debugFile, debugID string) (*breakpadCFI, error) {
+	file, err := ns.getBreakpadSymbols(ctx, debugFile, debugID)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.cfi, nil
+}
+
+func (ns *basicSymbolicator) getBreakpadSymbols(ctx context.Context, debugFile, debugID string) (*debugFile, error) {
+	return ns.getDebugFile(ctx, breakpadSymbolsPath(debugFile, debugID), debugID, func() ([]byte, error) {
+		return ns.store.GetBreakpadSymbols(ctx, debugFile, debugID)
+	}, parseBreakpadSymbols)
+}
+
+// parseArchive parses the objects of a debug file that symbolic can read.
+func parseArchive(data []byte) (*debugFile, error) {
+	archive, err := symbolic.NewArchiveFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &debugFile{archive: archive}, nil
+}
+
+// parseBreakpadSymbols parses a Breakpad symbol file with its call frame information.
+func parseBreakpadSymbols(data []byte) (*debugFile, error) {
+	file, err := parseArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	file.cfi = parseBreakpadCFI(data)
+	return file, nil
+}
+
+// parsePortablePDBFile parses a .NET portable PDB.
+func parsePortablePDBFile(data []byte) (*debugFile, error) {
+	pdb, err := parsePortablePDB(data)
+	if err != nil {
+		return nil, err
+	}
+	return &debugFile{portablePDB: pdb}, nil
+}
+
+// getDebugFile returns a debug file from the cache, fetching and parsing it
+// if it isn't cached yet.
+func (ns *basicSymbolicator) getDebugFile(ctx context.Context, cacheKey, id string, fetch func() ([]byte, error), parse func([]byte) (*debugFile, error)) (*debugFile, error) {
+	select {
+	case ns.ch <- struct{}{}:
+	case <-time.After(ns.timeout):
+		return nil, &FetchError{BuildID: id, Err: fmt.Errorf("timeout")}
+	}
+
+	defer func() {
+		<-ns.ch
+	}()
+
+	file, ok := ns.cache.Get(cacheKey)
+	ns.telemetryBuilder.ProcessorDebugFileCacheSize.Record(ctx, int64(ns.cache.Len()), ns.attributes)
+
+	if !ok {
+		debugFileBytes, err := fetch()
+		if err != nil {
+			ns.telemetryBuilder.ProcessorTotalDebugFileFetchFailures.Add(ctx, 1, ns.attributes)
+			return nil, &FetchError{BuildID: id, Err: err}
+		}
+
+		file, err = parse(debugFileBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		ns.cache.Add(cacheKey, file)
+	}
+
+	// If the cache size has changed, we should record the new size
+	ns.telemetryBuilder.ProcessorDebugFileCacheSize.Record(ctx, int64(ns.cache.Len()), ns.attributes)
+
+	return file, nil
+}
+
+// lookupAddress symbolicates an address in the object of an archive with the
+// given debug ID. Inlined locations are returned innermost first.
+func lookupAddress(archive *symbolic.Archive, debugID string, addr uint64) ([]*mappedNativeStackFrame, error) {
+	symCache, ok := archive.SymCaches[debugID]
+	if !ok && len(archive.SymCaches) == 1 {
+		// a debug file found by its identifier only has the one object
+		for _, sc := range archive.SymCaches {
+			symCache = sc
+		}
+		ok = true
+	}
+	if !ok {
+		return nil, fmt.Errorf("could not find symcache for debug id %s", debugID)
+	}
+
+	locations, err := symCache.Lookup(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("could not find symbol at location %d", addr)
+	}
+
+	res := make([]*mappedNativeStackFrame, len(locations))
+	for i, loc := range locations {
+		res[i] = &mappedNativeStackFrame{
+			path:      loc.FullPath,
+			instrAddr: loc.InstrAddr,
+			lang:      loc.Lang,
+			line:      loc.Line,
+			symAddr:   loc.SymAddr,
+			symbol:    loc.Symbol,
+		}
+	}
+
+	return res, nil
+}
+
+// elfDebugID converts a GNU build ID to the debug ID symbolic identifies ELF
+// objects by: its first 16 bytes, zero padded, read as a little-endian GUID.
+func elfDebugID(buildID string) string {
+	id := strings.ToLower(buildID)
+	if len(id) < 32 {
+		id += strings.Repeat("0", 32-len(id))
+	}
+	id = id[:32]
+
+	swap := func(s string) string {
+		var b strings.Builder
+		for i := len(s) - 2; i >= 0; i -= 2 {
+			b.WriteString(s[i : i+2])
+		}
+		return b.String()
+	}
+
+	return swap(id[0:8]) + "-" + swap(id[8:12]) + "-" + swap(id[12:16]) + "-" + id[16:20] + "-" + id[20:32]
+}
+
+// breakpadDebugID converts a Breakpad or symstore debug ID, a GUID followed by an
+// age such as 3D2C1B0A5F4E71608293A4B5C6D7E8F90, to the debug ID symbolic uses for it.
+func breakpadDebugID(debugID string) string {
+	id := strings.ToLower(debugID)
+	if len(id) < 32 {
+		return id
+	}
+
+	res := id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+	if age := strings.TrimLeft(id[32:], "0"); age != "" {
+		res += "-" + age
+	}
+	return res
+}
@@ -0,0 +1,59 @@
+package nativeprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/opentelemetry-collector-symbolicator/nativeprocessor/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestNativeSymbolicator(t *testing.T) {
+	ctx := context.Background()
+
+	testTel := componenttest.NewTelemetry()
+	tb, err := metadata.NewTelemetryBuilder(testTel.NewTelemetrySettings())
+	assert.NoError(t, err)
+	defer tb.Shutdown()
+
+	attributes := attribute.NewSet(
+		attribute.String("processor_type", "native_symbolicator"),
+	)
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDebugFileConfiguration{Path: "../test_assets"})
+	assert.NoError(t, err)
+	sym, _ := newBasicSymbolicator(ctx, 5*time.Second, 64, fs, tb, attributes)
+
+	frames, err := sym.symbolicateFrame(ctx, "0a1b2c3d4e5f60718293a4b5c6d7e8f9", 0x102f)
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+	assert.Equal(t, "on_event", frames[0].symbol)
+	assert.Equal(t, "/src/app/native.c", frames[0].path)
+	assert.Equal(t, uint32(10), frames[0].line)
+
+	// served from the cache
+	frames, err = sym.symbolicateFrame(ctx, "0A1B2C3D4E5F60718293A4B5C6D7E8F9", 0x1022)
+	require.NoError(t, err)
+	assert.Equal(t, "handle_tap", frames[0].symbol)
+	assert.Equal(t, 1, sym.cache.Len())
+
+	// build ID doesn't exist
+	_, err = sym.symbolicateFrame(ctx, "ffffffffffffffffffffffffffffffff", 0x102f)
+	assert.ErrorIs(t, err, errFailedToFindDebugFile)
+
+	// nothing at that address
+	_, err = sym.symbolicateFrame(ctx, "0a1b2c3d4e5f60718293a4b5c6d7e8f9", 9999999999)
+	assert.Error(t, err)
+}
+
+func TestELFDebugID(t *testing.T) {
+	assert.Equal(t, "3d2c1b0a-5f4e-7160-8293-a4b5c6d7e8f9", elfDebugID("0a1b2c3d4e5f60718293a4b5c6d7e8f9"))
+	// SHA-1 build IDs are truncated, short ones are padded
+	assert.Equal(t, "3d2c1b0a-5f4e-7160-8293-a4b5c6d7e8f9", elfDebugID("0A1B2C3D4E5F60718293A4B5C6D7E8F901234567"))
+	assert.Equal(t, "3d2c1b0a-0000-0000-0000-000000000000", elfDebugID("0a1b2c3d"))
+}
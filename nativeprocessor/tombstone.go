@@ -0,0 +1,239 @@
+package nativeprocessor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// tombstoneThreadSeparator separates the threads of a full tombstone. The
+// crashing thread's backtrace always comes first.
+const tombstoneThreadSeparator = "--- --- ---"
+
+var (
+	// groups: line prefix up to the pc, frame index, pc, the rest of the line
+	tombstoneFrameRegex = regexp.MustCompile(`^(.*?#(\d+)\s+pc\s+)([\da-fA-F]+)(.*)$`)
+	// groups: spacing, mapping path, the rest of the line
+	tombstoneMappingRegex = regexp.MustCompile(`^(\s+)(\S+)(.*)$`)
+	// groups: build id
+	tombstoneBuildIDRegex = regexp.MustCompile(`\(BuildId: ([\da-fA-F]+)\)`)
+	// matches the offset into an APK that a library is mapped from
+	tombstoneOffsetRegex = regexp.MustCompile(`\(offset 0x[\da-fA-F]+\)`)
+	// groups: signal name, code name, fault address
+	tombstoneSignalRegex = regexp.MustCompile(`signal \d+ \((\w+)\), code -?\d+ \((\w+)\)(?:, fault addr (\S+))?`)
+	// groups: abort message
+	tombstoneAbortRegex = regexp.MustCompile(`Abort message: '(.*)'`)
+	// groups: cause
+	tombstoneCauseRegex = regexp.MustCompile(`Cause: (.*)$`)
+)
+
+// tombstone is a native crash report from Android's debuggerd, either the full
+// tombstone or the backtrace it logs to logcat.
+type tombstone struct {
+	exceptionType    string
+	exceptionMessage string
+	// lines are the frames, and every other line of the tombstone, which are
+	// copied as-is.
+	lines []tombstoneLine
+}
+
+type tombstoneLine struct {
+	raw   string
+	frame *tombstoneFrame
+	// crashed is true for the frames of the crashing thread.
+	crashed bool
+}
+
+type tombstoneFrame struct {
+	// prefix is everything up to the pc, eg. "      #00 pc ".
+	prefix string
+	// pc is relative to the start of the ELF file the frame is in.
+	pc uint64
+	// pcText is the pc as written, to keep its zero padding.
+	pcText string
+	// spacing is the whitespace between the pc and the mapping.
+	spacing string
+	// mapping is the path of the ELF file or APK the frame is in.
+	mapping string
+	// offset is the offset into an APK the ELF file is mapped from, if any.
+	offset  string
+	buildID string
+}
+
+// isTombstone reports whether a stack trace contains Android native backtrace frames.
+func isTombstone(raw string) bool {
+	for _, line := range strings.Split(raw, "\n") {
+		if tombstoneFrameRegex.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTombstone parses an Android tombstone or logcat backtrace, eg.
+//
+//	signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x0
+//	backtrace:
+//	      #00 pc 0000000000001020  /data/app/~~Xy==/com.example-Ab==/lib/arm64/libnative.so (BuildId: 0a1b2c3d...)
+func parseTombstone(raw string) *tombstone {
+	t := &tombstone{}
+	crashed := true
+	var cause, signalMessage string
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.Contains(line, tombstoneThreadSeparator) {
+			crashed = false
+		}
+
+		if matches := tombstoneSignalRegex.FindStringSubmatch(line); matches != nil && t.exceptionType == "" {
+			t.exceptionType = matches[1]
+			signalMessage = matches[2]
+			if matches[3] != "" {
+				signalMessage += " fault addr " + matches[3]
+			}
+		}
+		if matches := tombstoneAbortRegex.FindStringSubmatch(line); matches != nil {
+			t.exceptionMessage = matches[1]
+		}
+		if matches := tombstoneCauseRegex.FindStringSubmatch(line); matches != nil {
+			cause = matches[1]
+		}
+
+		t.lines = append(t.lines, tombstoneLine{raw: line, frame: parseTombstoneFrame(line), crashed: crashed})
+	}
+
+	if t.exceptionMessage == "" {
+		t.exceptionMessage = cause
+	}
+	if t.exceptionMessage == "" {
+		t.exceptionMessage = signalMessage
+	}
+
+	return t
+}
+
+func parseTombstoneFrame(line string) *tombstoneFrame {
+	matches := tombstoneFrameRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+
+	pc, err := strconv.ParseUint(matches[3], 16, 64)
+	if err != nil {
+		return nil
+	}
+
+	frame := &tombstoneFrame{prefix: matches[1], pc: pc, pcText: matches[3]}
+
+	if mapping := tombstoneMappingRegex.FindStringSubmatch(matches[4]); mapping != nil {
+		frame.spacing = mapping[1]
+		frame.mapping = mapping[2]
+	}
+	frame.offset = tombstoneOffsetRegex.FindString(matches[4])
+	if buildID := tombstoneBuildIDRegex.FindStringSubmatch(matches[4]); buildID != nil {
+		frame.buildID = strings.ToLower(buildID[1])
+	}
+
+	return frame
+}
+
+// binaryName returns the file name of the library a frame is in, eg. libnative.so
+// for /data/app/.../lib/arm64/libnative.so or base.apk!libnative.so.
+func (f *tombstoneFrame) binaryName() string {
+	name := path.Base(f.mapping)
+	if idx := strings.LastIndex(name, "!"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+// formatTombstoneFrame formats a symbolicated location in the same layout as the
+// tombstone, with the function and its source location. Every location but the
+// last was inlined into the one after it.
+func formatTombstoneFrame(frame *tombstoneFrame, i int, locations []*mappedNativeStackFrame) string {
+	loc := locations[i]
+
+	mapping := frame.mapping
+	if frame.offset != "" {
+		mapping += " " + frame.offset
+	}
+
+	function := fmt.Sprintf("(%s+%d)", loc.symbol, frame.pc-loc.symAddr)
+	if i < len(locations)-1 {
+		function = fmt.Sprintf("(%s) [inlined]", loc.symbol)
+	}
+
+	return fmt.Sprintf("%s%s%s%s %s (%s:%d) (BuildId: %s)", frame.prefix, frame.pcText, frame.spacing, mapping, function, loc.path, loc.line, frame.buildID)
+}
+
+func (sp *symbolicatorProcessor) processTombstoneThrows(ctx context.Context, attributes pcommon.Map, raw string) error {
+	t := parseTombstone(raw)
+
+	structured := sp.newStructuredStackTrace(attributes)
+	lines := make([]string, 0, len(t.lines))
+	symbolicationFailed := false
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	for _, line := range t.lines {
+		if line.frame == nil {
+			lines = append(lines, line.raw)
+			continue
+		}
+
+		var locations []*mappedNativeStackFrame
+		status := frameStatusMissingDebugFile
+
+		// frames without a build ID, eg. in anonymous or JIT mappings, can't be looked up
+		if line.frame.buildID != "" {
+			var err error
+			locations, err = sp.lookupFrame(ctx, line.frame.buildID, line.frame.pc, fetchErrorCache)
+			switch {
+			case err != nil:
+				status = frameStatusFailed
+				symbolicationFailed = true
+			case len(locations) > 0:
+				status = frameStatusSymbolicated
+			}
+		}
+
+		if line.crashed {
+			structured.appendFrame(line.frame.binaryName(), locations, status)
+		}
+
+		if len(locations) == 0 {
+			lines = append(lines, line.raw)
+			continue
+		}
+
+		for i := range locations {
+			lines = append(lines, formatTombstoneFrame(line.frame, i, locations))
+		}
+	}
+
+	if sp.cfg.PreserveStackTrace {
+		attributes.PutStr(sp.cfg.OriginalStackTraceAttributeKey, raw)
+	}
+	attributes.PutStr(sp.cfg.StackTraceAttributeKey, strings.Join(lines, "\n"))
+
+	if t.exceptionType != "" {
+		attributes.PutStr(sp.cfg.ExceptionTypeAttributeKey, t.exceptionType)
+	}
+	if t.exceptionMessage != "" {
+		attributes.PutStr(sp.cfg.ExceptionMessageAttributeKey, t.exceptionMessage)
+	}
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+
+	return nil
+}
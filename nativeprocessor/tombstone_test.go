@@ -0,0 +1,148 @@
+package nativeprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zaptest"
+)
+
+const testTombstone = `*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+Build fingerprint: 'google/oriole/oriole:14/UQ1A.240205.004/11269751:user/release-keys'
+ABI: 'arm64'
+pid: 4321, tid: 4321, name: com.example  >>> com.example <<<
+signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x0000000000000000
+Cause: null pointer dereference
+
+backtrace:
+      #00 pc 0000000000001022  /data/app/~~Xy==/com.example-Ab==/lib/arm64/libnative.so (handle_tap+2) (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)
+      #01 pc 000000000000102f  /data/app/~~Xy==/com.example-Ab==/base.apk!libnative.so (offset 0x4000) (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)
+      #02 pc 00000000000b0a54  /apex/com.android.runtime/lib64/bionic/libc.so (__libc_init+96) (BuildId: 5812256023147338b8a9538321d4c456)
+      #03 pc 0000000000001d6c  [anon:dalvik-jit-code-cache]
+
+--- --- --- --- --- --- --- --- --- --- --- --- --- --- --- ---
+pid: 4321, tid: 4330, name: RenderThread  >>> com.example <<<
+backtrace:
+      #00 pc 000000000000102f  /data/app/~~Xy==/com.example-Ab==/lib/arm64/libnative.so (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)`
+
+func TestParseTombstone(t *testing.T) {
+	tombstone := parseTombstone(testTombstone)
+
+	assert.Equal(t, "SIGSEGV", tombstone.exceptionType)
+	assert.Equal(t, "null pointer dereference", tombstone.exceptionMessage)
+
+	frames := make([]*tombstoneFrame, 0)
+	crashed := 0
+	for _, line := range tombstone.lines {
+		if line.frame != nil {
+			frames = append(frames, line.frame)
+			if line.crashed {
+				crashed++
+			}
+		}
+	}
+	require.Len(t, frames, 5)
+	assert.Equal(t, 4, crashed)
+
+	assert.Equal(t, uint64(0x1022), frames[0].pc)
+	assert.Equal(t, "0a1b2c3d4e5f60718293a4b5c6d7e8f9", frames[0].buildID)
+	assert.Equal(t, "libnative.so", frames[0].binaryName())
+
+	assert.Equal(t, "(offset 0x4000)", frames[1].offset)
+	assert.Equal(t, "libnative.so", frames[1].binaryName())
+
+	assert.Equal(t, "", frames[3].buildID)
+	assert.Equal(t, "[anon:dalvik-jit-code-cache]", frames[3].mapping)
+
+	// an abort message takes precedence over the signal
+	tombstone = parseTombstone(`signal 6 (SIGABRT), code -1 (SI_QUEUE), fault addr --------
+Abort message: 'terminating with uncaught exception of type std::runtime_error: boom'
+    #00 pc 000000000005b3ac  /apex/com.android.runtime/lib64/bionic/libc.so (abort+164)`)
+	assert.Equal(t, "SIGABRT", tombstone.exceptionType)
+	assert.Equal(t, "terminating with uncaught exception of type std::runtime_error: boom", tombstone.exceptionMessage)
+
+	tombstone = parseTombstone(`signal 11 (SIGSEGV), code 2 (SEGV_ACCERR), fault addr 0x7b2c400000
+    #00 pc 000000000005b3ac  /system/lib64/libfoo.so`)
+	assert.Equal(t, "SEGV_ACCERR fault addr 0x7b2c400000", tombstone.exceptionMessage)
+
+	assert.True(t, isTombstone(testTombstone))
+	assert.False(t, isTombstone(testDartStackTrace))
+}
+
+func TestProcessTombstone(t *testing.T) {
+	ctx := context.Background()
+	cfg := createDefaultConfig().(*Config)
+
+	tb, attributes, cleanup := createTestTelemetry(t)
+	defer cleanup()
+
+	fs, err := newFileStore(ctx, zaptest.NewLogger(t), &LocalDebugFileConfiguration{Path: "../test_assets"})
+	require.NoError(t, err)
+	sym, err := newBasicSymbolicator(ctx, 5*time.Second, 64, fs, tb, attributes)
+	require.NoError(t, err)
+
+	processor := newSymbolicatorProcessor(ctx, cfg, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{
+			Logger: zaptest.NewLogger(t),
+		},
+	}, sym, tb, attributes)
+
+	attrs := pcommon.NewMap()
+	attrs.PutStr(cfg.StackTraceAttributeKey, testTombstone)
+
+	processor.processStackTraceAttributes(ctx, attrs, testTombstone, processor.processTombstoneThrows)
+
+	stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+Build fingerprint: 'google/oriole/oriole:14/UQ1A.240205.004/11269751:user/release-keys'
+ABI: 'arm64'
+pid: 4321, tid: 4321, name: com.example  >>> com.example <<<
+signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x0000000000000000
+Cause: null pointer dereference
+
+backtrace:
+      #00 pc 0000000000001022  /data/app/~~Xy==/com.example-Ab==/lib/arm64/libnative.so (handle_tap+2) (/src/app/native.c:6) (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)
+      #01 pc 000000000000102f  /data/app/~~Xy==/com.example-Ab==/base.apk!libnative.so (offset 0x4000) (on_event+9) (/src/app/native.c:10) (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)
+      #02 pc 00000000000b0a54  /apex/com.android.runtime/lib64/bionic/libc.so (__libc_init+96) (BuildId: 5812256023147338b8a9538321d4c456)
+      #03 pc 0000000000001d6c  [anon:dalvik-jit-code-cache]
+
+--- --- --- --- --- --- --- --- --- --- --- --- --- --- --- ---
+pid: 4321, tid: 4330, name: RenderThread  >>> com.example <<<
+backtrace:
+      #00 pc 000000000000102f  /data/app/~~Xy==/com.example-Ab==/lib/arm64/libnative.so (on_event+9) (/src/app/native.c:10) (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)`, stackTrace.Str())
+
+	failed, _ := attrs.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+
+	exceptionType, _ := attrs.Get(cfg.ExceptionTypeAttributeKey)
+	assert.Equal(t, "SIGSEGV", exceptionType.Str())
+	exceptionMessage, _ := attrs.Get(cfg.ExceptionMessageAttributeKey)
+	assert.Equal(t, "null pointer dereference", exceptionMessage.Str())
+
+	// only the crashing thread is structured
+	binaries, _ := attrs.Get(cfg.OutputStackTraceBinariesAttributeKey)
+	assert.Equal(t, []any{"libnative.so", "libnative.so", "libc.so", "[anon:dalvik-jit-code-cache]"}, binaries.Slice().AsRaw())
+	functions, _ := attrs.Get(cfg.OutputStackTraceFunctionsAttributeKey)
+	assert.Equal(t, []any{"handle_tap", "on_event", "", ""}, functions.Slice().AsRaw())
+	statuses, _ := attrs.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "symbolicated", "missing_debug_file", "missing_debug_file"}, statuses.Slice().AsRaw())
+}
+
+func TestFormatTombstoneFrameInlined(t *testing.T) {
+	frame := parseTombstoneFrame("  #00 pc 0000000000001020  /system/lib64/libnative.so (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)")
+	require.NotNil(t, frame)
+
+	locations := []*mappedNativeStackFrame{
+		{symbol: "crash", path: "/src/app/native.c", line: 2, symAddr: 0x1020},
+		{symbol: "handle_tap", path: "/src/app/native.c", line: 6, symAddr: 0x1020},
+	}
+
+	assert.Equal(t, "  #00 pc 0000000000001020  /system/lib64/libnative.so (crash) [inlined] (/src/app/native.c:2) (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)", formatTombstoneFrame(frame, 0, locations))
+	assert.Equal(t, "  #00 pc 0000000000001020  /system/lib64/libnative.so (handle_tap+0) (/src/app/native.c:6) (BuildId: 0a1b2c3d4e5f60718293a4b5c6d7e8f9)", formatTombstoneFrame(frame, 1, locations))
+}
@@ -0,0 +1,97 @@
+package nativeprocessor
+
+// maxUnwoundFrames bounds the frames unwound for a thread, in case the stack
+// is corrupt and unwinding goes around in circles.
+const maxUnwoundFrames = 256
+
+// unwoundFrame is a frame of a thread's stack, recovered from its registers and
+// the stack memory captured in the minidump.
+type unwoundFrame struct {
+	pc uint64
+	// caller is false for the innermost frame, whose pc is the instruction that
+	// was executing rather than a return address.
+	caller bool
+}
+
+// address returns the address used to unwind and symbolicate a frame. Return
+// addresses point after the call, which may be the first instruction of the
+// next line or even function, so the address of a caller frame is moved back
+// into the call instruction.
+func (f unwoundFrame) address() uint64 {
+	if f.caller && f.pc > 0 {
+		return f.pc - 1
+	}
+	return f.pc
+}
+
+// unwindThread walks the stack of a thread from its registers. Each frame is
+// unwound with the call frame information of its module if there is any, and
+// by following the frame pointer otherwise.
+func unwindThread(md *minidump, regs registers, cfi func(module *minidumpModule) *breakpadCFI) []unwoundFrame {
+	pcRegister, spRegister := md.pcRegister(), md.spRegister()
+
+	if regs[pcRegister] == 0 {
+		return nil
+	}
+
+	frames := []unwoundFrame{{pc: regs[pcRegister]}}
+	for len(frames) < maxUnwoundFrames {
+		frame := frames[len(frames)-1]
+		addr := frame.address()
+
+		var caller registers
+		if module, ok := md.module(addr); ok {
+			if rules, ok := cfi(module).rules(addr - module.base); ok {
+				caller, _ = unwindCFI(md, rules, regs)
+			}
+		}
+		if caller == nil {
+			caller = unwindFramePointer(md, regs)
+		}
+
+		// the stack grows down, so anything but a caller higher up the stack
+		// means unwinding went wrong
+		if caller == nil || caller[pcRegister] == 0 || caller[spRegister] <= regs[spRegister] {
+			break
+		}
+
+		frames = append(frames, unwoundFrame{pc: caller[pcRegister], caller: true})
+		regs = caller
+	}
+
+	return frames
+}
+
+// unwindFramePointer recovers the caller's registers from the frame record the
+// frame pointer points to, which holds the caller's frame pointer followed by
+// the return address. It returns nil if there is no frame record.
+func unwindFramePointer(md *minidump, callee registers) registers {
+	fpRegister := "x29"
+	if md.arch == minidumpCPUAMD64 {
+		fpRegister = "rbp"
+	}
+
+	fp := callee[fpRegister]
+	if fp == 0 {
+		return nil
+	}
+
+	callerFP, ok := md.readU64(fp)
+	if !ok {
+		return nil
+	}
+	returnAddress, ok := md.readU64(fp + 8)
+	if !ok {
+		return nil
+	}
+
+	caller := make(registers, len(callee))
+	for register, value := range callee {
+		caller[register] = value
+	}
+	caller[fpRegister] = callerFP
+	caller[md.pcRegister()] = returnAddress
+	caller[md.spRegister()] = fp + 16
+
+	return caller
+}
@@ -0,0 +1,230 @@
+package nativeprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+var (
+	// groups: line prefix, module name, offset, the rest of the line
+	windowsFrameRegex = regexp.MustCompile(`(?i)^(.*?)([\w.\-]+\.(?:dll|exe|sys|ocx|node|pyd))\+0x([\da-f]+)(.*)$`)
+	// pdbDebugIDRegex matches a formatted PDB debug ID, a GUID followed by an age
+	pdbDebugIDRegex = regexp.MustCompile(`^[0-9A-F]+$`)
+)
+
+// pdbModule is a Windows module loaded into a crashed process, identified by
+// the GUID and age of its PDB.
+type pdbModule struct {
+	name string
+	// debugFile is the file name of the module's PDB, eg. app.pdb.
+	debugFile string
+	// debugID is the PDB's GUID followed by its age, as symstore writes them,
+	// eg. 3D2C1B0A5F4E71608293A4B5C6D7E8F91.
+	debugID string
+}
+
+// pdbModules are the modules of a process, keyed by their lowercased name,
+// since Windows file names are case insensitive.
+type pdbModules map[string]pdbModule
+
+// pdbModuleJSON is a module as sent in the modules attribute,
+// eg. {"name": "app.exe", "debug_file": "app.pdb", "debug_id": "3D2C1B0A5F4E71608293A4B5C6D7E8F91"}.
+type pdbModuleJSON struct {
+	Name      string `json:"name"`
+	DebugFile string `json:"debug_file"`
+	DebugID   string `json:"debug_id"`
+}
+
+// parsePDBModules parses the modules attribute, a JSON array of modules. A
+// module without a debug file is assumed to have a PDB of the same name.
+func parsePDBModules(raw string) (pdbModules, error) {
+	var parsed []pdbModuleJSON
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, err
+	}
+
+	modules := make(pdbModules, len(parsed))
+	for _, module := range parsed {
+		// modules are often listed by their full path
+		name := path.Base(strings.ReplaceAll(module.Name, `\`, "/"))
+
+		debugFile := path.Base(strings.ReplaceAll(module.DebugFile, `\`, "/"))
+		if module.DebugFile == "" {
+			debugFile = strings.TrimSuffix(name, path.Ext(name)) + ".pdb"
+		}
+
+		// the debug file and ID are part of the PDB's path in the store, so
+		// modules whose IDs would reach outside of it are skipped
+		debugID := formatPDBDebugID(module.DebugID)
+		if !pdbDebugIDRegex.MatchString(debugID) || debugFile == "." || debugFile == ".." {
+			continue
+		}
+
+		modules[strings.ToLower(name)] = pdbModule{
+			name:      name,
+			debugFile: debugFile,
+			debugID:   debugID,
+		}
+	}
+
+	return modules, nil
+}
+
+// formatPDBDebugID formats a PDB's GUID and age the way symstore does, eg. turning
+// {3D2C1B0A-5F4E-7160-8293-A4B5C6D7E8F9}-1 or 3d2c1b0a-5f4e-7160-8293-a4b5c6d7e8f9-1
+// into 3D2C1B0A5F4E71608293A4B5C6D7E8F91.
+func formatPDBDebugID(debugID string) string {
+	debugID = strings.ToUpper(strings.Trim(debugID, "{}"))
+
+	parts := strings.Split(strings.ReplaceAll(debugID, "}", ""), "-")
+	if len(parts) == 6 {
+		age, err := strconv.ParseUint(parts[5], 16, 32)
+		if err == nil {
+			return strings.Join(parts[:5], "") + fmt.Sprintf("%X", age)
+		}
+	}
+
+	return strings.ReplaceAll(debugID, "-", "")
+}
+
+// pdbModules returns the modules from the modules attribute of a record,
+// falling back to its resource, or none if neither has one.
+func (sp *symbolicatorProcessor) pdbModules(attributes, resourceAttributes pcommon.Map) (pdbModules, error) {
+	value, ok := attributes.Get(sp.cfg.ModulesAttributeKey)
+	if !ok {
+		value, ok = resourceAttributes.Get(sp.cfg.ModulesAttributeKey)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	modules, err := parsePDBModules(value.Str())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s attribute: %w", sp.cfg.ModulesAttributeKey, err)
+	}
+
+	return modules, nil
+}
+
+// hasPDBModules reports whether a record or its resource has modules.
+func (sp *symbolicatorProcessor) hasPDBModules(attributes, resourceAttributes pcommon.Map) bool {
+	if _, ok := attributes.Get(sp.cfg.ModulesAttributeKey); ok {
+		return true
+	}
+	_, ok := resourceAttributes.Get(sp.cfg.ModulesAttributeKey)
+	return ok
+}
+
+// windowsFrame is a frame of a Windows stack trace, eg. app.exe+0x1014.
+type windowsFrame struct {
+	prefix string
+	module string
+	offset uint64
+	suffix string
+}
+
+// isWindowsStackTrace reports whether a stack trace contains Windows module+offset frames.
+func isWindowsStackTrace(raw string) bool {
+	for _, line := range strings.Split(raw, "\n") {
+		if windowsFrameRegex.MatchString(strings.TrimRight(line, "\r")) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWindowsFrame(line string) *windowsFrame {
+	matches := windowsFrameRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+
+	offset, err := strconv.ParseUint(matches[3], 16, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &windowsFrame{prefix: matches[1], module: matches[2], offset: offset, suffix: matches[4]}
+}
+
+// formatWindowsFrame formats a symbolicated location the way WinDbg does, with
+// the function and its source location, eg. app.exe!crash+0x4 [C:\src\app\main.cpp @ 6].
+// Every location but the last was inlined into the one after it.
+func formatWindowsFrame(frame *windowsFrame, i int, locations []*mappedNativeStackFrame) string {
+	loc := locations[i]
+
+	function := fmt.Sprintf("%s+0x%x", loc.symbol, frame.offset-loc.symAddr)
+	if i < len(locations)-1 {
+		function = fmt.Sprintf("%s [inlined]", loc.symbol)
+	}
+
+	return fmt.Sprintf("%s%s!%s [%s @ %d]%s", frame.prefix, frame.module, function, loc.path, loc.line, frame.suffix)
+}
+
+func (sp *symbolicatorProcessor) processWindowsStackTraceThrows(ctx context.Context, attributes, resourceAttributes pcommon.Map, raw string) error {
+	modules, err := sp.pdbModules(attributes, resourceAttributes)
+	if err != nil {
+		return err
+	}
+
+	structured := sp.newStructuredStackTrace(attributes)
+	lines := make([]string, 0)
+	symbolicationFailed := false
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		frame := parseWindowsFrame(line)
+		if frame == nil {
+			lines = append(lines, line)
+			continue
+		}
+
+		var locations []*mappedNativeStackFrame
+		status := frameStatusMissingDebugFile
+
+		// frames of modules that aren't listed have no PDB to look up
+		if module, ok := modules[strings.ToLower(frame.module)]; ok && module.debugID != "" {
+			locations, err = sp.lookupPDBFrame(ctx, module.debugFile, module.debugID, frame.offset, fetchErrorCache)
+			switch {
+			case err != nil:
+				status = frameStatusFailed
+				symbolicationFailed = true
+			case len(locations) > 0:
+				status = frameStatusSymbolicated
+			}
+		}
+
+		structured.appendFrame(frame.module, locations, status)
+
+		if len(locations) == 0 {
+			lines = append(lines, line)
+			continue
+		}
+
+		for i := range locations {
+			lines = append(lines, formatWindowsFrame(frame, i, locations))
+		}
+	}
+
+	if sp.cfg.PreserveStackTrace {
+		attributes.PutStr(sp.cfg.OriginalStackTraceAttributeKey, raw)
+	}
+	attributes.PutStr(sp.cfg.StackTraceAttributeKey, strings.Join(lines, "\n"))
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package nativeprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const testWindowsStackTrace = `EXCEPTION_ACCESS_VIOLATION_READ
+  #00 app.exe+0x1014
+  #01 app.exe+0x1010
+  #02 ntdll.dll+0x9e3f
+  #03 plugin.dll+0x2000`
+
+const testPDBModules = `[
+	{"name": "C:\\Program Files\\App\\app.exe", "debug_file": "app.pdb", "debug_id": "{3D2C1B0A-5F4E-7160-8293-A4B5C6D7E8F9}-1"},
+	{"name": "ntdll.dll", "debug_id": "1EB9FACB04EA273BB4BA52C8D2E2E6DB1"}
+]`
+
+func TestParsePDBModules(t *testing.T) {
+	modules, err := parsePDBModules(testPDBModules)
+	require.NoError(t, err)
+
+	assert.Equal(t, pdbModule{name: "app.exe", debugFile: "app.pdb", debugID: "3D2C1B0A5F4E71608293A4B5C6D7E8F91"}, modules["app.exe"])
+	assert.Equal(t, pdbModule{name: "ntdll.dll", debugFile: "ntdll.pdb", debugID: "1EB9FACB04EA273BB4BA52C8D2E2E6DB1"}, modules["ntdll.dll"])
+
+	assert.Equal(t, "3D2C1B0A5F4E71608293A4B5C6D7E8F9A", formatPDBDebugID("3d2c1b0a-5f4e-7160-8293-a4b5c6d7e8f9-a"))
+
+	_, err = parsePDBModules("not json")
+	assert.Error(t, err)
+}
+
+func TestParsePDBModules_InvalidDebugIDs(t *testing.T) {
+	modules, err := parsePDBModules(`[
+		{"name": "app.exe", "debug_file": "app.pdb", "debug_id": "../../../x"},
+		{"name": "evil.dll", "debug_file": "..", "debug_id": "1EB9FACB04EA273BB4BA52C8D2E2E6DB1"},
+		{"name": "empty.dll", "debug_id": ""},
+		{"name": "ntdll.dll", "debug_id": "1EB9FACB04EA273BB4BA52C8D2E2E6DB1"}
+	]`)
+	require.NoError(t, err)
+
+	// modules whose debug file or ID would escape the store's prefix are skipped
+	assert.Len(t, modules, 1)
+	assert.Contains(t, modules, "ntdll.dll")
+}
+
+func TestParseWindowsFrame(t *testing.T) {
+	frame := parseWindowsFrame("  #00 App.EXE+0x1014 (no symbols)")
+	require.NotNil(t, frame)
+	assert.Equal(t, "  #00 ", frame.prefix)
+	assert.Equal(t, "App.EXE", frame.module)
+	assert.Equal(t, uint64(0x1014), frame.offset)
+	assert.Equal(t, " (no symbols)", frame.suffix)
+
+	assert.Nil(t, parseWindowsFrame("app!crash+0x4 [C:\\src\\app\\main.cpp @ 6]"))
+
+	assert.True(t, isWindowsStackTrace(testWindowsStackTrace))
+	assert.False(t, isWindowsStackTrace(testTombstone))
+}
+
+func TestProcessWindowsStackTrace(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestMinidumpProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	// modules can be sent once on the resource
+	rl.Resource().Attributes().PutStr(cfg.ModulesAttributeKey, testPDBModules)
+	record := rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.StackTraceAttributeKey, testWindowsStackTrace)
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	attrs := record.Attributes()
+
+	stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `EXCEPTION_ACCESS_VIOLATION_READ
+  #00 app.exe!helper() [inlined] [C:\src\app\main.cpp @ 2]
+  #00 app.exe!crash()+0x4 [C:\src\app\main.cpp @ 6]
+  #01 app.exe!crash()+0x0 [C:\src\app\main.cpp @ 5]
+  #02 ntdll.dll+0x9e3f
+  #03 plugin.dll+0x2000`, stackTrace.Str())
+
+	original, _ := attrs.Get(cfg.OriginalStackTraceAttributeKey)
+	assert.Equal(t, testWindowsStackTrace, original.Str())
+
+	failed, _ := attrs.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+
+	binaries, _ := attrs.Get(cfg.OutputStackTraceBinariesAttributeKey)
+	assert.Equal(t, []any{"app.exe", "app.exe", "app.exe", "ntdll.dll", "plugin.dll"}, binaries.Slice().AsRaw())
+	functions, _ := attrs.Get(cfg.OutputStackTraceFunctionsAttributeKey)
+	assert.Equal(t, []any{"helper()", "crash()", "crash()", "", ""}, functions.Slice().AsRaw())
+	lines, _ := attrs.Get(cfg.OutputStackTraceLinesAttributeKey)
+	assert.Equal(t, []any{int64(2), int64(6), int64(5), int64(0), int64(0)}, lines.Slice().AsRaw())
+	statuses, _ := attrs.Get(cfg.OutputStackTraceFrameStatusesAttributeKey)
+	assert.Equal(t, []any{"symbolicated", "symbolicated", "symbolicated", "missing_debug_file", "missing_debug_file"}, statuses.Slice().AsRaw())
+}
+
+func TestProcessWindowsStackTraceWithoutModules(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	processor, cleanup := newTestProcessor(t, cfg)
+	defer cleanup()
+
+	logs := plog.NewLogs()
+	record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Attributes().PutStr(cfg.StackTraceAttributeKey, testWindowsStackTrace)
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	// without modules there is nothing to look PDBs up by, so it's left to other processors
+	_, ok := record.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, ok)
+}
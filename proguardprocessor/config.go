@@ -1,6 +1,9 @@
 package proguardprocessor
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Config struct {
 	// SymbolicatorFailureAttributeKey is the attribute key that will be set to
@@ -51,6 +54,14 @@ type Config struct {
 	// trace.
 	OriginalStackTraceAttributeKey string `mapstructure:"original_stack_trace_attribute_key"`
 
+	// OriginalExceptionTypeAttributeKey is the attribute key that preserves the
+	// original, obfuscated exception type.
+	OriginalExceptionTypeAttributeKey string `mapstructure:"original_exception_type_attribute_key"`
+
+	// OriginalExceptionMessageAttributeKey is the attribute key that preserves the
+	// original exception message, before the class names in it are deobfuscated.
+	OriginalExceptionMessageAttributeKey string `mapstructure:"original_exception_message_attribute_key"`
+
 	// OriginalClassesAttributeKey is the attribute key that preserves the original class
 	// names.
 	OriginalClassesAttributeKey string `mapstructure:"original_classes_attribute_key"`
@@ -67,13 +78,59 @@ type Config struct {
 	// source file names.
 	OriginalSourceFilesAttributeKey string `mapstructure:"original_source_files_attribute_key"`
 
+	// AmbiguousAttributeKey is the attribute key that contains whether each
+	// frame of the structured stack trace is one of several alternatives its
+	// obfuscated frame could map to.
+	AmbiguousAttributeKey string `mapstructure:"ambiguous_attribute_key"`
+
+	// FrameIndexesAttributeKey is the attribute key that contains the index of
+	// the obfuscated frame each frame of the structured stack trace came from.
+	FrameIndexesAttributeKey string `mapstructure:"frame_indexes_attribute_key"`
+
+	// ExpandFramesInCommon is a config option that determines whether to replace
+	// the "... N more" of a cause or suppressed exception with the frames it has
+	// in common with the exception enclosing it.
+	ExpandFramesInCommon bool `mapstructure:"expand_frames_in_common"`
+
+	// CauseTypesAttributeKey is the attribute key that the exception type of each
+	// cause and suppressed exception of a parsed stack trace is populated into.
+	CauseTypesAttributeKey string `mapstructure:"cause_types_attribute_key"`
+
+	// CauseMessagesAttributeKey is the attribute key that the exception message of
+	// each cause and suppressed exception is populated into.
+	CauseMessagesAttributeKey string `mapstructure:"cause_messages_attribute_key"`
+
+	// CauseKindsAttributeKey is the attribute key that contains whether each cause
+	// is a "Caused by" or "Suppressed" exception.
+	CauseKindsAttributeKey string `mapstructure:"cause_kinds_attribute_key"`
+
+	// CauseEnclosingAttributeKey is the attribute key that contains the index of
+	// the cause each cause belongs to, or -1 for the top level exception.
+	CauseEnclosingAttributeKey string `mapstructure:"cause_enclosing_attribute_key"`
+
 	// ProguardUUIDAttributeKey is the attribute key that contains the UUID
-	// of the proguard mapping file.
+	// of the proguard mapping file, a list of UUIDs that are tried in order, or
+	// a map of package prefixes to the UUID of the mapping for their classes.
 	// This is used to identify which proguard mapping file to use for symbolication.
 	ProguardUUIDAttributeKey string `mapstructure:"proguard_uuid_attribute_key"`
 
+	// ApplicationIDAttributeKey is the attribute key that contains the application
+	// ID of the build, used to find its mapping with the "application_version"
+	// store layout.
+	ApplicationIDAttributeKey string `mapstructure:"application_id_attribute_key"`
+
+	// VersionCodeAttributeKey is the attribute key that contains the version code
+	// of the build, used to find its mapping with the "application_version" store
+	// layout.
+	VersionCodeAttributeKey string `mapstructure:"version_code_attribute_key"`
+
 	ProguardStoreKey string `mapstructure:"proguard_store"`
 
+	// ProguardStoreLayouts are the layouts used to look up mappings in the store,
+	// tried in order: "uuid" (<uuid>.txt) or "application_version"
+	// (<applicationId>/<versionCode>/mapping.txt).
+	ProguardStoreLayouts []string `mapstructure:"proguard_store_layouts"`
+
 	// LocalProguardConfiguration is the configuration for sourcing proguard files on a local volume.
 	LocalProguardConfiguration *LocalStoreConfiguration `mapstructure:"local_store"`
 
@@ -89,6 +146,38 @@ type Config struct {
 	// CacheSize is the maximum number of proguard files to cache.
 	ProguardCacheSize int `mapstructure:"proguard_cache_size"`
 
+	// ProguardCacheSizeBytes is the maximum total size, in bytes, of the proguard
+	// files to cache, or 0 for no limit other than ProguardCacheSize. The least
+	// recently used files are evicted first.
+	ProguardCacheSizeBytes int64 `mapstructure:"proguard_cache_size_bytes"`
+
+	// ProguardMappingDirectory is a directory to keep downloaded proguard files
+	// in, so they're only downloaded once across cache misses and restarts. If
+	// empty, they're downloaded to a temp file each time they're loaded.
+	ProguardMappingDirectory string `mapstructure:"proguard_mapping_directory"`
+
+	// ProguardMappingDirectorySizeBytes is the maximum total size, in bytes, of
+	// the proguard files kept in ProguardMappingDirectory, or 0 for no limit. The
+	// least recently used files are removed first.
+	ProguardMappingDirectorySizeBytes int64 `mapstructure:"proguard_mapping_directory_size_bytes"`
+
+	// DeobfuscateClassAttributeKeys are the keys of span, span event and log
+	// attributes whose values are obfuscated class names, eg. "code.namespace".
+	DeobfuscateClassAttributeKeys []string `mapstructure:"deobfuscate_class_attribute_keys"`
+
+	// DeobfuscateMethodAttributeKeys are the keys of span, span event and log
+	// attributes whose values are obfuscated methods qualified with their class,
+	// eg. "a.b.c.d". A method that isn't qualified, such as "code.function", is
+	// looked up in the class of the first of DeobfuscateClassAttributeKeys.
+	DeobfuscateMethodAttributeKeys []string `mapstructure:"deobfuscate_method_attribute_keys"`
+
+	// DeobfuscateSpanNames deobfuscates the classes and methods in span names,
+	// eg. "a.b.c.onCreate".
+	DeobfuscateSpanNames bool `mapstructure:"deobfuscate_span_names"`
+
+	// DeobfuscateLogBodies deobfuscates the classes and methods in log bodies.
+	DeobfuscateLogBodies bool `mapstructure:"deobfuscate_log_bodies"`
+
 	// LanguageAttributeKey is the attribute key that contains the programming language
 	// or SDK language of the telemetry signal (e.g., "telemetry.sdk.language").
 	// This is used to determine if this processor should handle the signal.
@@ -122,5 +211,11 @@ type GCSStoreConfiguration struct {
 }
 
 func (c *Config) Validate() error {
-	return nil
+	if c.ProguardCacheSizeBytes < 0 {
+		return fmt.Errorf("proguard_cache_size_bytes must not be negative")
+	}
+	if c.ProguardMappingDirectorySizeBytes < 0 {
+		return fmt.Errorf("proguard_mapping_directory_size_bytes must not be negative")
+	}
+	return validateStoreLayouts(c.ProguardStoreLayouts)
 }
@@ -0,0 +1,153 @@
+package proguardprocessor
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// deobfuscatesLogs reports whether log records are deobfuscated beyond their
+// stack traces.
+func (p *proguardLogsProcessor) deobfuscatesLogs() bool {
+	return p.deobfuscatesAttributes() || p.cfg.DeobfuscateLogBodies
+}
+
+// deobfuscatesAttributes reports whether any attributes are deobfuscated.
+func (p *proguardLogsProcessor) deobfuscatesAttributes() bool {
+	return len(p.cfg.DeobfuscateClassAttributeKeys) > 0 || len(p.cfg.DeobfuscateMethodAttributeKeys) > 0
+}
+
+// deobfuscateLogRecord deobfuscates the configured attributes and body of a
+// log record with the mappings of the record.
+func (p *proguardLogsProcessor) deobfuscateLogRecord(ctx context.Context, lr plog.LogRecord, resourceAttrs pcommon.Map) {
+	mappings, err := p.mappingSet(lr.Attributes(), resourceAttrs)
+	if err != nil {
+		p.logger.Debug("Not deobfuscating log record", zap.Error(err))
+		return
+	}
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	p.deobfuscateAttributes(ctx, mappings, lr.Attributes(), fetchErrorCache)
+
+	if p.cfg.DeobfuscateLogBodies && lr.Body().Type() == pcommon.ValueTypeStr {
+		lr.Body().SetStr(p.remapReferences(ctx, mappings, lr.Body().Str(), fetchErrorCache))
+	}
+}
+
+// deobfuscateSpan deobfuscates the configured attributes of a span and its
+// events, and its name, with the mappings of the span.
+func (p *proguardLogsProcessor) deobfuscateSpan(ctx context.Context, span ptrace.Span, resourceAttrs pcommon.Map) {
+	mappings, err := p.mappingSet(span.Attributes(), resourceAttrs)
+	if err != nil {
+		p.logger.Debug("Not deobfuscating span", zap.Error(err))
+		return
+	}
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	if p.cfg.DeobfuscateSpanNames {
+		span.SetName(p.remapReferences(ctx, mappings, span.Name(), fetchErrorCache))
+	}
+
+	p.deobfuscateAttributes(ctx, mappings, span.Attributes(), fetchErrorCache)
+	for i := 0; i < span.Events().Len(); i++ {
+		p.deobfuscateAttributes(ctx, mappings, span.Events().At(i).Attributes(), fetchErrorCache)
+	}
+}
+
+// deobfuscateAttributes deobfuscates the class and method attributes of a
+// span, span event or log record. A method that isn't qualified with its
+// class, such as code.function, is in the class of the first class attribute,
+// such as code.namespace.
+func (p *proguardLogsProcessor) deobfuscateAttributes(ctx context.Context, mappings *mappingSet, attributes pcommon.Map, fetchErrorCache map[string]error) {
+	var class string
+	for _, key := range p.cfg.DeobfuscateClassAttributeKeys {
+		value, ok := attributes.Get(key)
+		if !ok || value.Type() != pcommon.ValueTypeStr || value.Str() == "" {
+			continue
+		}
+		if class == "" {
+			class = value.Str()
+		}
+		value.SetStr(p.remapClass(ctx, mappings, value.Str(), fetchErrorCache))
+	}
+
+	for _, key := range p.cfg.DeobfuscateMethodAttributeKeys {
+		value, ok := attributes.Get(key)
+		if !ok || value.Type() != pcommon.ValueTypeStr || value.Str() == "" {
+			continue
+		}
+		if strings.Contains(value.Str(), ".") {
+			value.SetStr(p.remapReference(ctx, mappings, value.Str(), fetchErrorCache))
+		} else if class != "" {
+			_, method := p.remapMethod(ctx, mappings, class, value.Str(), fetchErrorCache)
+			value.SetStr(method)
+		}
+	}
+}
+
+// remapReferences deobfuscates the classes, and methods qualified with their
+// class, in a span name or log body, eg. "a.b.c.onCreate".
+func (p *proguardLogsProcessor) remapReferences(ctx context.Context, mappings *mappingSet, text string, fetchErrorCache map[string]error) string {
+	remapped := make(map[string]string)
+
+	return classNameRegex.ReplaceAllStringFunc(text, func(reference string) string {
+		if r, ok := remapped[reference]; ok {
+			return r
+		}
+		remapped[reference] = p.remapReference(ctx, mappings, reference, fetchErrorCache)
+		return remapped[reference]
+	})
+}
+
+// remapReference deobfuscates a class, or a method qualified with its class.
+// Anything the mappings don't have is kept.
+func (p *proguardLogsProcessor) remapReference(ctx context.Context, mappings *mappingSet, reference string, fetchErrorCache map[string]error) string {
+	if remapped := p.remapClass(ctx, mappings, reference, fetchErrorCache); remapped != reference {
+		return remapped
+	}
+
+	i := strings.LastIndex(reference, ".")
+	if i < 0 {
+		return reference
+	}
+
+	class, method := reference[:i], reference[i+1:]
+	remappedClass, remappedMethod := p.remapMethod(ctx, mappings, class, method, fetchErrorCache)
+	return remappedClass + "." + remappedMethod
+}
+
+// remapMethod deobfuscates a method and its class with the first of the
+// mappings that has it. Without a line number, an obfuscated name can be
+// several methods, in which case only the class is deobfuscated.
+func (p *proguardLogsProcessor) remapMethod(ctx context.Context, mappings *mappingSet, class, method string, fetchErrorCache map[string]error) (string, string) {
+	for _, uuid := range mappings.candidates(class) {
+		if _, exists := fetchErrorCache[uuid]; exists {
+			continue
+		}
+
+		remappedClass, remappedMethod, err := p.symbolicator.remapMethod(ctx, uuid, class, method)
+		if err != nil {
+			var fetchErr *FetchError
+			if errors.As(err, &fetchErr) {
+				fetchErrorCache[uuid] = err
+			}
+			p.logger.Debug("Failed to remap method", zap.String("class", class), zap.String("method", method), zap.Error(err))
+			continue
+		}
+
+		if remappedClass != "" {
+			return remappedClass, remappedMethod
+		}
+	}
+
+	return p.remapClass(ctx, mappings, class, fetchErrorCache), method
+}
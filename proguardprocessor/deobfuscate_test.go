@@ -0,0 +1,148 @@
+package proguardprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/opentelemetry-collector-symbolicator/proguardprocessor/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.uber.org/zap/zaptest"
+)
+
+const deobfuscateTestMapping = `com.example.MainActivity -> a.b.c:
+    1:3:void onCreate(android.os.Bundle):20:22 -> onCreate
+    1:5:void loadFeed():10:14 -> d
+    6:8:void loadFeed():16:18 -> d
+    1:2:void start():30:31 -> e
+    3:4:void stop(int):40:41 -> e
+com.example.FeedRepository -> a.b.d:
+`
+
+func newDeobfuscateTestProcessor(t *testing.T, configure func(cfg *Config)) *proguardLogsProcessor {
+	ctx := context.Background()
+	tb, attributes := createMockTelemetry(t)
+	store := &mockLogProcessorStore{mapping: map[string][]byte{"test-uuid": []byte(deobfuscateTestMapping)}}
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, store, tb, attributes)
+	require.NoError(t, err)
+
+	cfg := createDefaultConfig().(*Config)
+	configure(cfg)
+	processor, err := newProguardLogsProcessor(ctx, cfg, store, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+	}, symbolicator, tb, attributes)
+	require.NoError(t, err)
+	return processor
+}
+
+func TestProcessTraces_Deobfuscate(t *testing.T) {
+	p := newDeobfuscateTestProcessor(t, func(cfg *Config) {
+		cfg.DeobfuscateSpanNames = true
+		cfg.DeobfuscateClassAttributeKeys = []string{"code.namespace"}
+		cfg.DeobfuscateMethodAttributeKeys = []string{"code.function", "code.function.name"}
+	})
+
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("app.debug.proguard_uuid", "test-uuid")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+
+	span := spans.AppendEmpty()
+	span.SetName("a.b.c.onCreate")
+	span.Attributes().PutStr("code.namespace", "a.b.c")
+	span.Attributes().PutStr("code.function", "d")
+	event := span.Events().AppendEmpty()
+	event.Attributes().PutStr("code.function.name", "a.b.c.d")
+
+	ambiguous := spans.AppendEmpty()
+	ambiguous.SetName("load a.b.d with a.b.c.e")
+	ambiguous.Attributes().PutStr("code.function.name", "a.b.c.e")
+
+	_, err := p.ProcessTraces(context.Background(), td)
+	require.NoError(t, err)
+
+	assert.Equal(t, "com.example.MainActivity.onCreate", span.Name())
+	namespace, _ := span.Attributes().Get("code.namespace")
+	assert.Equal(t, "com.example.MainActivity", namespace.Str())
+	function, _ := span.Attributes().Get("code.function")
+	assert.Equal(t, "loadFeed", function.Str())
+	functionName, _ := event.Attributes().Get("code.function.name")
+	assert.Equal(t, "com.example.MainActivity.loadFeed", functionName.Str())
+
+	// e is both start and stop, so only its class is deobfuscated
+	assert.Equal(t, "load com.example.FeedRepository with com.example.MainActivity.e", ambiguous.Name())
+	functionName, _ = ambiguous.Attributes().Get("code.function.name")
+	assert.Equal(t, "com.example.MainActivity.e", functionName.Str())
+}
+
+func TestProcessTraces_NotConfigured(t *testing.T) {
+	p := newDeobfuscateTestProcessor(t, func(cfg *Config) {})
+
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("app.debug.proguard_uuid", "test-uuid")
+	span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("a.b.c.onCreate")
+
+	_, err := p.ProcessTraces(context.Background(), td)
+	require.NoError(t, err)
+	assert.Equal(t, "a.b.c.onCreate", span.Name())
+}
+
+func TestProcessLogRecord_Deobfuscate(t *testing.T) {
+	p := newDeobfuscateTestProcessor(t, func(cfg *Config) {
+		cfg.DeobfuscateLogBodies = true
+		cfg.DeobfuscateClassAttributeKeys = []string{"code.namespace"}
+	})
+
+	lr := plog.NewLogRecord()
+	lr.Body().SetStr("Loaded feed in a.b.c.d from a.b.d, version 1.2.3")
+	lr.Attributes().PutStr("code.namespace", "a.b.d")
+	resourceAttrs := pcommon.NewMap()
+	resourceAttrs.PutStr("app.debug.proguard_uuid", "test-uuid")
+
+	p.processLogRecord(context.Background(), lr, resourceAttrs)
+
+	assert.Equal(t, "Loaded feed in com.example.MainActivity.loadFeed from com.example.FeedRepository, version 1.2.3", lr.Body().Str())
+	namespace, _ := lr.Attributes().Get("code.namespace")
+	assert.Equal(t, "com.example.FeedRepository", namespace.Str())
+
+	// a record without a stack trace isn't marked as failed to symbolicate
+	_, hasFailure := lr.Attributes().Get("exception.symbolicator.failed")
+	assert.False(t, hasFailure)
+}
+
+func TestNewFactory_SharesProcessor(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	set := processortest.NewNopSettings(metadata.Type)
+
+	logs, err := factory.CreateLogs(ctx, set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	traces, err := factory.CreateTraces(ctx, set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+
+	// the logs and traces pipelines of a configuration share its processor
+	require.Len(t, processors, 1)
+	assert.Equal(t, 2, processors[cfg.(*Config)].refs)
+
+	// a different configuration has its own processor
+	other, err := factory.CreateLogs(ctx, set, factory.CreateDefaultConfig(), consumertest.NewNop())
+	require.NoError(t, err)
+	assert.Len(t, processors, 2)
+
+	require.NoError(t, logs.Shutdown(ctx))
+	assert.Equal(t, 1, processors[cfg.(*Config)].refs)
+	require.NoError(t, traces.Shutdown(ctx))
+	require.NoError(t, other.Shutdown(ctx))
+	assert.Empty(t, processors)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/honeycombio/opentelemetry-collector-symbolicator/proguardprocessor/internal/metadata"
@@ -23,6 +24,20 @@ const (
 	processorVersion = "1.0.1"
 )
 
+// processors are the processors shared by the logs and traces pipelines of
+// each configuration, so they share one mapping cache.
+var (
+	processorsMu sync.Mutex
+	processors   = map[*Config]*sharedProcessor{}
+)
+
+// sharedProcessor is a processor used by one or more pipelines, which is
+// forgotten when the last of them shuts down.
+type sharedProcessor struct {
+	processor *proguardLogsProcessor
+	refs      int
+}
+
 func createDefaultConfig() component.Config {
 	return &Config{
 		SymbolicatorFailureAttributeKey:       "exception.symbolicator.failed",
@@ -37,29 +52,75 @@ func createDefaultConfig() component.Config {
 		ExceptionMessageAttributeKey:          "exception.message",
 		PreserveStackTrace:                    true,
 		OriginalStackTraceAttributeKey:        "exception.stacktrace.original",
+		OriginalExceptionTypeAttributeKey:     "exception.type.original",
+		OriginalExceptionMessageAttributeKey:  "exception.message.original",
 		OriginalClassesAttributeKey:           "exception.structured_stacktrace.classes.original",
 		OriginalMethodsAttributeKey:           "exception.structured_stacktrace.methods.original",
 		OriginalLinesAttributeKey:             "exception.structured_stacktrace.lines.original",
 		OriginalSourceFilesAttributeKey:       "exception.structured_stacktrace.source_files.original",
+		AmbiguousAttributeKey:                 "exception.structured_stacktrace.ambiguous",
+		FrameIndexesAttributeKey:              "exception.structured_stacktrace.frame_indexes",
+		CauseTypesAttributeKey:                "exception.causes.types",
+		CauseMessagesAttributeKey:             "exception.causes.messages",
+		CauseKindsAttributeKey:                "exception.causes.kinds",
+		CauseEnclosingAttributeKey:            "exception.causes.enclosing",
 		ProguardUUIDAttributeKey:              "app.debug.proguard_uuid",
+		ApplicationIDAttributeKey:             "service.name",
+		VersionCodeAttributeKey:               "service.version",
 		ProguardStoreKey:                      "file_store",
+		ProguardStoreLayouts:                  []string{storeLayoutUUID},
 		LocalProguardConfiguration: &LocalStoreConfiguration{
 			Path: ".",
 		},
-		Timeout:              5 * time.Second,
-		ProguardCacheSize:    128,
-		LanguageAttributeKey: "telemetry.sdk.language",
-		AllowedLanguages:     []string{}, // Empty by default, processes all signals
+		Timeout:                           5 * time.Second,
+		ProguardCacheSize:                 128,
+		ProguardMappingDirectorySizeBytes: 10 << 30,
+		LanguageAttributeKey:              "telemetry.sdk.language",
+		AllowedLanguages:                  []string{}, // Empty by default, processes all signals
 	}
 }
 
-func createLogsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Logs) (processor.Logs, error) {
+// acquireProguardProcessor returns the processor of a configuration, creating
+// it for the first pipeline that uses it. Each pipeline releases it with the
+// returned function when it shuts down.
+func acquireProguardProcessor(ctx context.Context, set processor.Settings, cfg component.Config) (*proguardLogsProcessor, func(context.Context) error, error) {
 	symCfg, ok := cfg.(*Config)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: expected Config type, got %T", ErrorInvalidConfig, cfg)
+	}
+
+	processorsMu.Lock()
+	defer processorsMu.Unlock()
 
+	shared, ok := processors[symCfg]
 	if !ok {
-		return nil, fmt.Errorf("%w: expected Config type, got %T", ErrorInvalidConfig, cfg)
+		p, err := createProguardProcessor(ctx, set, symCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		shared = &sharedProcessor{processor: p}
+		processors[symCfg] = shared
+	}
+	shared.refs++
+
+	var once sync.Once
+	release := func(context.Context) error {
+		once.Do(func() {
+			processorsMu.Lock()
+			defer processorsMu.Unlock()
+
+			shared.refs--
+			if shared.refs == 0 {
+				delete(processors, symCfg)
+			}
+		})
+		return nil
 	}
+	return shared.processor, release, nil
+}
 
+// createProguardProcessor creates the processor of a configuration.
+func createProguardProcessor(ctx context.Context, set processor.Settings, symCfg *Config) (*proguardLogsProcessor, error) {
 	var store fileStore
 	var err error
 
@@ -86,14 +147,46 @@ func createLogsProcessor(ctx context.Context, set processor.Settings, cfg compon
 	if err != nil {
 		return nil, err
 	}
+	symbolicator.maxCacheBytes = symCfg.ProguardCacheSizeBytes
+	symbolicator.mappingDirectory = symCfg.ProguardMappingDirectory
+	symbolicator.maxDirectoryBytes = symCfg.ProguardMappingDirectorySizeBytes
 
-	processor, err := newProguardLogsProcessor(ctx, symCfg, store, set, symbolicator, tb, attributeSet)
+	return newProguardLogsProcessor(ctx, symCfg, store, set, symbolicator, tb, attributeSet)
+}
 
+// createLogsProcessor creates a processor that accepts logs.
+func createLogsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Logs) (processor.Logs, error) {
+	processor, release, err := acquireProguardProcessor(ctx, set, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return processorhelper.NewLogs(ctx, set, cfg, next, processor.ProcessLogs, processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+	p, err := processorhelper.NewLogs(ctx, set, cfg, next, processor.ProcessLogs,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithShutdown(release))
+	if err != nil {
+		_ = release(ctx)
+		return nil, err
+	}
+	return p, nil
+}
+
+// createTracesProcessor creates a processor that accepts traces, to deobfuscate
+// span names and attributes.
+func createTracesProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Traces) (processor.Traces, error) {
+	processor, release, err := acquireProguardProcessor(ctx, set, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := processorhelper.NewTraces(ctx, set, cfg, next, processor.ProcessTraces,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithShutdown(release))
+	if err != nil {
+		_ = release(ctx)
+		return nil, err
+	}
+	return p, nil
 }
 
 func setUpResourceAttributes() attribute.Set {
@@ -115,5 +208,6 @@ func NewFactory() processor.Factory {
 		typeStr,
 		createDefaultConfig,
 		processor.WithLogs(createLogsProcessor, component.StabilityLevelAlpha),
+		processor.WithTraces(createTracesProcessor, metadata.TracesStability),
 	)
 }
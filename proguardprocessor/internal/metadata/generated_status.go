@@ -12,5 +12,6 @@ var (
 )
 
 const (
-	LogsStability = component.StabilityLevelStable
+	LogsStability   = component.StabilityLevelStable
+	TracesStability = component.StabilityLevelAlpha
 )
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"regexp"
 	"strings"
 	"time"
 
@@ -23,9 +24,42 @@ var (
 	errPartialSymbolication = errors.New("symbolication failed for some stack frames")
 )
 
+// classNameRegex matches fully qualified class names in exception messages,
+// eg. a.b.c and com.example.Outer$Inner in "a.b.c cannot be cast to com.example.Outer$Inner".
+var classNameRegex = regexp.MustCompile(`[A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)+`)
+
 // symbolicator interface is used to symbolicate stack traces.
 type symbolicator interface {
 	symbolicate(ctx context.Context, uuid, class, method string, line int) ([]*mappedStackFrame, error)
+	remapClass(ctx context.Context, uuid, class string) (string, error)
+	remapMethod(ctx context.Context, uuid, class, method string) (string, string, error)
+	r8Mapping(ctx context.Context, uuid string) (*r8Mapping, error)
+}
+
+// retraceState is the state of retracing the frames of one exception, innermost
+// first, which R8's mapping information applies across.
+type retraceState struct {
+	// r8 is the R8 mapping information of each mapping, by UUID.
+	r8 map[string]*r8Mapping
+	// exceptionTypes are the obfuscated and deobfuscated types of the exception.
+	exceptionTypes []string
+	// throwing is true until the frame that threw the exception is retraced.
+	throwing bool
+	// outlinePosition is the line of an outline frame, which is retraced at
+	// the callsite that follows it with the outline's mapping.
+	outlinePosition int
+	outlineUUID     string
+}
+
+func newRetraceState(r8 map[string]*r8Mapping, exceptionTypes ...string) *retraceState {
+	return &retraceState{r8: r8, exceptionTypes: exceptionTypes, throwing: true}
+}
+
+// coroutineBoundary resets the state at a coroutine boundary, since the frames
+// after it are from the coroutine that resumed or created the one before it.
+func (r *retraceState) coroutineBoundary() {
+	r.throwing = false
+	r.outlinePosition = 0
 }
 
 type proguardLogsProcessor struct {
@@ -66,28 +100,23 @@ func (p *proguardLogsProcessor) processScopeLogs(ctx context.Context, sl plog.Sc
 func (p *proguardLogsProcessor) processLogRecord(ctx context.Context, lr plog.LogRecord, resourceAttrs pcommon.Map) {
 	attributes := lr.Attributes()
 
-	// Skip all processing if StackTraceAttributeKey is not present
-	if _, ok := attributes.Get(p.cfg.StackTraceAttributeKey); !ok {
+	// Skip all processing if StackTraceAttributeKey is not present, unless
+	// other parts of the record are deobfuscated
+	_, hasStackTrace := attributes.Get(p.cfg.StackTraceAttributeKey)
+	if !hasStackTrace && !p.deobfuscatesLogs() {
 		return
 	}
 
-	// Check language filtering if configured
-	if len(p.cfg.AllowedLanguages) > 0 {
-		// Get language attribute from log attributes or resource attributes
-		languageValue, ok := attributes.Get(p.cfg.LanguageAttributeKey)
-		if !ok {
-			languageValue, ok = resourceAttrs.Get(p.cfg.LanguageAttributeKey)
-		}
+	if !p.languageAllowed(attributes, resourceAttrs) {
+		return
+	}
 
-		// If language attribute exists, check if it matches allowed languages
-		if ok {
-			language := languageValue.Str()
-			if !isLanguageAllowed(language, p.cfg.AllowedLanguages) {
-				return
-			}
-		} else { // Language attribute not found, skip processing
-			return
-		}
+	if p.deobfuscatesLogs() {
+		p.deobfuscateLogRecord(ctx, lr, resourceAttrs)
+	}
+
+	if !hasStackTrace {
+		return
 	}
 
 	// Start timing symbolication only when we actually perform it
@@ -112,15 +141,9 @@ func (p *proguardLogsProcessor) processLogRecord(ctx context.Context, lr plog.Lo
 }
 
 func (p *proguardLogsProcessor) processLogRecordThrow(ctx context.Context, attributes pcommon.Map, resourceAttrs pcommon.Map) error {
-	var ok bool
-
-	// Support retrieving the Proguard UUID from either resource or log attributes, for now.
-	uuidValue, ok := attributes.Get(p.cfg.ProguardUUIDAttributeKey)
-	if !ok {
-		uuidValue, ok = resourceAttrs.Get(p.cfg.ProguardUUIDAttributeKey)
-		if !ok {
-			return fmt.Errorf("%w: %s", errMissingAttribute, p.cfg.ProguardUUIDAttributeKey)
-		}
+	mappings, err := p.mappingSet(attributes, resourceAttrs)
+	if err != nil {
+		return err
 	}
 
 	var classes, methods, lines, sourceFiles pcommon.Slice
@@ -138,7 +161,6 @@ func (p *proguardLogsProcessor) processLogRecordThrow(ctx context.Context, attri
 
 	// If any of the structured attributes are missing, attempt to parse the raw stack trace
 	var parsedStackTrace *stackTrace
-	var err error
 	if !hasClasses || !hasMethods || !hasLines || !hasSourceFiles {
 		if !hasRawStackTrace {
 			return fmt.Errorf("%w: missing structured stack trace attributes and %s attribute is missing",
@@ -147,6 +169,11 @@ func (p *proguardLogsProcessor) processLogRecordThrow(ctx context.Context, attri
 			)
 		}
 
+		// Thread dumps, such as ANR traces, have threads instead of an exception
+		if isThreadDump(rawStackTrace.Str()) {
+			return p.processThreadDump(ctx, attributes, mappings, rawStackTrace.Str())
+		}
+
 		parsedStackTrace, err = parseStackTrace(rawStackTrace.Str())
 		if err != nil {
 			return fmt.Errorf("failed to parse raw stack trace from %s: %w", p.cfg.StackTraceAttributeKey, err)
@@ -163,35 +190,52 @@ func (p *proguardLogsProcessor) processLogRecordThrow(ctx context.Context, attri
 		attributes.PutStr(p.cfg.SymbolicatorParsingMethodAttributeKey, "structured_stacktrace_attributes")
 	}
 
-	uuid := uuidValue.Str()
-
 	var stack []string
 	var symbolicationFailed bool
 
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+
+	// Deobfuscate the exception type, and any class names in the message
+	var obfuscatedExceptionType, deobfuscatedExceptionType string
+	if hasExceptionType {
+		original := exceptionType.Str()
+		obfuscatedExceptionType = original
+		if p.cfg.PreserveStackTrace {
+			attributes.PutStr(p.cfg.OriginalExceptionTypeAttributeKey, original)
+		}
+		attributes.PutStr(p.cfg.ExceptionTypeAttributeKey, p.remapClass(ctx, mappings, original, fetchErrorCache))
+		exceptionType, _ = attributes.Get(p.cfg.ExceptionTypeAttributeKey)
+		deobfuscatedExceptionType = exceptionType.Str()
+	}
+	if hasExceptionMessage {
+		original := exceptionMessage.Str()
+		if p.cfg.PreserveStackTrace {
+			attributes.PutStr(p.cfg.OriginalExceptionMessageAttributeKey, original)
+		}
+		attributes.PutStr(p.cfg.ExceptionMessageAttributeKey, p.remapClassNames(ctx, mappings, original, fetchErrorCache))
+		exceptionMessage, _ = attributes.Get(p.cfg.ExceptionMessageAttributeKey)
+	}
+
 	// Reconstruct the stack trace with symbolicated frames
 	if hasExceptionType && hasExceptionMessage {
 		stack = append(stack, fmt.Sprintf("%s: %s", exceptionType.Str(), exceptionMessage.Str()))
 	}
 
-	// Cache FetchErrors to avoid redundant fetches for missing resources.
-	fetchErrorCache := make(map[string]error)
-
-	// Set up iteration and output slices based on route
-	var mappedClasses, mappedMethods, mappedLines pcommon.Slice
-	var iterCount int
+	// Set up output slices based on route
+	var mappedClasses, mappedMethods, mappedLines, mappedAmbiguous, mappedFrameIndexes pcommon.Slice
 
-	// Set up iteration based on whether we have a parsed stack trace or structured attributes
+	// Preserve the originals based on whether we have a parsed stack trace or structured attributes
 	if parsedStackTrace != nil {
-		iterCount = len(parsedStackTrace.elements)
-
 		if p.cfg.PreserveStackTrace {
 			attributes.PutStr(p.cfg.OriginalStackTraceAttributeKey, rawStackTrace.Str())
 		}
 	} else {
-		iterCount = classes.Len()
 		mappedClasses = attributes.PutEmptySlice(p.cfg.ClassesAttributeKey)
 		mappedMethods = attributes.PutEmptySlice(p.cfg.MethodsAttributeKey)
 		mappedLines = attributes.PutEmptySlice(p.cfg.LinesAttributeKey)
+		mappedAmbiguous = attributes.PutEmptySlice(p.cfg.AmbiguousAttributeKey)
+		mappedFrameIndexes = attributes.PutEmptySlice(p.cfg.FrameIndexesAttributeKey)
 
 		// Ensure all slices are the same length
 		if classes.Len() != methods.Len() || classes.Len() != lines.Len() || classes.Len() != sourceFiles.Len() {
@@ -212,104 +256,486 @@ func (p *proguardLogsProcessor) processLogRecordThrow(ctx context.Context, attri
 		}
 	}
 
-	for i := 0; i < iterCount; i++ {
-		var class, method, sourceFile string
-		var line int64
+	r8 := p.r8Mappings(ctx, mappings, fetchErrorCache)
 
-		// Get frame data based on route
-		if parsedStackTrace != nil {
-			element := parsedStackTrace.elements[i]
-			// Preserve raw lines that couldn't be parsed as frames
-			if element.line != "" {
-				stack = append(stack, element.line)
-				continue
+	if parsedStackTrace != nil {
+		if p.cfg.ExpandFramesInCommon {
+			parsedStackTrace.expandFramesInCommon()
+		}
+
+		retrace := newRetraceState(r8, obfuscatedExceptionType, deobfuscatedExceptionType)
+		stack, symbolicationFailed = p.appendElements(ctx, stack, mappings, "", parsedStackTrace.elements, retrace, fetchErrorCache)
+
+		for _, c := range parsedStackTrace.causes {
+			var failed bool
+
+			obfuscatedCauseType := c.exceptionType
+			c.exceptionType = p.remapClass(ctx, mappings, c.exceptionType, fetchErrorCache)
+			c.exceptionMessage = p.remapClassNames(ctx, mappings, c.exceptionMessage, fetchErrorCache)
+			stack = append(stack, formatCauseHeader(c))
+
+			retrace := newRetraceState(r8, obfuscatedCauseType, c.exceptionType)
+			stack, failed = p.appendElements(ctx, stack, mappings, c.indent, c.elements, retrace, fetchErrorCache)
+			symbolicationFailed = symbolicationFailed || failed
+
+			if c.framesInCommon > 0 {
+				stack = append(stack, fmt.Sprintf("%s\t... %d more", c.indent, c.framesInCommon))
 			}
-			// Extract from parsed frame
-			class = element.frame.class
-			method = element.frame.method
-			line = int64(element.frame.line)
-			sourceFile = element.frame.sourceFile
-		} else {
+		}
+
+		if len(parsedStackTrace.causes) > 0 {
+			p.putCauses(attributes, parsedStackTrace.causes)
+		}
+	} else {
+		retrace := newRetraceState(r8, obfuscatedExceptionType, deobfuscatedExceptionType)
+		for i := 0; i < classes.Len(); i++ {
 			// Extract from structured attributes
-			class = classes.At(i).Str()
-			method = methods.At(i).Str()
-			line = lines.At(i).Int()
-			sourceFile = sourceFiles.At(i).Str()
+			class := classes.At(i).Str()
+			method := methods.At(i).Str()
+			line := lines.At(i).Int()
+			sourceFile := sourceFiles.At(i).Str()
+
+			frameLines, mappedFrames, ok := p.symbolicateFrame(ctx, mappings, "\t", class, method, sourceFile, line, retrace, fetchErrorCache)
+			stack = append(stack, frameLines...)
+			if !ok {
+				symbolicationFailed = true
+				continue
+			}
+
+			for _, mappedFrame := range mappedFrames {
+				mappedClasses.AppendEmpty().SetStr(mappedFrame.ClassName)
+				mappedMethods.AppendEmpty().SetStr(mappedFrame.MethodName)
+				mappedLines.AppendEmpty().SetInt(mappedFrame.LineNumber)
+				mappedAmbiguous.AppendEmpty().SetBool(mappedFrame.Ambiguous)
+				mappedFrameIndexes.AppendEmpty().SetInt(int64(i))
+			}
 		}
+	}
 
-		// Line numbers set to -2 and -1 are special values indicating a native method and unknown source respectively, per the Android docs.
-		if line < -2 || line > math.MaxUint32 {
-			stack = append(stack, fmt.Sprintf("\tInvalid line number %d for %s.%s", line, class, method))
-			symbolicationFailed = true
+	attributes.PutStr(p.cfg.StackTraceAttributeKey, strings.Join(stack, "\n"))
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	} else {
+		return nil
+	}
+}
+
+// processThreadDump deobfuscates the frames and locks of every thread of a
+// thread dump, and writes the main thread's frames to the structured stack
+// trace attributes.
+func (p *proguardLogsProcessor) processThreadDump(ctx context.Context, attributes pcommon.Map, mappings *mappingSet, raw string) error {
+	dump := parseThreadDump(raw)
+
+	attributes.PutStr(p.cfg.SymbolicatorParsingMethodAttributeKey, "processor_parsed_thread_dump")
+	if p.cfg.PreserveStackTrace {
+		attributes.PutStr(p.cfg.OriginalStackTraceAttributeKey, raw)
+	}
+
+	// Cache FetchErrors to avoid redundant fetches for missing resources.
+	fetchErrorCache := make(map[string]error)
+	r8 := p.r8Mappings(ctx, mappings, fetchErrorCache)
+
+	stack := append(make([]string, 0), dump.preamble...)
+	symbolicationFailed := false
+
+	for _, t := range dump.threads {
+		stack = append(stack, t.header)
+
+		var mappedClasses, mappedMethods, mappedLines, mappedAmbiguous, mappedFrameIndexes pcommon.Slice
+		isMain := t.name == mainThreadName
+		if isMain {
+			mappedClasses = attributes.PutEmptySlice(p.cfg.ClassesAttributeKey)
+			mappedMethods = attributes.PutEmptySlice(p.cfg.MethodsAttributeKey)
+			mappedLines = attributes.PutEmptySlice(p.cfg.LinesAttributeKey)
+			mappedAmbiguous = attributes.PutEmptySlice(p.cfg.AmbiguousAttributeKey)
+			mappedFrameIndexes = attributes.PutEmptySlice(p.cfg.FrameIndexesAttributeKey)
+		}
+
+		// A thread isn't throwing an exception, so no frames are rewritten
+		retrace := &retraceState{r8: r8}
+		frameIndex := 0
+		for _, e := range t.elements {
+			switch {
+			case e.frame != nil:
+				frame := e.frame
+				frameLines, mappedFrames, ok := p.symbolicateFrame(ctx, mappings, frame.indent, frame.class, frame.method, frame.sourceFile, int64(frame.line), retrace, fetchErrorCache)
+				stack = append(stack, frameLines...)
+				if !ok {
+					symbolicationFailed = true
+				}
+
+				if isMain {
+					for _, mappedFrame := range mappedFrames {
+						mappedClasses.AppendEmpty().SetStr(mappedFrame.ClassName)
+						mappedMethods.AppendEmpty().SetStr(mappedFrame.MethodName)
+						mappedLines.AppendEmpty().SetInt(mappedFrame.LineNumber)
+						mappedAmbiguous.AppendEmpty().SetBool(mappedFrame.Ambiguous)
+						mappedFrameIndexes.AppendEmpty().SetInt(int64(frameIndex))
+					}
+				}
+				frameIndex++
+			case e.lock != nil:
+				stack = append(stack, e.lock.prefix+p.remapClass(ctx, mappings, e.lock.class, fetchErrorCache)+e.lock.suffix)
+			default:
+				stack = append(stack, e.line)
+			}
+		}
+	}
+
+	attributes.PutStr(p.cfg.StackTraceAttributeKey, strings.Join(stack, "\n"))
+
+	if symbolicationFailed {
+		return errPartialSymbolication
+	}
+	return nil
+}
+
+// appendElements appends the symbolicated frames of an exception to the stack,
+// preserving lines that couldn't be parsed as frames. Frames are indented one
+// tab further than the exception's header. It reports whether any frame could
+// not be symbolicated.
+func (p *proguardLogsProcessor) appendElements(ctx context.Context, stack []string, mappings *mappingSet, indent string, elements []element, retrace *retraceState, fetchErrorCache map[string]error) ([]string, bool) {
+	symbolicationFailed := false
+
+	for _, element := range elements {
+		// Preserve raw lines that couldn't be parsed as frames
+		if element.frame == nil {
+			if element.coroutineBoundary {
+				retrace.coroutineBoundary()
+			}
+			stack = append(stack, element.line)
 			continue
 		}
 
-		p.telemetryBuilder.ProcessorTotalProcessedFrames.Add(ctx, 1, p.attributes)
+		frame := element.frame
+		frameLines, _, ok := p.symbolicateFrame(ctx, mappings, indent+"\t", frame.class, frame.method, frame.sourceFile, int64(frame.line), retrace, fetchErrorCache)
+		stack = append(stack, frameLines...)
+		if !ok {
+			symbolicationFailed = true
+		}
+	}
+
+	return stack, symbolicationFailed
+}
 
-		var mappedFrames []*mappedStackFrame
-		var err error
+// symbolicateFrame symbolicates a single frame, returning its lines in the
+// rebuilt stack trace, each starting with prefix, and the frames it maps to,
+// which is the frame itself if it needs no mapping. It returns false if the
+// frame could not be symbolicated.
+//
+// Frames are retraced the way R8's retrace tool does: outline frames are
+// retraced at their callsite, frames the compiler synthesized are removed, and
+// the frame that threw the exception can have inlined frames removed.
+func (p *proguardLogsProcessor) symbolicateFrame(ctx context.Context, mappings *mappingSet, prefix, class, method, sourceFile string, line int64, retrace *retraceState, fetchErrorCache map[string]error) ([]string, []*mappedStackFrame, bool) {
+	// Line numbers set to -2 and -1 are special values indicating a native method and unknown source respectively, per the Android docs.
+	if line < -2 || line > math.MaxUint32 {
+		return []string{fmt.Sprintf("%sInvalid line number %d for %s.%s", prefix, line, class, method)}, nil, false
+	}
 
+	// The frames marking coroutine boundaries aren't obfuscated
+	if isCoroutineBoundary(class) {
+		retrace.coroutineBoundary()
+		original := []*mappedStackFrame{{ClassName: class, MethodName: method, LineNumber: line, SourceFile: sourceFile}}
+		return []string{fmt.Sprintf("%sat %s.%s(%s:%d)", prefix, class, method, sourceFile, line)}, original, true
+	}
+
+	p.telemetryBuilder.ProcessorTotalProcessedFrames.Add(ctx, 1, p.attributes)
+
+	uuids := mappings.candidates(class)
+	for _, uuid := range uuids {
+		if retrace.r8[uuid].isOutline(class, method) {
+			retrace.outlinePosition, retrace.outlineUUID = int(line), uuid
+			return nil, nil, true
+		}
+	}
+	if retrace.outlinePosition > 0 {
+		if callsiteLine, ok := retrace.r8[retrace.outlineUUID].outlineCallsitePosition(class, method, int(line), retrace.outlinePosition); ok {
+			line = int64(callsiteLine)
+			uuids = []string{retrace.outlineUUID}
+		}
+		retrace.outlinePosition = 0
+	}
+	throwing := retrace.throwing
+	retrace.throwing = false
+
+	uuid, mappedFrames, err := p.symbolicateWith(ctx, uuids, class, method, int(line), fetchErrorCache)
+	if err != nil {
+		p.telemetryBuilder.ProcessorTotalFailedFrames.Add(ctx, 1, p.attributes)
+		return []string{fmt.Sprintf("%sFailed to symbolicate %s.%s(%d): %v", prefix, class, method, line, err)}, nil, false
+	}
+
+	// Not a symbolication failure but no mapping found or needed; use original stacktrace data,
+	// with the class deobfuscated if the mapping has it but not the method, eg. the
+	// invokeSuspend of a suspend lambda
+	if len(mappedFrames) == 0 {
+		class = p.remapNestedClass(ctx, mappings, class, fetchErrorCache)
+		original := []*mappedStackFrame{{ClassName: class, MethodName: method, LineNumber: line, SourceFile: sourceFile}}
+
+		if line == -2 {
+			// Native method, source file and line number are not applicable
+			return []string{fmt.Sprintf("%sat %s.%s(Native Method)", prefix, class, method)}, original, true
+		} else if line == -1 {
+			// Unknown source file and line number
+			return []string{fmt.Sprintf("%sat %s.%s(Unknown Source)", prefix, class, method)}, original, true
+		}
+		return []string{fmt.Sprintf("%sat %s.%s(%s:%d)", prefix, class, method, sourceFile, line)}, original, true
+	}
+
+	alternatives := make([][]*mappedStackFrame, 0, 1)
+	for _, alternative := range splitAlternatives(mappedFrames, retrace.r8[uuid].alternatives(class, method, int(line))) {
+		if throwing {
+			if n := retrace.r8[uuid].innerFramesToRemove(class, method, int(line), retrace.exceptionTypes...); n > 0 {
+				alternative = alternative[min(n, len(alternative)):]
+			}
+		}
+
+		retraced := make([]*mappedStackFrame, 0, len(alternative))
+		for _, mappedFrame := range alternative {
+			if retrace.r8[uuid].isSynthesized(mappedFrame.ClassName, mappedFrame.MethodName) {
+				continue
+			}
+			if sourceFile, ok := retrace.r8[uuid].sourceFile(mappedFrame.ClassName); ok {
+				mappedFrame.SourceFile = sourceFile
+			}
+			retraced = append(retraced, mappedFrame)
+		}
+		if len(retraced) > 0 {
+			alternatives = append(alternatives, retraced)
+		}
+	}
+
+	// Alternatives are marked with <OR> the way retrace does, so they can be
+	// told apart from inlined frames
+	frameLines := make([]string, 0, len(mappedFrames))
+	retraced := make([]*mappedStackFrame, 0, len(mappedFrames))
+	for i, alternative := range alternatives {
+		for j, mappedFrame := range alternative {
+			mappedFrame.Ambiguous = len(alternatives) > 1
+
+			at := "at"
+			if i > 0 && j == 0 {
+				at = "<OR> at"
+			}
+			frameLines = append(frameLines, fmt.Sprintf("%s%s %s.%s(%s:%d)", prefix, at, mappedFrame.ClassName, mappedFrame.MethodName, mappedFrame.SourceFile, mappedFrame.LineNumber))
+			retraced = append(retraced, mappedFrame)
+		}
+	}
+
+	return frameLines, retraced, true
+}
+
+// splitAlternatives splits the frames an obfuscated frame maps to into the
+// alternatives with the given number of frames each. The frames are a single
+// alternative if there aren't several, or they don't add up.
+func splitAlternatives(frames []*mappedStackFrame, alternatives []int) [][]*mappedStackFrame {
+	total := 0
+	for _, n := range alternatives {
+		total += n
+	}
+	if len(alternatives) < 2 || total != len(frames) {
+		return [][]*mappedStackFrame{frames}
+	}
+
+	split := make([][]*mappedStackFrame, 0, len(alternatives))
+	for _, n := range alternatives {
+		split = append(split, frames[:n])
+		frames = frames[n:]
+	}
+	return split
+}
+
+// symbolicateWith symbolicates a frame with the first of the mappings that
+// maps it, returning that mapping's UUID. It returns an error if the frame
+// isn't mapped and any of the mappings couldn't be loaded.
+func (p *proguardLogsProcessor) symbolicateWith(ctx context.Context, uuids []string, class, method string, line int, fetchErrorCache map[string]error) (string, []*mappedStackFrame, error) {
+	var lastErr error
+
+	for _, uuid := range uuids {
 		// Check if we have a cached fetch error for this UUID
 		if cachedError, exists := fetchErrorCache[uuid]; exists {
-			err = cachedError
-		} else {
-			mappedFrames, err = p.symbolicator.symbolicate(ctx, uuid, class, method, int(line))
+			lastErr = cachedError
+			continue
+		}
 
+		mappedFrames, err := p.symbolicator.symbolicate(ctx, uuid, class, method, line)
+		if err != nil {
 			// Only cache FetchErrors (404, timeout, etc.) - not parse or validation errors
-			if err != nil {
-				var fetchErr *FetchError
-				if errors.As(err, &fetchErr) {
-					fetchErrorCache[uuid] = err
-				}
+			var fetchErr *FetchError
+			if errors.As(err, &fetchErr) {
+				fetchErrorCache[uuid] = err
 			}
+			lastErr = err
+			continue
+		}
+
+		if len(mappedFrames) > 0 {
+			return uuid, mappedFrames, nil
+		}
+	}
+
+	return "", nil, lastErr
+}
+
+// remapClass deobfuscates a class name with the first of the mappings that has
+// it, keeping the obfuscated name if none do or they can't be loaded.
+func (p *proguardLogsProcessor) remapClass(ctx context.Context, mappings *mappingSet, class string, fetchErrorCache map[string]error) string {
+	for _, uuid := range mappings.candidates(class) {
+		if _, exists := fetchErrorCache[uuid]; exists {
+			continue
 		}
 
+		remapped, err := p.symbolicator.remapClass(ctx, uuid, class)
 		if err != nil {
-			stack = append(stack, fmt.Sprintf("\tFailed to symbolicate %s.%s(%d): %v", class, method, line, err))
-			symbolicationFailed = true
-			p.telemetryBuilder.ProcessorTotalFailedFrames.Add(ctx, 1, p.attributes)
+			var fetchErr *FetchError
+			if errors.As(err, &fetchErr) {
+				fetchErrorCache[uuid] = err
+			}
+			p.logger.Debug("Failed to remap class", zap.String("class", class), zap.Error(err))
 			continue
 		}
 
-		// Not a symbolication failure but no mapping found or needed; use original stacktrace data
-		if len(mappedFrames) == 0 {
-			// Only populate output slices for structured route
-			if parsedStackTrace == nil {
-				mappedClasses.AppendEmpty().SetStr(class)
-				mappedMethods.AppendEmpty().SetStr(method)
-				mappedLines.AppendEmpty().SetInt(line)
+		if remapped != class {
+			return remapped
+		}
+	}
+
+	return class
+}
+
+// remapNestedClass deobfuscates a class like remapClass and, if the mapping
+// doesn't have it, the outer class of a nested class, such as the continuation
+// a.b$d of a suspend function in a.b.
+func (p *proguardLogsProcessor) remapNestedClass(ctx context.Context, mappings *mappingSet, class string, fetchErrorCache map[string]error) string {
+	if remapped := p.remapClass(ctx, mappings, class, fetchErrorCache); remapped != class {
+		return remapped
+	}
+
+	for i := strings.LastIndex(class, "$"); i > 0; i = strings.LastIndex(class[:i], "$") {
+		if outer := p.remapClass(ctx, mappings, class[:i], fetchErrorCache); outer != class[:i] {
+			return outer + class[i:]
+		}
+	}
+	return class
+}
+
+// mappingSet finds the mappings of a log record through each store layout, in
+// order, from either its resource or log attributes. The mappings of a layout
+// are the fallback of those of the layouts before it.
+func (p *proguardLogsProcessor) mappingSet(attributes pcommon.Map, resourceAttrs pcommon.Map) (*mappingSet, error) {
+	layouts := p.cfg.ProguardStoreLayouts
+	if len(layouts) == 0 {
+		layouts = []string{storeLayoutUUID}
+	}
+
+	var sets []*mappingSet
+	for _, layout := range layouts {
+		switch layout {
+		case storeLayoutUUID:
+			uuidValue, ok := getAttribute(p.cfg.ProguardUUIDAttributeKey, attributes, resourceAttrs)
+			if !ok {
+				continue
+			}
+			set, err := newMappingSet(uuidValue)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, p.cfg.ProguardUUIDAttributeKey)
+			}
+			sets = append(sets, set)
+		case storeLayoutApplicationVersion:
+			applicationID, hasApplicationID := getAttribute(p.cfg.ApplicationIDAttributeKey, attributes, resourceAttrs)
+			versionCode, hasVersionCode := getAttribute(p.cfg.VersionCodeAttributeKey, attributes, resourceAttrs)
+			if !hasApplicationID || !hasVersionCode || applicationID.AsString() == "" || versionCode.AsString() == "" {
+				continue
+			}
+			if !validMappingIDElement(applicationID.AsString()) || !validMappingIDElement(versionCode.AsString()) {
+				return nil, fmt.Errorf("%w: %s, %s", errInvalidMappingID, p.cfg.ApplicationIDAttributeKey, p.cfg.VersionCodeAttributeKey)
 			}
+			sets = append(sets, &mappingSet{uuids: []string{applicationVersionID(applicationID.AsString(), versionCode.AsString())}})
+		}
+	}
 
-			if line == -2 {
-				// Native method, source file and line number are not applicable
-				stack = append(stack, fmt.Sprintf("\tat %s.%s(Native Method)", class, method))
-			} else if line == -1 {
-				// Unknown source file and line number
-				stack = append(stack, fmt.Sprintf("\tat %s.%s(Unknown Source)", class, method))
-			} else {
-				stack = append(stack, fmt.Sprintf("\tat %s.%s(%s:%d)", class, method, sourceFile, line))
+	if len(sets) == 0 {
+		keys := []string{}
+		for _, layout := range layouts {
+			switch layout {
+			case storeLayoutUUID:
+				keys = append(keys, p.cfg.ProguardUUIDAttributeKey)
+			case storeLayoutApplicationVersion:
+				keys = append(keys, p.cfg.ApplicationIDAttributeKey, p.cfg.VersionCodeAttributeKey)
 			}
+		}
+		return nil, fmt.Errorf("%w: %s", errMissingAttribute, strings.Join(keys, ", "))
+	}
+
+	for i := len(sets) - 2; i >= 0; i-- {
+		sets[i].fallback = sets[i+1]
+	}
+	return sets[0], nil
+}
+
+// r8Mappings returns the R8 mapping information of each mapping file by UUID.
+// Mappings that can't be loaded have none, so their frames are retraced one by one.
+func (p *proguardLogsProcessor) r8Mappings(ctx context.Context, mappings *mappingSet, fetchErrorCache map[string]error) map[string]*r8Mapping {
+	uuids := mappings.all()
+	r8Mappings := make(map[string]*r8Mapping, len(uuids))
+
+	for _, uuid := range uuids {
+		if _, exists := fetchErrorCache[uuid]; exists {
 			continue
 		}
 
-		for _, mappedFrame := range mappedFrames {
-			// Only populate output slices for structured route
-			if parsedStackTrace == nil {
-				mappedClasses.AppendEmpty().SetStr(mappedFrame.ClassName)
-				mappedMethods.AppendEmpty().SetStr(mappedFrame.MethodName)
-				mappedLines.AppendEmpty().SetInt(mappedFrame.LineNumber)
+		r8, err := p.symbolicator.r8Mapping(ctx, uuid)
+		if err != nil {
+			var fetchErr *FetchError
+			if errors.As(err, &fetchErr) {
+				fetchErrorCache[uuid] = err
 			}
-
-			stack = append(stack, fmt.Sprintf("\tat %s.%s(%s:%d)", mappedFrame.ClassName, mappedFrame.MethodName, mappedFrame.SourceFile, mappedFrame.LineNumber))
+			p.logger.Debug("Failed to load R8 mapping information", zap.String("uuid", uuid), zap.Error(err))
+			continue
 		}
+		r8Mappings[uuid] = r8
 	}
 
-	attributes.PutStr(p.cfg.StackTraceAttributeKey, strings.Join(stack, "\n"))
+	return r8Mappings
+}
 
-	if symbolicationFailed {
-		return errPartialSymbolication
-	} else {
-		return nil
+// remapClassNames deobfuscates the fully qualified class names in a message,
+// eg. "a.b.c cannot be cast to d.e". Anything that isn't a class in the
+// mapping, such as a file name or version number, is kept.
+func (p *proguardLogsProcessor) remapClassNames(ctx context.Context, mappings *mappingSet, message string, fetchErrorCache map[string]error) string {
+	remapped := make(map[string]string)
+
+	return classNameRegex.ReplaceAllStringFunc(message, func(class string) string {
+		if r, ok := remapped[class]; ok {
+			return r
+		}
+		remapped[class] = p.remapClass(ctx, mappings, class, fetchErrorCache)
+		return remapped[class]
+	})
+}
+
+// formatCauseHeader formats the header of a cause or suppressed exception the
+// way Java prints it, eg. Caused by: java.io.IOException: IO error.
+func formatCauseHeader(c *cause) string {
+	if !c.hasMessage {
+		return fmt.Sprintf("%s%s: %s", c.indent, c.kind, c.exceptionType)
+	}
+	return fmt.Sprintf("%s%s: %s: %s", c.indent, c.kind, c.exceptionType, c.exceptionMessage)
+}
+
+// putCauses writes the causes and suppressed exceptions of a stack trace as
+// parallel slice attributes, in the order they're printed.
+func (p *proguardLogsProcessor) putCauses(attributes pcommon.Map, causes []*cause) {
+	types := attributes.PutEmptySlice(p.cfg.CauseTypesAttributeKey)
+	messages := attributes.PutEmptySlice(p.cfg.CauseMessagesAttributeKey)
+	kinds := attributes.PutEmptySlice(p.cfg.CauseKindsAttributeKey)
+	enclosing := attributes.PutEmptySlice(p.cfg.CauseEnclosingAttributeKey)
+
+	for _, c := range causes {
+		types.AppendEmpty().SetStr(c.exceptionType)
+		messages.AppendEmpty().SetStr(c.exceptionMessage)
+		kinds.AppendEmpty().SetStr(string(c.kind))
+		enclosing.AppendEmpty().SetInt(int64(c.enclosing))
 	}
 }
 
@@ -333,6 +759,31 @@ func getSlice(key string, m pcommon.Map) (pcommon.Slice, bool) {
 	return v.Slice(), true
 }
 
+// getAttribute retrieves an attribute from the log attributes, or the resource
+// attributes if the log doesn't have it.
+func getAttribute(key string, attributes pcommon.Map, resourceAttrs pcommon.Map) (pcommon.Value, bool) {
+	if value, ok := attributes.Get(key); ok {
+		return value, true
+	}
+	return resourceAttrs.Get(key)
+}
+
+// languageAllowed checks the language of a signal, from its attributes or
+// resource attributes, if the processor is limited to some languages.
+func (p *proguardLogsProcessor) languageAllowed(attributes pcommon.Map, resourceAttrs pcommon.Map) bool {
+	if len(p.cfg.AllowedLanguages) == 0 {
+		return true
+	}
+
+	// Language attribute not found, skip processing
+	languageValue, ok := getAttribute(p.cfg.LanguageAttributeKey, attributes, resourceAttrs)
+	if !ok {
+		return false
+	}
+
+	return isLanguageAllowed(languageValue.Str(), p.cfg.AllowedLanguages)
+}
+
 // isLanguageAllowed checks if the given language matches any of the allowed languages.
 // Comparison is case insensitive.
 func isLanguageAllowed(language string, allowedLanguages []string) bool {
@@ -1,14 +1,18 @@
 package proguardprocessor
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/honeycombio/opentelemetry-collector-symbolicator/proguardprocessor/internal/metadata"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
@@ -24,8 +28,11 @@ type mockLogProcessorStore struct {
 	err     error
 }
 
-func (m *mockLogProcessorStore) GetProguardMapping(ctx context.Context, uuid string) ([]byte, error) {
-	return m.mapping[uuid], m.err
+func (m *mockLogProcessorStore) GetProguardMapping(ctx context.Context, uuid string) (io.ReadCloser, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return io.NopCloser(bytes.NewReader(m.mapping[uuid])), nil
 }
 
 type mockLogProcessorSymbolicator struct {
@@ -39,6 +46,18 @@ func (m *mockLogProcessorSymbolicator) symbolicate(ctx context.Context, uuid, cl
 	return m.frames, m.err
 }
 
+func (m *mockLogProcessorSymbolicator) remapClass(ctx context.Context, uuid, className string) (string, error) {
+	return className, m.err
+}
+
+func (m *mockLogProcessorSymbolicator) remapMethod(ctx context.Context, uuid, className, methodName string) (string, string, error) {
+	return "", "", m.err
+}
+
+func (m *mockLogProcessorSymbolicator) r8Mapping(ctx context.Context, uuid string) (*r8Mapping, error) {
+	return nil, nil
+}
+
 func (m *mockLogProcessorSymbolicator) clear() {
 	m.callCount = 0
 }
@@ -1034,6 +1053,18 @@ type testSymbolicatorWithFetchErrors struct {
 	err              error
 }
 
+func (m *testSymbolicatorWithFetchErrors) remapClass(ctx context.Context, uuid, className string) (string, error) {
+	return className, nil
+}
+
+func (m *testSymbolicatorWithFetchErrors) remapMethod(ctx context.Context, uuid, className, methodName string) (string, string, error) {
+	return "", "", nil
+}
+
+func (m *testSymbolicatorWithFetchErrors) r8Mapping(ctx context.Context, uuid string) (*r8Mapping, error) {
+	return nil, nil
+}
+
 func (m *testSymbolicatorWithFetchErrors) symbolicate(ctx context.Context, uuid, className, methodName string, lineNumber int) ([]*mappedStackFrame, error) {
 	m.callCount++
 	if m.err != nil {
@@ -1319,3 +1350,642 @@ func TestLanguageFiltering(t *testing.T) {
 		})
 	}
 }
+
+// mockClassRemappingSymbolicator deobfuscates classes from a fixed table,
+// keeping the method and line of each frame.
+type mockClassRemappingSymbolicator struct {
+	classes map[string]string
+}
+
+func (m *mockClassRemappingSymbolicator) symbolicate(ctx context.Context, uuid, className, methodName string, lineNumber int) ([]*mappedStackFrame, error) {
+	remapped, ok := m.classes[className]
+	if !ok {
+		return nil, nil
+	}
+	return []*mappedStackFrame{{ClassName: remapped, MethodName: methodName, SourceFile: "Source.java", LineNumber: int64(lineNumber)}}, nil
+}
+
+func (m *mockClassRemappingSymbolicator) remapClass(ctx context.Context, uuid, className string) (string, error) {
+	if remapped, ok := m.classes[className]; ok {
+		return remapped, nil
+	}
+	return className, nil
+}
+
+func (m *mockClassRemappingSymbolicator) remapMethod(ctx context.Context, uuid, className, methodName string) (string, string, error) {
+	if remapped, ok := m.classes[className]; ok {
+		return remapped, methodName, nil
+	}
+	return "", "", nil
+}
+
+func (m *mockClassRemappingSymbolicator) r8Mapping(ctx context.Context, uuid string) (*r8Mapping, error) {
+	return nil, nil
+}
+
+func TestProcessLogRecord_CausesAndSuppressedExceptions(t *testing.T) {
+	rawStackTrace := `java.lang.RuntimeException: Error
+	at a.b.c(SourceFile:10)
+	at a.b.d(SourceFile:20)
+	Suppressed: a.e: close failed
+		at a.e.f(SourceFile:30)
+		... 1 more
+Caused by: a.g
+	at a.g.h(SourceFile:40)
+	... 2 more`
+
+	symbolicator := &mockClassRemappingSymbolicator{classes: map[string]string{
+		"a.b": "com.example.Main",
+		"a.e": "com.example.CloseException",
+		"a.g": "com.example.RootException",
+	}}
+
+	process := func(cfg *Config) pcommon.Map {
+		tb, attributes := createMockTelemetry(t)
+		processor, err := newProguardLogsProcessor(context.Background(), cfg, &mockLogProcessorStore{}, processor.Settings{
+			TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+		}, symbolicator, tb, attributes)
+		assert.NoError(t, err)
+
+		lr := plog.NewLogRecord()
+		lr.Attributes().PutStr(cfg.StackTraceAttributeKey, rawStackTrace)
+		resourceAttrs := pcommon.NewMap()
+		resourceAttrs.PutStr(cfg.ProguardUUIDAttributeKey, "test-uuid")
+
+		processor.processLogRecord(context.Background(), lr, resourceAttrs)
+		return lr.Attributes()
+	}
+
+	t.Run("rebuilds the cause tree", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		attrs := process(cfg)
+
+		failed, _ := attrs.Get(cfg.SymbolicatorFailureAttributeKey)
+		assert.False(t, failed.Bool())
+
+		stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+		assert.Equal(t, `java.lang.RuntimeException: Error
+	at com.example.Main.c(Source.java:10)
+	at com.example.Main.d(Source.java:20)
+	Suppressed: com.example.CloseException: close failed
+		at com.example.CloseException.f(Source.java:30)
+		... 1 more
+Caused by: com.example.RootException
+	at com.example.RootException.h(Source.java:40)
+	... 2 more`, stackTrace.Str())
+
+		types, _ := attrs.Get(cfg.CauseTypesAttributeKey)
+		assert.Equal(t, []any{"com.example.CloseException", "com.example.RootException"}, types.Slice().AsRaw())
+		messages, _ := attrs.Get(cfg.CauseMessagesAttributeKey)
+		assert.Equal(t, []any{"close failed", ""}, messages.Slice().AsRaw())
+		kinds, _ := attrs.Get(cfg.CauseKindsAttributeKey)
+		assert.Equal(t, []any{"Suppressed", "Caused by"}, kinds.Slice().AsRaw())
+		enclosing, _ := attrs.Get(cfg.CauseEnclosingAttributeKey)
+		assert.Equal(t, []any{int64(-1), int64(-1)}, enclosing.Slice().AsRaw())
+	})
+
+	t.Run("expands frames in common", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		cfg.ExpandFramesInCommon = true
+		attrs := process(cfg)
+
+		stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+		assert.Equal(t, `java.lang.RuntimeException: Error
+	at com.example.Main.c(Source.java:10)
+	at com.example.Main.d(Source.java:20)
+	Suppressed: com.example.CloseException: close failed
+		at com.example.CloseException.f(Source.java:30)
+		at com.example.Main.d(Source.java:20)
+Caused by: com.example.RootException
+	at com.example.RootException.h(Source.java:40)
+	at com.example.Main.c(Source.java:10)
+	at com.example.Main.d(Source.java:20)`, stackTrace.Str())
+	})
+
+	t.Run("without causes", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		tb, attributes := createMockTelemetry(t)
+		processor, _ := newProguardLogsProcessor(context.Background(), cfg, &mockLogProcessorStore{}, processor.Settings{
+			TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+		}, symbolicator, tb, attributes)
+
+		lr := plog.NewLogRecord()
+		lr.Attributes().PutStr(cfg.StackTraceAttributeKey, "java.lang.RuntimeException: Error\n\tat a.b.c(SourceFile:10)")
+		resourceAttrs := pcommon.NewMap()
+		resourceAttrs.PutStr(cfg.ProguardUUIDAttributeKey, "test-uuid")
+		processor.processLogRecord(context.Background(), lr, resourceAttrs)
+
+		_, ok := lr.Attributes().Get(cfg.CauseTypesAttributeKey)
+		assert.False(t, ok)
+	})
+}
+
+func TestProcessLogRecord_DeobfuscateExceptionTypeAndMessage(t *testing.T) {
+	symbolicator := &mockClassRemappingSymbolicator{classes: map[string]string{
+		"a.b":   "com.example.Main",
+		"a.b$c": "com.example.Main$Listener",
+		"a.g":   "com.example.RootException",
+	}}
+
+	process := func(cfg *Config, setup func(attrs pcommon.Map)) pcommon.Map {
+		tb, attributes := createMockTelemetry(t)
+		processor, err := newProguardLogsProcessor(context.Background(), cfg, &mockLogProcessorStore{}, processor.Settings{
+			TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+		}, symbolicator, tb, attributes)
+		assert.NoError(t, err)
+
+		lr := plog.NewLogRecord()
+		setup(lr.Attributes())
+		resourceAttrs := pcommon.NewMap()
+		resourceAttrs.PutStr(cfg.ProguardUUIDAttributeKey, "test-uuid")
+
+		processor.processLogRecord(context.Background(), lr, resourceAttrs)
+		return lr.Attributes()
+	}
+
+	t.Run("parsed route", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		attrs := process(cfg, func(attrs pcommon.Map) {
+			attrs.PutStr(cfg.StackTraceAttributeKey, `a.g: a.b$c cannot be cast to a.b (in file.txt, version 1.2)
+	at a.b.c(SourceFile:10)
+Caused by: java.lang.ClassCastException: a.b cannot be cast to java.lang.String
+	at a.b.d(SourceFile:20)`)
+		})
+
+		exceptionType, _ := attrs.Get(cfg.ExceptionTypeAttributeKey)
+		assert.Equal(t, "com.example.RootException", exceptionType.Str())
+		exceptionMessage, _ := attrs.Get(cfg.ExceptionMessageAttributeKey)
+		assert.Equal(t, "com.example.Main$Listener cannot be cast to com.example.Main (in file.txt, version 1.2)", exceptionMessage.Str())
+
+		originalType, _ := attrs.Get(cfg.OriginalExceptionTypeAttributeKey)
+		assert.Equal(t, "a.g", originalType.Str())
+		originalMessage, _ := attrs.Get(cfg.OriginalExceptionMessageAttributeKey)
+		assert.Equal(t, "a.b$c cannot be cast to a.b (in file.txt, version 1.2)", originalMessage.Str())
+
+		stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+		assert.Equal(t, `com.example.RootException: com.example.Main$Listener cannot be cast to com.example.Main (in file.txt, version 1.2)
+	at com.example.Main.c(Source.java:10)
+Caused by: java.lang.ClassCastException: com.example.Main cannot be cast to java.lang.String
+	at com.example.Main.d(Source.java:20)`, stackTrace.Str())
+	})
+
+	t.Run("structured route", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		cfg.PreserveStackTrace = false
+		attrs := process(cfg, func(attrs pcommon.Map) {
+			attrs.PutStr(cfg.StackTraceAttributeKey, "a.g: boom")
+			attrs.PutStr(cfg.ExceptionTypeAttributeKey, "a.g")
+			attrs.PutStr(cfg.ExceptionMessageAttributeKey, "boom")
+			attrs.PutEmptySlice(cfg.ClassesAttributeKey).AppendEmpty().SetStr("a.b")
+			attrs.PutEmptySlice(cfg.MethodsAttributeKey).AppendEmpty().SetStr("c")
+			attrs.PutEmptySlice(cfg.LinesAttributeKey).AppendEmpty().SetInt(10)
+			attrs.PutEmptySlice(cfg.SourceFilesAttributeKey).AppendEmpty().SetStr("SourceFile")
+		})
+
+		exceptionType, _ := attrs.Get(cfg.ExceptionTypeAttributeKey)
+		assert.Equal(t, "com.example.RootException", exceptionType.Str())
+
+		// originals are only kept when the stack trace is preserved
+		_, ok := attrs.Get(cfg.OriginalExceptionTypeAttributeKey)
+		assert.False(t, ok)
+	})
+}
+
+func TestProcessLogRecord_R8Retrace(t *testing.T) {
+	ctx := context.Background()
+	tb, attributes := createMockTelemetry(t)
+	store := &mockLogProcessorStore{mapping: map[string][]byte{"test-uuid": []byte(r8TestMapping)}}
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, store, tb, attributes)
+	require.NoError(t, err)
+
+	cfg := createDefaultConfig().(*Config)
+	processor, err := newProguardLogsProcessor(ctx, cfg, store, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+	}, symbolicator, tb, attributes)
+	require.NoError(t, err)
+
+	lr := plog.NewLogRecord()
+	// b.t throws, so the inlined Util.check frame is removed, c.run and the
+	// inlined access$run are synthesized, and the outline a.a is retraced at
+	// its callsite b.s
+	lr.Attributes().PutStr(cfg.StackTraceAttributeKey, `java.lang.NullPointerException: boom
+	at b.t(SourceFile:1)
+	at c.run(SourceFile:1)
+	at b.u(SourceFile:2)
+Caused by: java.lang.IllegalStateException: bad
+	at a.a(SourceFile:1)
+	at b.s(SourceFile:27)`)
+	resourceAttrs := pcommon.NewMap()
+	resourceAttrs.PutStr(cfg.ProguardUUIDAttributeKey, "test-uuid")
+
+	processor.processLogRecord(ctx, lr, resourceAttrs)
+
+	failed, _ := lr.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+	stackTrace, _ := lr.Attributes().Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `java.lang.NullPointerException: boom
+	at com.example.Main.run(Main.kt:20)
+	at com.example.Main.main(Main.kt:40)
+Caused by: java.lang.IllegalStateException: bad
+	at com.example.Main.outlineCaller(Main.kt:98)`, stackTrace.Str())
+}
+
+func TestProcessLogRecord_AmbiguousFrames(t *testing.T) {
+	ctx := context.Background()
+	tb, attributes := createMockTelemetry(t)
+	store := &mockLogProcessorStore{mapping: map[string][]byte{"test-uuid": []byte(`com.example.Main -> a:
+    void start() -> a
+    void stop(int) -> a
+    1:1:void inlined():10:10 -> b
+    1:1:void caller():20 -> b
+`)}}
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, store, tb, attributes)
+	require.NoError(t, err)
+
+	process := func(cfg *Config, setup func(attrs pcommon.Map)) pcommon.Map {
+		processor, err := newProguardLogsProcessor(ctx, cfg, store, processor.Settings{
+			TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+		}, symbolicator, tb, attributes)
+		require.NoError(t, err)
+
+		lr := plog.NewLogRecord()
+		setup(lr.Attributes())
+		resourceAttrs := pcommon.NewMap()
+		resourceAttrs.PutStr(cfg.ProguardUUIDAttributeKey, "test-uuid")
+
+		processor.processLogRecord(ctx, lr, resourceAttrs)
+		return lr.Attributes()
+	}
+
+	t.Run("parsed route", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		attrs := process(cfg, func(attrs pcommon.Map) {
+			attrs.PutStr(cfg.StackTraceAttributeKey, `java.lang.RuntimeException: boom
+	at a.a(SourceFile:5)
+	at a.b(SourceFile:1)`)
+		})
+
+		stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+		assert.Equal(t, `java.lang.RuntimeException: boom
+	at com.example.Main.start(:0)
+	<OR> at com.example.Main.stop(:0)
+	at com.example.Main.inlined(:10)
+	at com.example.Main.caller(:20)`, stackTrace.Str())
+	})
+
+	t.Run("structured route", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		attrs := process(cfg, func(attrs pcommon.Map) {
+			attrs.PutStr(cfg.StackTraceAttributeKey, "java.lang.RuntimeException: boom")
+			attrs.PutStr(cfg.ExceptionTypeAttributeKey, "java.lang.RuntimeException")
+			attrs.PutStr(cfg.ExceptionMessageAttributeKey, "boom")
+			classes := attrs.PutEmptySlice(cfg.ClassesAttributeKey)
+			methods := attrs.PutEmptySlice(cfg.MethodsAttributeKey)
+			lines := attrs.PutEmptySlice(cfg.LinesAttributeKey)
+			sourceFiles := attrs.PutEmptySlice(cfg.SourceFilesAttributeKey)
+			for _, frame := range []struct {
+				method string
+				line   int64
+			}{{"a", 5}, {"b", 1}} {
+				classes.AppendEmpty().SetStr("a")
+				methods.AppendEmpty().SetStr(frame.method)
+				lines.AppendEmpty().SetInt(frame.line)
+				sourceFiles.AppendEmpty().SetStr("SourceFile")
+			}
+		})
+
+		methods, _ := attrs.Get(cfg.MethodsAttributeKey)
+		assert.Equal(t, []any{"start", "stop", "inlined", "caller"}, methods.Slice().AsRaw())
+		ambiguous, _ := attrs.Get(cfg.AmbiguousAttributeKey)
+		assert.Equal(t, []any{true, true, false, false}, ambiguous.Slice().AsRaw())
+		frameIndexes, _ := attrs.Get(cfg.FrameIndexesAttributeKey)
+		assert.Equal(t, []any{int64(0), int64(0), int64(1), int64(1)}, frameIndexes.Slice().AsRaw())
+	})
+}
+
+func TestProcessLogRecord_ThreadDump(t *testing.T) {
+	ctx := context.Background()
+	tb, attributes := createMockTelemetry(t)
+	store := &mockLogProcessorStore{mapping: map[string][]byte{"test-uuid": []byte(`com.example.MainActivity -> a.b:
+    12:12:void onClick(android.view.View):40:40 -> c
+    34:34:void run():50:50 -> e
+com.example.Lock -> a.d:
+    void <init>() -> <init>
+`)}}
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, store, tb, attributes)
+	require.NoError(t, err)
+
+	cfg := createDefaultConfig().(*Config)
+	processor, err := newProguardLogsProcessor(ctx, cfg, store, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+	}, symbolicator, tb, attributes)
+	require.NoError(t, err)
+
+	lr := plog.NewLogRecord()
+	lr.Attributes().PutStr(cfg.StackTraceAttributeKey, anrTrace)
+	resourceAttrs := pcommon.NewMap()
+	resourceAttrs.PutStr(cfg.ProguardUUIDAttributeKey, "test-uuid")
+
+	processor.processLogRecord(ctx, lr, resourceAttrs)
+	attrs := lr.Attributes()
+
+	failed, _ := attrs.Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+	parsingMethod, _ := attrs.Get(cfg.SymbolicatorParsingMethodAttributeKey)
+	assert.Equal(t, "processor_parsed_thread_dump", parsingMethod.Str())
+
+	stackTrace, _ := attrs.Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `----- pid 12345 at 2026-01-01 12:00:00.000 -----
+Cmd line: com.example.app
+
+DALVIK THREADS (2):
+"main" prio=5 tid=1 Blocked
+  | group="main" sCount=1 ucsCount=0 flags=1 obj=0x72f6d1a8 self=0xb400007b1c0e2be0
+  at com.example.MainActivity.onClick(:40)
+  - waiting to lock <0x0a1b2c3d> (a com.example.Lock) held by thread 15
+  at java.lang.Object.wait(Native Method)
+  native: #00 pc 000000000004c3a0  /apex/com.android.runtime/lib64/bionic/libc.so (syscall+32)
+
+"Signal Catcher" daemon prio=10 tid=6 Runnable
+  at com.example.MainActivity.run(:50)
+  - locked <0x0e4f5a6b> (a java.lang.Object)
+----- end 12345 -----`, stackTrace.Str())
+
+	original, _ := attrs.Get(cfg.OriginalStackTraceAttributeKey)
+	assert.Equal(t, anrTrace, original.Str())
+
+	// only the main thread's frames are structured
+	classes, _ := attrs.Get(cfg.ClassesAttributeKey)
+	assert.Equal(t, []any{"com.example.MainActivity", "java.lang.Object"}, classes.Slice().AsRaw())
+	methods, _ := attrs.Get(cfg.MethodsAttributeKey)
+	assert.Equal(t, []any{"onClick", "wait"}, methods.Slice().AsRaw())
+	lines, _ := attrs.Get(cfg.LinesAttributeKey)
+	assert.Equal(t, []any{int64(40), int64(-2)}, lines.Slice().AsRaw())
+	frameIndexes, _ := attrs.Get(cfg.FrameIndexesAttributeKey)
+	assert.Equal(t, []any{int64(0), int64(1)}, frameIndexes.Slice().AsRaw())
+}
+
+func TestProcessLogRecord_CoroutineBoundaries(t *testing.T) {
+	ctx := context.Background()
+	tb, attributes := createMockTelemetry(t)
+	store := &mockLogProcessorStore{mapping: map[string][]byte{"test-uuid": []byte(`com.example.Repository -> a.b:
+    10:10:java.lang.Object load(kotlin.coroutines.Continuation):40:40 -> c
+    30:30:void refresh():60:60 -> e
+com.example.Repository$load$1 -> a.b$d:
+    void <init>() -> <init>
+`)}}
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, store, tb, attributes)
+	require.NoError(t, err)
+
+	cfg := createDefaultConfig().(*Config)
+	processor, err := newProguardLogsProcessor(ctx, cfg, store, processor.Settings{
+		TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+	}, symbolicator, tb, attributes)
+	require.NoError(t, err)
+
+	lr := plog.NewLogRecord()
+	// a.b$d.invokeSuspend isn't renamed, so only its class is in the mapping,
+	// and a.b$f isn't in the mapping at all, only its outer class
+	lr.Attributes().PutStr(cfg.StackTraceAttributeKey, `java.lang.IllegalStateException: boom
+	at a.b.c(SourceFile:10)
+	at _COROUTINE._BOUNDARY._(CoroutineDebugging.kt:46)
+	at a.b$d.invokeSuspend(SourceFile:20)
+	(Coroutine boundary)
+	at a.b$f.invoke(SourceFile:25)
+	at a.b.e(SourceFile:30)`)
+	resourceAttrs := pcommon.NewMap()
+	resourceAttrs.PutStr(cfg.ProguardUUIDAttributeKey, "test-uuid")
+
+	processor.processLogRecord(ctx, lr, resourceAttrs)
+
+	failed, _ := lr.Attributes().Get(cfg.SymbolicatorFailureAttributeKey)
+	assert.False(t, failed.Bool())
+	stackTrace, _ := lr.Attributes().Get(cfg.StackTraceAttributeKey)
+	assert.Equal(t, `java.lang.IllegalStateException: boom
+	at com.example.Repository.load(:40)
+	at _COROUTINE._BOUNDARY._(CoroutineDebugging.kt:46)
+	at com.example.Repository$load$1.invokeSuspend(SourceFile:20)
+	(Coroutine boundary)
+	at com.example.Repository$f.invoke(SourceFile:25)
+	at com.example.Repository.refresh(:60)`, stackTrace.Str())
+}
+
+// mockMultiMappingSymbolicator deobfuscates classes from a table per mapping
+// UUID, failing to fetch the mappings it has an error for.
+type mockMultiMappingSymbolicator struct {
+	classes map[string]map[string]string
+	errs    map[string]error
+	calls   map[string]int
+}
+
+func (m *mockMultiMappingSymbolicator) symbolicate(ctx context.Context, uuid, className, methodName string, lineNumber int) ([]*mappedStackFrame, error) {
+	m.calls[uuid]++
+	if err, ok := m.errs[uuid]; ok {
+		return nil, &FetchError{UUID: uuid, Err: err}
+	}
+	remapped, ok := m.classes[uuid][className]
+	if !ok {
+		return nil, nil
+	}
+	return []*mappedStackFrame{{ClassName: remapped, MethodName: methodName, SourceFile: uuid + ".java", LineNumber: int64(lineNumber)}}, nil
+}
+
+func (m *mockMultiMappingSymbolicator) remapClass(ctx context.Context, uuid, className string) (string, error) {
+	if err, ok := m.errs[uuid]; ok {
+		return "", &FetchError{UUID: uuid, Err: err}
+	}
+	if remapped, ok := m.classes[uuid][className]; ok {
+		return remapped, nil
+	}
+	return className, nil
+}
+
+func (m *mockMultiMappingSymbolicator) remapMethod(ctx context.Context, uuid, className, methodName string) (string, string, error) {
+	if err, ok := m.errs[uuid]; ok {
+		return "", "", &FetchError{UUID: uuid, Err: err}
+	}
+	if remapped, ok := m.classes[uuid][className]; ok {
+		return remapped, methodName, nil
+	}
+	return "", "", nil
+}
+
+func (m *mockMultiMappingSymbolicator) r8Mapping(ctx context.Context, uuid string) (*r8Mapping, error) {
+	if err, ok := m.errs[uuid]; ok {
+		return nil, &FetchError{UUID: uuid, Err: err}
+	}
+	return nil, nil
+}
+
+func TestProcessLogRecord_MultipleMappings(t *testing.T) {
+	process := func(symbolicator symbolicator, setup func(resourceAttrs pcommon.Map)) pcommon.Map {
+		cfg := createDefaultConfig().(*Config)
+		tb, attributes := createMockTelemetry(t)
+		processor, err := newProguardLogsProcessor(context.Background(), cfg, &mockLogProcessorStore{}, processor.Settings{
+			TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+		}, symbolicator, tb, attributes)
+		require.NoError(t, err)
+
+		lr := plog.NewLogRecord()
+		lr.Attributes().PutStr(cfg.StackTraceAttributeKey, `a.c: boom
+	at a.b.c(SourceFile:10)
+	at com.feature.a.d(SourceFile:20)
+	at java.lang.Thread.run(Thread.java:1012)`)
+		resourceAttrs := pcommon.NewMap()
+		setup(resourceAttrs)
+
+		processor.processLogRecord(context.Background(), lr, resourceAttrs)
+		return lr.Attributes()
+	}
+
+	classes := map[string]map[string]string{
+		"app":     {"a.b": "com.example.Main", "a.c": "com.example.AppException"},
+		"feature": {"com.feature.a": "com.feature.Checkout"},
+	}
+
+	t.Run("list of UUIDs", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{classes: classes, calls: map[string]int{}}
+		attrs := process(symbolicator, func(resourceAttrs pcommon.Map) {
+			uuids := resourceAttrs.PutEmptySlice("app.debug.proguard_uuid")
+			uuids.AppendEmpty().SetStr("feature")
+			uuids.AppendEmpty().SetStr("app")
+		})
+
+		stackTrace, _ := attrs.Get("exception.stacktrace")
+		assert.Equal(t, `com.example.AppException: boom
+	at com.example.Main.c(app.java:10)
+	at com.feature.Checkout.d(feature.java:20)
+	at java.lang.Thread.run(Thread.java:1012)`, stackTrace.Str())
+		failed, _ := attrs.Get("exception.symbolicator.failed")
+		assert.False(t, failed.Bool())
+	})
+
+	t.Run("UUIDs by package prefix", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{classes: classes, calls: map[string]int{}}
+		attrs := process(symbolicator, func(resourceAttrs pcommon.Map) {
+			uuids := resourceAttrs.PutEmptyMap("app.debug.proguard_uuid")
+			uuids.PutStr("com.feature", "feature")
+			uuids.PutStr("a", "app")
+		})
+
+		// java.lang.Thread isn't in either package, so it isn't looked up
+		stackTrace, _ := attrs.Get("exception.stacktrace")
+		assert.Equal(t, `com.example.AppException: boom
+	at com.example.Main.c(app.java:10)
+	at com.feature.Checkout.d(feature.java:20)
+	at java.lang.Thread.run(Thread.java:1012)`, stackTrace.Str())
+		assert.Equal(t, map[string]int{"app": 1, "feature": 1}, symbolicator.calls)
+	})
+
+	t.Run("fetch errors are cached per UUID", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{
+			classes: classes,
+			errs:    map[string]error{"feature": errors.New("404 not found")},
+			calls:   map[string]int{},
+		}
+		attrs := process(symbolicator, func(resourceAttrs pcommon.Map) {
+			uuids := resourceAttrs.PutEmptySlice("app.debug.proguard_uuid")
+			uuids.AppendEmpty().SetStr("feature")
+			uuids.AppendEmpty().SetStr("app")
+		})
+
+		stackTrace, _ := attrs.Get("exception.stacktrace")
+		assert.Equal(t, `com.example.AppException: boom
+	at com.example.Main.c(app.java:10)
+	Failed to symbolicate com.feature.a.d(20): failed to fetch ProGuard mapping for feature: 404 not found
+	Failed to symbolicate java.lang.Thread.run(1012): failed to fetch ProGuard mapping for feature: 404 not found`, stackTrace.Str())
+		failed, _ := attrs.Get("exception.symbolicator.failed")
+		assert.True(t, failed.Bool())
+		assert.Equal(t, 0, symbolicator.calls["feature"], "the missing mapping's error was cached when loading its R8 information")
+		assert.Equal(t, 3, symbolicator.calls["app"])
+	})
+}
+
+func TestProcessLogRecord_ApplicationVersionLayout(t *testing.T) {
+	process := func(layouts []string, symbolicator symbolicator, setup func(resourceAttrs pcommon.Map)) pcommon.Map {
+		cfg := createDefaultConfig().(*Config)
+		cfg.ProguardStoreLayouts = layouts
+		tb, attributes := createMockTelemetry(t)
+		processor, err := newProguardLogsProcessor(context.Background(), cfg, &mockLogProcessorStore{}, processor.Settings{
+			TelemetrySettings: component.TelemetrySettings{Logger: zaptest.NewLogger(t)},
+		}, symbolicator, tb, attributes)
+		require.NoError(t, err)
+
+		lr := plog.NewLogRecord()
+		lr.Attributes().PutStr(cfg.StackTraceAttributeKey, `a.c: boom
+	at a.b.c(SourceFile:10)
+	at com.feature.a.d(SourceFile:20)`)
+		resourceAttrs := pcommon.NewMap()
+		setup(resourceAttrs)
+
+		processor.processLogRecord(context.Background(), lr, resourceAttrs)
+		return lr.Attributes()
+	}
+
+	classes := map[string]map[string]string{
+		"com.example.app/42": {"a.b": "com.example.Main", "a.c": "com.example.AppException"},
+		"feature":            {"com.feature.a": "com.feature.Checkout"},
+	}
+
+	t.Run("application ID and version code", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{classes: classes, calls: map[string]int{}}
+		attrs := process([]string{storeLayoutApplicationVersion}, symbolicator, func(resourceAttrs pcommon.Map) {
+			resourceAttrs.PutStr("service.name", "com.example.app")
+			resourceAttrs.PutInt("service.version", 42)
+		})
+
+		stackTrace, _ := attrs.Get("exception.stacktrace")
+		assert.Equal(t, `com.example.AppException: boom
+	at com.example.Main.c(com.example.app/42.java:10)
+	at com.feature.a.d(SourceFile:20)`, stackTrace.Str())
+		assert.Zero(t, symbolicator.calls["feature"])
+	})
+
+	t.Run("falls back to the UUID layout", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{classes: classes, calls: map[string]int{}}
+		attrs := process([]string{storeLayoutApplicationVersion, storeLayoutUUID}, symbolicator, func(resourceAttrs pcommon.Map) {
+			resourceAttrs.PutStr("service.name", "com.example.app")
+			resourceAttrs.PutStr("service.version", "42")
+			resourceAttrs.PutStr("app.debug.proguard_uuid", "feature")
+		})
+
+		stackTrace, _ := attrs.Get("exception.stacktrace")
+		assert.Equal(t, `com.example.AppException: boom
+	at com.example.Main.c(com.example.app/42.java:10)
+	at com.feature.Checkout.d(feature.java:20)`, stackTrace.Str())
+	})
+
+	t.Run("UUID layout when the build has no version", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{classes: classes, calls: map[string]int{}}
+		attrs := process([]string{storeLayoutApplicationVersion, storeLayoutUUID}, symbolicator, func(resourceAttrs pcommon.Map) {
+			resourceAttrs.PutStr("service.name", "com.example.app")
+			resourceAttrs.PutStr("app.debug.proguard_uuid", "feature")
+		})
+
+		stackTrace, _ := attrs.Get("exception.stacktrace")
+		assert.Equal(t, `a.c: boom
+	at a.b.c(SourceFile:10)
+	at com.feature.Checkout.d(feature.java:20)`, stackTrace.Str())
+		assert.Zero(t, symbolicator.calls["com.example.app/42"])
+	})
+
+	t.Run("application ID escaping the store prefix", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{classes: classes, calls: map[string]int{}}
+		attrs := process([]string{storeLayoutApplicationVersion}, symbolicator, func(resourceAttrs pcommon.Map) {
+			resourceAttrs.PutStr("service.name", "../../secrets")
+			resourceAttrs.PutStr("service.version", "42")
+		})
+
+		errorMsg, _ := attrs.Get("exception.symbolicator.error")
+		assert.Contains(t, errorMsg.Str(), "invalid proguard mapping ID: service.name, service.version")
+		assert.Empty(t, symbolicator.calls)
+	})
+
+	t.Run("missing every attribute", func(t *testing.T) {
+		symbolicator := &mockMultiMappingSymbolicator{classes: classes, calls: map[string]int{}}
+		attrs := process([]string{storeLayoutApplicationVersion, storeLayoutUUID}, symbolicator, func(resourceAttrs pcommon.Map) {})
+
+		errorMsg, _ := attrs.Get("exception.symbolicator.error")
+		assert.Contains(t, errorMsg.Str(), "missing attribute: service.name, service.version, app.debug.proguard_uuid")
+	})
+}
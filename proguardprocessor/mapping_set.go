@@ -0,0 +1,129 @@
+package proguardprocessor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+var errInvalidMappingUUIDs = errors.New("invalid proguard mapping UUIDs")
+
+// mappingSet is the mapping files the frames of a log record are deobfuscated
+// with. Apps with dynamic feature modules, or SDKs obfuscated separately, have
+// more than one: either a list that's tried in order, or a mapping per package.
+type mappingSet struct {
+	// uuids are the UUIDs of every mapping, in the order they're tried.
+	uuids []string
+	// prefixes map package prefixes to the UUID of the mapping for their
+	// classes, or are nil if every mapping is tried.
+	prefixes map[string]string
+	// fallback is the set of mappings tried after these, found through another
+	// store layout, if any.
+	fallback *mappingSet
+}
+
+// newMappingSet reads the mapping UUIDs of a log record from an attribute that
+// is a single UUID, a list of UUIDs, or a map of package prefixes to UUIDs.
+func newMappingSet(value pcommon.Value) (*mappingSet, error) {
+	switch value.Type() {
+	case pcommon.ValueTypeStr:
+		if value.Str() == "" {
+			return nil, fmt.Errorf("%w: empty UUID", errInvalidMappingUUIDs)
+		}
+		if !validMappingIDElement(value.Str()) {
+			return nil, fmt.Errorf("%w: UUID %q is not a valid mapping ID", errInvalidMappingUUIDs, value.Str())
+		}
+		return &mappingSet{uuids: []string{value.Str()}}, nil
+	case pcommon.ValueTypeSlice:
+		m := &mappingSet{}
+		for i := 0; i < value.Slice().Len(); i++ {
+			uuid := value.Slice().At(i)
+			if uuid.Type() != pcommon.ValueTypeStr || uuid.Str() == "" {
+				return nil, fmt.Errorf("%w: UUID %d is not a string", errInvalidMappingUUIDs, i)
+			}
+			if !validMappingIDElement(uuid.Str()) {
+				return nil, fmt.Errorf("%w: UUID %q is not a valid mapping ID", errInvalidMappingUUIDs, uuid.Str())
+			}
+			m.uuids = append(m.uuids, uuid.Str())
+		}
+		if len(m.uuids) == 0 {
+			return nil, fmt.Errorf("%w: no UUIDs", errInvalidMappingUUIDs)
+		}
+		return m, nil
+	case pcommon.ValueTypeMap:
+		m := &mappingSet{prefixes: make(map[string]string)}
+		seen := make(map[string]bool)
+		for prefix, uuid := range value.Map().All() {
+			if uuid.Type() != pcommon.ValueTypeStr || uuid.Str() == "" {
+				return nil, fmt.Errorf("%w: UUID of %q is not a string", errInvalidMappingUUIDs, prefix)
+			}
+			if !validMappingIDElement(uuid.Str()) {
+				return nil, fmt.Errorf("%w: UUID %q is not a valid mapping ID", errInvalidMappingUUIDs, uuid.Str())
+			}
+			m.prefixes[prefix] = uuid.Str()
+			if !seen[uuid.Str()] {
+				seen[uuid.Str()] = true
+				m.uuids = append(m.uuids, uuid.Str())
+			}
+		}
+		if len(m.uuids) == 0 {
+			return nil, fmt.Errorf("%w: no UUIDs", errInvalidMappingUUIDs)
+		}
+		sort.Strings(m.uuids)
+		return m, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %s", errInvalidMappingUUIDs, value.Type())
+	}
+}
+
+// candidates returns the UUIDs of the mappings to deobfuscate a class with, in
+// order. With package prefixes, that's the mapping of the longest prefix the
+// class is in, if any. The fallback's candidates come last.
+func (m *mappingSet) candidates(class string) []string {
+	var candidates []string
+	if m.prefixes == nil {
+		candidates = m.uuids
+	} else {
+		longest := -1
+		var candidate string
+		for prefix, uuid := range m.prefixes {
+			if inPackage(class, prefix) && len(prefix) > longest {
+				longest, candidate = len(prefix), uuid
+			}
+		}
+		if longest >= 0 {
+			candidates = []string{candidate}
+		}
+	}
+
+	if m.fallback == nil {
+		return candidates
+	}
+	return append(append([]string{}, candidates...), m.fallback.candidates(class)...)
+}
+
+// all returns the UUIDs of every mapping of the set and its fallback, once each.
+func (m *mappingSet) all() []string {
+	if m.fallback == nil {
+		return m.uuids
+	}
+
+	all := append([]string{}, m.uuids...)
+	for _, uuid := range m.fallback.all() {
+		if !slices.Contains(all, uuid) {
+			all = append(all, uuid)
+		}
+	}
+	return all
+}
+
+// inPackage reports whether a class is in a package, or one of its
+// subpackages. The empty package has every class.
+func inPackage(class, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, ".")
+	return prefix == "" || class == prefix || strings.HasPrefix(class, prefix+".")
+}
@@ -0,0 +1,89 @@
+package proguardprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestNewMappingSet(t *testing.T) {
+	t.Run("single UUID", func(t *testing.T) {
+		m, err := newMappingSet(pcommon.NewValueStr("uuid-1"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"uuid-1"}, m.uuids)
+		assert.Equal(t, []string{"uuid-1"}, m.candidates("a.b"))
+	})
+
+	t.Run("list of UUIDs", func(t *testing.T) {
+		value := pcommon.NewValueSlice()
+		require.NoError(t, value.FromRaw([]any{"uuid-1", "uuid-2"}))
+
+		m, err := newMappingSet(value)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"uuid-1", "uuid-2"}, m.candidates("a.b"))
+	})
+
+	t.Run("UUIDs by package prefix", func(t *testing.T) {
+		value := pcommon.NewValueMap()
+		require.NoError(t, value.Map().FromRaw(map[string]any{
+			"":                  "app",
+			"com.example.sdk":   "sdk",
+			"com.example.sdk.a": "sdk-internal",
+			"com.feature.":      "feature",
+		}))
+
+		m, err := newMappingSet(value)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app", "feature", "sdk", "sdk-internal"}, m.uuids)
+		assert.Equal(t, []string{"app"}, m.candidates("a.b"))
+		assert.Equal(t, []string{"sdk"}, m.candidates("com.example.sdk.b"))
+		assert.Equal(t, []string{"sdk-internal"}, m.candidates("com.example.sdk.a.c"))
+		assert.Equal(t, []string{"feature"}, m.candidates("com.feature.a"))
+		// prefixes are packages, not any prefix of the name
+		assert.Equal(t, []string{"app"}, m.candidates("com.example.sdkx.a"))
+	})
+
+	t.Run("no prefix matches", func(t *testing.T) {
+		value := pcommon.NewValueMap()
+		value.Map().PutStr("com.example.sdk", "sdk")
+
+		m, err := newMappingSet(value)
+		require.NoError(t, err)
+		assert.Nil(t, m.candidates("a.b"))
+	})
+
+	invalid := map[string]pcommon.Value{
+		"empty UUID":     pcommon.NewValueStr(""),
+		"empty list":     pcommon.NewValueSlice(),
+		"empty map":      pcommon.NewValueMap(),
+		"non-string":     pcommon.NewValueInt(1),
+		"path traversal": pcommon.NewValueStr("../../etc/passwd"),
+		"nested UUID":    pcommon.NewValueStr("com.example.app/42"),
+		"non-string UUID": func() pcommon.Value {
+			value := pcommon.NewValueSlice()
+			value.Slice().AppendEmpty().SetInt(1)
+			return value
+		}(),
+	}
+	for name, value := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := newMappingSet(value)
+			assert.ErrorIs(t, err, errInvalidMappingUUIDs)
+		})
+	}
+}
+
+func TestMappingSet_Fallback(t *testing.T) {
+	value := pcommon.NewValueMap()
+	require.NoError(t, value.Map().FromRaw(map[string]any{"com.feature": "feature", "a": "app"}))
+	fallback, err := newMappingSet(value)
+	require.NoError(t, err)
+
+	m := &mappingSet{uuids: []string{"com.example.app/42", "app"}, fallback: fallback}
+	assert.Equal(t, []string{"com.example.app/42", "app", "feature"}, m.all())
+	assert.Equal(t, []string{"com.example.app/42", "app", "feature"}, m.candidates("com.feature.a"))
+	assert.Equal(t, []string{"com.example.app/42", "app"}, m.candidates("java.lang.Thread"))
+	assert.Equal(t, []string{"com.example.app/42", "app"}, m.uuids, "candidates don't modify the set")
+}
@@ -0,0 +1,394 @@
+package proguardprocessor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// R8 mapping information ids, from https://r8.googlesource.com/r8/+/refs/heads/main/doc/retrace.md.
+const (
+	r8SourceFileID      = "sourceFile"
+	r8SynthesizedID     = "com.android.tools.r8.synthesized"
+	r8OutlineID         = "com.android.tools.r8.outline"
+	r8OutlineCallsiteID = "com.android.tools.r8.outlineCallsite"
+	r8RewriteFrameID    = "com.android.tools.r8.rewriteFrame"
+
+	// maxR8MappingLineLength is the longest line of a mapping file that is read
+	// for R8 mapping information. Longer lines are skipped.
+	maxR8MappingLineLength = 1024 * 1024
+)
+
+var (
+	// r8MemberRegex matches a method line of a mapping file.
+	// Capture Groups:
+	// 		1: Start of the obfuscated line range (optional)
+	// 		2: End of the obfuscated line range (optional)
+	// 		3: Original method name, qualified by its class if it was inlined from another class
+	// 		4: Obfuscated method name
+	//
+	// Examples that match:
+	// 		1:2:void onClick(android.view.View):10:11 -> a
+	// 		3:3:void com.example.Other.inlined():5:5 -> a
+	// 		void <init>() -> <init>
+	//
+	r8MemberRegex = regexp.MustCompile(`^\s+(?:(\d+):(\d+):)?\S+\s+([^\s(]+)\(.*\)\S*\s+->\s+(\S+)\s*$`)
+	// r8ThrowsRegex matches a rewriteFrame condition on the thrown exception.
+	r8ThrowsRegex = regexp.MustCompile(`^throws\(L([^;]+);\)$`)
+	// r8RemoveInnerFramesRegex matches a rewriteFrame action.
+	r8RemoveInnerFramesRegex = regexp.MustCompile(`^removeInnerFrames\((\d+)\)$`)
+)
+
+// r8Mapping is the R8 mapping information of a mapping file, and the methods
+// it maps ambiguously, which change how a stack trace is retraced beyond
+// remapping each frame.
+type r8Mapping struct {
+	// sourceFiles are the source file names of original classes.
+	sourceFiles map[string]string
+	// synthesizedClasses and synthesizedMethods are the original classes and
+	// methods, qualified by their class, that the compiler synthesized.
+	synthesizedClasses map[string]bool
+	synthesizedMethods map[string]bool
+	// outlines are the obfuscated methods, qualified by their class, that code
+	// was outlined into.
+	outlines map[string]bool
+	// outlineCallsites and rewriteFrames are keyed by the obfuscated method,
+	// qualified by its class.
+	outlineCallsites map[string][]r8OutlineCallsite
+	rewriteFrames    map[string][]r8RewriteFrame
+	// ambiguous are the obfuscated methods, qualified by their class, that more
+	// than one mapping could apply to at the same line, with all their mappings.
+	ambiguous map[string][]r8MemberMapping
+}
+
+// r8Range is a range of obfuscated line numbers, or every line if it's empty.
+type r8Range struct {
+	start, end int
+}
+
+func (r r8Range) contains(line int) bool {
+	return r.start == 0 && r.end == 0 || line >= r.start && line <= r.end
+}
+
+// r8OutlineCallsite maps the positions in an outline to the lines of the
+// method calling it.
+type r8OutlineCallsite struct {
+	r8Range
+	positions map[int]int
+}
+
+// r8RewriteFrame removes inlined frames when a frame throws an exception.
+type r8RewriteFrame struct {
+	r8Range
+	throws            []string
+	removeInnerFrames int
+}
+
+// r8MemberMapping is one mapping of an obfuscated method: a run of method lines
+// with the same obfuscated line range, the innermost inlined method first.
+type r8MemberMapping struct {
+	r8Range
+	frames int
+}
+
+// r8MappingInformation is a mapping information comment, eg.
+// # {"id":"sourceFile","fileName":"Main.kt"}.
+type r8MappingInformation struct {
+	ID         string         `json:"id"`
+	FileName   string         `json:"fileName"`
+	Positions  map[string]int `json:"positions"`
+	Conditions []string       `json:"conditions"`
+	Actions    []string       `json:"actions"`
+}
+
+// parseR8Mapping reads the R8 mapping information of a mapping file, a line at
+// a time. Each comment applies to the class or method line before it.
+func parseR8Mapping(mapping io.Reader) (*r8Mapping, error) {
+	m := &r8Mapping{
+		sourceFiles:        make(map[string]string),
+		synthesizedClasses: make(map[string]bool),
+		synthesizedMethods: make(map[string]bool),
+		outlines:           make(map[string]bool),
+		outlineCallsites:   make(map[string][]r8OutlineCallsite),
+		rewriteFrames:      make(map[string][]r8RewriteFrame),
+		ambiguous:          make(map[string][]r8MemberMapping),
+	}
+
+	var originalClass, obfuscatedClass, member string
+
+	// the mappings of each method of the current class, and the method and
+	// range of the last method line, which the next one is inlined into if
+	// it has the same range
+	members := make(map[string][]r8MemberMapping)
+	var lastMethod string
+	var lastRange r8Range
+
+	reader := bufio.NewReaderSize(mapping, 64*1024)
+	for {
+		line, err := readMappingLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read R8 mapping information: %w", err)
+		}
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			// only JSON comments are mapping information
+			var info r8MappingInformation
+			if err := json.Unmarshal([]byte(strings.TrimSpace(trimmed[1:])), &info); err != nil {
+				continue
+			}
+			if member == "" {
+				m.addClassInformation(originalClass, info)
+			} else {
+				m.addMemberInformation(originalClass, obfuscatedClass, member, info)
+			}
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			// class lines are "original -> obfuscated:"
+			original, obfuscated, ok := strings.Cut(strings.TrimSuffix(trimmed, ":"), " -> ")
+			if ok {
+				m.addAmbiguousMembers(obfuscatedClass, members)
+				clear(members)
+				originalClass, obfuscatedClass = original, obfuscated
+			}
+			member, lastMethod = "", ""
+		default:
+			member = line
+
+			method, lines, ok := parseMemberLine(trimmed)
+			if !ok {
+				continue
+			}
+			mappings := members[method]
+			if method == lastMethod && lines == lastRange && lines != (r8Range{}) {
+				mappings[len(mappings)-1].frames++
+			} else {
+				members[method] = append(mappings, r8MemberMapping{r8Range: lines, frames: 1})
+			}
+			lastMethod, lastRange = method, lines
+		}
+	}
+	m.addAmbiguousMembers(obfuscatedClass, members)
+
+	return m, nil
+}
+
+// readMappingLine reads the next line of a mapping file, without its line
+// ending. Lines longer than maxR8MappingLineLength, which symbolic reads but
+// aren't worth holding in memory for their mapping information, are skipped.
+func readMappingLine(r *bufio.Reader) (string, error) {
+	for {
+		var line []byte
+		tooLong := false
+
+		for {
+			chunk, isPrefix, err := r.ReadLine()
+			if err != nil {
+				return "", err
+			}
+			if !tooLong && len(line)+len(chunk) > maxR8MappingLineLength {
+				tooLong, line = true, nil
+			}
+			if !tooLong {
+				line = append(line, chunk...)
+			}
+			if !isPrefix {
+				break
+			}
+		}
+
+		if !tooLong {
+			return string(line), nil
+		}
+	}
+}
+
+// parseMemberLine returns the obfuscated name and line range of a method line,
+// without the cost of matching every line of the mapping file with a regex.
+func parseMemberLine(line string) (string, r8Range, bool) {
+	i := strings.LastIndex(line, " -> ")
+	if i < 0 || !strings.Contains(line[:i], "(") {
+		// a field
+		return "", r8Range{}, false
+	}
+	method := strings.TrimSpace(line[i+len(" -> "):])
+
+	// only the obfuscated range, before the return type, has no spaces
+	prefix, _, _ := strings.Cut(line, " ")
+	start, end, ok := strings.Cut(prefix, ":")
+	if !ok {
+		return method, r8Range{}, true
+	}
+	end, _, _ = strings.Cut(end, ":")
+
+	var lines r8Range
+	var errStart, errEnd error
+	lines.start, errStart = strconv.Atoi(start)
+	lines.end, errEnd = strconv.Atoi(end)
+	if errStart != nil || errEnd != nil {
+		return method, r8Range{}, true
+	}
+	return method, lines, true
+}
+
+// addAmbiguousMembers keeps the methods of a class with mappings whose line
+// ranges overlap, since those can't be told apart.
+func (m *r8Mapping) addAmbiguousMembers(obfuscatedClass string, members map[string][]r8MemberMapping) {
+	for method, mappings := range members {
+		if overlapping(mappings) {
+			m.ambiguous[obfuscatedClass+"."+method] = mappings
+		}
+	}
+}
+
+func overlapping(mappings []r8MemberMapping) bool {
+	for i, a := range mappings {
+		for _, b := range mappings[i+1:] {
+			if a.r8Range == (r8Range{}) || b.r8Range == (r8Range{}) || a.start <= b.end && b.start <= a.end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *r8Mapping) addClassInformation(class string, info r8MappingInformation) {
+	switch info.ID {
+	case r8SourceFileID:
+		m.sourceFiles[class] = info.FileName
+	case r8SynthesizedID:
+		m.synthesizedClasses[class] = true
+	}
+}
+
+func (m *r8Mapping) addMemberInformation(originalClass, obfuscatedClass, member string, info r8MappingInformation) {
+	matches := r8MemberRegex.FindStringSubmatch(member)
+	if matches == nil {
+		return
+	}
+
+	var lines r8Range
+	lines.start, _ = strconv.Atoi(matches[1])
+	lines.end, _ = strconv.Atoi(matches[2])
+
+	// methods inlined from another class are qualified by it
+	originalMethod := matches[3]
+	if !strings.Contains(originalMethod, ".") {
+		originalMethod = originalClass + "." + originalMethod
+	}
+	obfuscatedMethod := obfuscatedClass + "." + matches[4]
+
+	switch info.ID {
+	case r8SynthesizedID:
+		m.synthesizedMethods[originalMethod] = true
+	case r8OutlineID:
+		m.outlines[obfuscatedMethod] = true
+	case r8OutlineCallsiteID:
+		callsite := r8OutlineCallsite{r8Range: lines, positions: make(map[int]int)}
+		for position, line := range info.Positions {
+			if p, err := strconv.Atoi(position); err == nil {
+				callsite.positions[p] = line
+			}
+		}
+		m.outlineCallsites[obfuscatedMethod] = append(m.outlineCallsites[obfuscatedMethod], callsite)
+	case r8RewriteFrameID:
+		rewrite := r8RewriteFrame{r8Range: lines}
+		for _, condition := range info.Conditions {
+			if matches := r8ThrowsRegex.FindStringSubmatch(condition); matches != nil {
+				rewrite.throws = append(rewrite.throws, strings.ReplaceAll(matches[1], "/", "."))
+			}
+		}
+		for _, action := range info.Actions {
+			if matches := r8RemoveInnerFramesRegex.FindStringSubmatch(action); matches != nil {
+				rewrite.removeInnerFrames, _ = strconv.Atoi(matches[1])
+			}
+		}
+		m.rewriteFrames[obfuscatedMethod] = append(m.rewriteFrames[obfuscatedMethod], rewrite)
+	}
+}
+
+// isOutline reports whether an obfuscated method is an outline.
+func (m *r8Mapping) isOutline(class, method string) bool {
+	return m != nil && m.outlines[class+"."+method]
+}
+
+// outlineCallsitePosition returns the line of an outline's callsite that the
+// given position in the outline was outlined from.
+func (m *r8Mapping) outlineCallsitePosition(class, method string, line, position int) (int, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	for _, callsite := range m.outlineCallsites[class+"."+method] {
+		if callsite.contains(line) {
+			l, ok := callsite.positions[position]
+			return l, ok
+		}
+	}
+	return 0, false
+}
+
+// alternatives returns the number of frames of each mapping that applies to an
+// obfuscated frame, in the order they're remapped, if more than one does.
+func (m *r8Mapping) alternatives(class, method string, line int) []int {
+	if m == nil {
+		return nil
+	}
+
+	var frames []int
+	for _, mapping := range m.ambiguous[class+"."+method] {
+		if mapping.contains(line) {
+			frames = append(frames, mapping.frames)
+		}
+	}
+	if len(frames) < 2 {
+		return nil
+	}
+	return frames
+}
+
+// isSynthesized reports whether an original method, or its class, was
+// synthesized by the compiler.
+func (m *r8Mapping) isSynthesized(class, method string) bool {
+	return m != nil && (m.synthesizedClasses[class] || m.synthesizedMethods[class+"."+method])
+}
+
+// innerFramesToRemove returns how many of the innermost inlined frames of an
+// obfuscated frame to remove when it throws the given exception.
+func (m *r8Mapping) innerFramesToRemove(class, method string, line int, exceptionTypes ...string) int {
+	if m == nil {
+		return 0
+	}
+
+	for _, rewrite := range m.rewriteFrames[class+"."+method] {
+		if !rewrite.contains(line) {
+			continue
+		}
+		for _, throws := range rewrite.throws {
+			for _, exceptionType := range exceptionTypes {
+				if throws == exceptionType {
+					return rewrite.removeInnerFrames
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// sourceFile returns the source file name of an original class, if the
+// mapping has one.
+func (m *r8Mapping) sourceFile(class string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	sourceFile, ok := m.sourceFiles[class]
+	return sourceFile, ok
+}
@@ -0,0 +1,142 @@
+package proguardprocessor
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// r8TestMapping has each kind of R8 mapping information, following the
+// examples in R8's retrace documentation.
+const r8TestMapping = `# {"id":"com.android.tools.r8.mapping","version":"2.0"}
+outline.Class -> a:
+    1:2:int outline():0:1 -> a
+# {"id":"com.android.tools.r8.outline"}
+com.example.Main -> b:
+# {"id":"sourceFile","fileName":"Main.kt"}
+    4:4:int outlineCaller(int):98:98 -> s
+    5:5:int outlineCaller(int):24:24 -> s
+    27:27:int outlineCaller(int):0:0 -> s
+    # {"id":"com.android.tools.r8.outlineCallsite","positions":{"1":4,"2":5},"outline":"La;a()I"}
+    1:1:void com.example.Util.check(java.lang.Object):10:10 -> t
+    # {"id":"com.android.tools.r8.rewriteFrame","conditions":["throws(Ljava/lang/NullPointerException;)"],"actions":["removeInnerFrames(1)"]}
+    1:1:void run():20:20 -> t
+    2:2:void access$run(com.example.Main):0:0 -> u
+    # {"id":"com.android.tools.r8.synthesized"}
+    2:2:void main():40:40 -> u
+com.example.Main$$ExternalSyntheticLambda0 -> c:
+# {"id":"com.android.tools.r8.synthesized"}
+    1:1:void run():0:0 -> run
+`
+
+func TestParseR8Mapping_LongLines(t *testing.T) {
+	long := "# " + strings.Repeat("x", 2*maxR8MappingLineLength) + "\n"
+	m, err := parseR8Mapping(strings.NewReader(long + r8TestMapping + long[:len(long)-1]))
+	require.NoError(t, err)
+
+	sourceFile, ok := m.sourceFile("com.example.Main")
+	assert.True(t, ok)
+	assert.Equal(t, "Main.kt", sourceFile)
+	assert.True(t, m.isSynthesized("com.example.Main$$ExternalSyntheticLambda0", "run"))
+
+	line, err := readMappingLine(bufio.NewReader(strings.NewReader("no line ending")))
+	assert.NoError(t, err)
+	assert.Equal(t, "no line ending", line)
+}
+
+func TestParseR8Mapping(t *testing.T) {
+	m, err := parseR8Mapping(strings.NewReader(r8TestMapping))
+	require.NoError(t, err)
+
+	sourceFile, ok := m.sourceFile("com.example.Main")
+	assert.True(t, ok)
+	assert.Equal(t, "Main.kt", sourceFile)
+	_, ok = m.sourceFile("outline.Class")
+	assert.False(t, ok)
+
+	assert.True(t, m.isOutline("a", "a"))
+	assert.False(t, m.isOutline("b", "s"))
+
+	line, ok := m.outlineCallsitePosition("b", "s", 27, 1)
+	assert.True(t, ok)
+	assert.Equal(t, 4, line)
+	line, ok = m.outlineCallsitePosition("b", "s", 27, 2)
+	assert.True(t, ok)
+	assert.Equal(t, 5, line)
+	_, ok = m.outlineCallsitePosition("b", "s", 4, 1)
+	assert.False(t, ok)
+
+	assert.True(t, m.isSynthesized("com.example.Main", "access$run"))
+	assert.True(t, m.isSynthesized("com.example.Main$$ExternalSyntheticLambda0", "run"))
+	assert.False(t, m.isSynthesized("com.example.Main", "main"))
+
+	assert.Equal(t, 1, m.innerFramesToRemove("b", "t", 1, "java.lang.NullPointerException"))
+	assert.Equal(t, 0, m.innerFramesToRemove("b", "t", 1, "java.lang.IllegalStateException"))
+	assert.Equal(t, 0, m.innerFramesToRemove("b", "t", 2, "java.lang.NullPointerException"))
+}
+
+func TestR8MappingNil(t *testing.T) {
+	var m *r8Mapping
+
+	assert.False(t, m.isOutline("a", "a"))
+	assert.False(t, m.isSynthesized("com.example.Main", "main"))
+	assert.Equal(t, 0, m.innerFramesToRemove("b", "t", 1, "java.lang.NullPointerException"))
+	_, ok := m.outlineCallsitePosition("b", "s", 27, 1)
+	assert.False(t, ok)
+	_, ok = m.sourceFile("com.example.Main")
+	assert.False(t, ok)
+}
+
+func TestR8MappingAlternatives(t *testing.T) {
+	m, err := parseR8Mapping(strings.NewReader(`com.example.Main -> a:
+    void start() -> a
+    void stop(int) -> a
+    1:1:void inlined():10:10 -> b
+    1:1:void caller():20 -> b
+    2:2:void other():30:30 -> b
+    3:3:void first():40:40 -> c
+    4:4:void second():50:50 -> c
+    3:4:void merged():60:61 -> c
+    1:1:void inlinedAgain():70:70 -> c
+    1:1:void callerAgain():80:80 -> c
+    java.lang.String field -> a
+com.example.Other -> b:
+    void start() -> a
+`))
+	require.NoError(t, err)
+
+	// line info was stripped, so either method could have been running
+	assert.Equal(t, []int{1, 1}, m.alternatives("a", "a", 5))
+	// inlined frames have the same range, they aren't alternatives
+	assert.Nil(t, m.alternatives("a", "b", 1))
+	assert.Equal(t, []int{1, 1}, m.alternatives("a", "c", 3))
+	assert.Equal(t, []int{1, 1}, m.alternatives("a", "c", 4))
+	assert.Nil(t, m.alternatives("a", "c", 1))
+	// only the class's own methods are alternatives
+	assert.Nil(t, m.alternatives("b", "a", 5))
+}
+
+func TestParseMemberLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		method string
+		lines  r8Range
+		ok     bool
+	}{
+		{line: "1:2:void onClick(android.view.View):10:11 -> a", method: "a", lines: r8Range{1, 2}, ok: true},
+		{line: "3:3:void com.example.Other.inlined():5:5 -> b", method: "b", lines: r8Range{3, 3}, ok: true},
+		{line: "void <init>() -> <init>", method: "<init>", ok: true},
+		{line: "void start():10:10 -> c", method: "c", ok: true},
+		{line: "java.lang.String field -> a"},
+	}
+
+	for _, tt := range tests {
+		method, lines, ok := parseMemberLine(tt.line)
+		assert.Equal(t, tt.ok, ok, tt.line)
+		assert.Equal(t, tt.method, method, tt.line)
+		assert.Equal(t, tt.lines, lines, tt.line)
+	}
+}
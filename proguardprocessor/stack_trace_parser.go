@@ -19,22 +19,68 @@ type stackFrame struct {
 	method     string
 	line       int
 	sourceFile string
+	// indent is the whitespace before a frame of a thread dump, which isn't
+	// always a tab.
+	indent string
 }
 
 // element represents a single element in a stack trace.
-// Either a frame or a raw line can be stored. Not both at the same time.
+// Either a frame, a lock or a raw line can be stored. Not more than one at the same time.
 type element struct {
 	frame *stackFrame
+	lock  *lockLine
 	line  string
+	// coroutineBoundary is set for a raw line that separates the frames of a
+	// coroutine from the frames of the coroutine that resumed or created it.
+	coroutineBoundary bool
 }
 
-// stackTrace represents the parsed stack trace.
-type stackTrace struct {
+// coroutineBoundaryPackage is the package of the frames kotlinx.coroutines adds
+// to mark coroutine boundaries, eg. _COROUTINE._BOUNDARY._(CoroutineDebugging.kt:46).
+const coroutineBoundaryPackage = "_COROUTINE."
+
+// causeKind is how an exception is enclosed by another in a stack trace.
+type causeKind string
+
+const (
+	causeKindCausedBy   causeKind = "Caused by"
+	causeKindSuppressed causeKind = "Suppressed"
+)
+
+// exception represents a single exception in a stack trace, with its frames.
+type exception struct {
 	exceptionType    string
 	exceptionMessage string
 	elements         []element
 }
 
+// cause represents an exception printed after the frames of the exception
+// enclosing it, as either its cause or one of its suppressed exceptions.
+type cause struct {
+	exception
+
+	kind causeKind
+	// hasMessage is false when the header has no message, not even an empty one.
+	hasMessage bool
+	// indent is the whitespace before the header. The frames of a cause are
+	// indented one tab further.
+	indent string
+	// enclosing is the index in causes of the exception this is a cause or
+	// suppressed exception of, or -1 for the top level exception.
+	enclosing int
+	// framesInCommon is the number of trailing frames shared with the enclosing
+	// exception, which are omitted as "... N more".
+	framesInCommon int
+}
+
+// stackTrace represents the parsed stack trace: the top level exception, and
+// its causes and suppressed exceptions in the order they're printed.
+type stackTrace struct {
+	exception
+
+	causes []*cause
+}
+
 // Regex patterns for parsing stack traces.
 var (
 	// exceptionHeaderRegex matches the first line of a stack trace to extract
@@ -64,6 +110,36 @@ var (
 	// 		at com.example.Class.method(File.java)
 	//
 	stackFrameRegex = regexp.MustCompile(`^\s*at\s+([^\s(]+)\.([^\s.(]+)\(([^:)]+)(?::(-?\d+))?\)\s*$`)
+	// causeHeaderRegex matches the header of a cause or suppressed exception.
+	// Capture Groups:
+	// 		1: Indentation
+	// 		2: "Caused by" or "Suppressed"
+	// 		3: Exception type
+	// 		4: Exception message (optional)
+	//
+	// Examples that match:
+	// 		Caused by: java.io.IOException: IO error
+	// 		Caused by: java.lang.NullPointerException
+	// 			Suppressed: a.b.c: closing failed
+	//
+	causeHeaderRegex = regexp.MustCompile(`^(\s*)(Caused by|Suppressed):\s*([^\s:]+)(?:\s*:\s*(.*))?$`)
+	// framesInCommonRegex matches the line that replaces the frames a cause has
+	// in common with its enclosing exception.
+	// Capture Groups:
+	// 		1: Number of frames in common
+	//
+	// Examples that match:
+	// 		... 12 more
+	//
+	framesInCommonRegex = regexp.MustCompile(`^\s*\.\.\.\s+(\d+)\s+more\s*$`)
+	// coroutineBoundaryRegex matches the lines older versions of kotlinx.coroutines
+	// print between the frames of coroutines.
+	//
+	// Examples that match:
+	// 		(Coroutine boundary)
+	// 		(Coroutine creation stacktrace)
+	//
+	coroutineBoundaryRegex = regexp.MustCompile(`^\s*\(Coroutine (?:boundary|creation stacktrace)\)\s*$`)
 )
 
 // parseStackTrace parses a raw stack trace string into structured components.
@@ -80,7 +156,7 @@ func parseStackTrace(stackTraceStr string) (*stackTrace, error) {
 	}
 
 	result := &stackTrace{
-		elements: make([]element, 0),
+		exception: exception{elements: make([]element, 0)},
 	}
 
 	// Parse the first line to extract exception type and message
@@ -93,7 +169,10 @@ func parseStackTrace(stackTraceStr string) (*stackTrace, error) {
 		return nil, errInvalidStackTrace
 	}
 
-	// Parse each subsequent line as a stack frame
+	// Parse each subsequent line as a stack frame, or the header of a cause
+	// that the following frames belong to
+	current := &result.exception
+	var currentCause *cause
 	for i := 1; i < len(lines); i++ {
 		line := lines[i]
 
@@ -102,24 +181,115 @@ func parseStackTrace(stackTraceStr string) (*stackTrace, error) {
 			continue
 		}
 
+		if c := parseCauseHeader(line); c != nil {
+			c.enclosing = result.enclosingIndex(c)
+			result.causes = append(result.causes, c)
+			current, currentCause = &c.exception, c
+			continue
+		}
+
+		if matches := framesInCommonRegex.FindStringSubmatch(line); matches != nil && currentCause != nil {
+			currentCause.framesInCommon, _ = strconv.Atoi(matches[1])
+			continue
+		}
+
+		if coroutineBoundaryRegex.MatchString(line) {
+			current.elements = append(current.elements, element{line: line, coroutineBoundary: true})
+			continue
+		}
+
 		// Try to parse the line as a stack frame, adding as a frame if successful,
 		// if not, add the raw line to preserve it
 		frame := parseStackFrame(line)
 		if frame != nil {
-			result.elements = append(result.elements, element{frame: frame})
+			current.elements = append(current.elements, element{frame: frame})
 		} else {
-			result.elements = append(result.elements, element{line: line})
+			current.elements = append(current.elements, element{line: line})
 		}
 	}
 
 	// If we didn't parse any frames, return an error
-	if len(result.elements) == 0 {
+	if len(result.elements) == 0 && len(result.causes) == 0 {
 		return nil, errNoFramesParsed
 	}
 
 	return result, nil
 }
 
+// parseCauseHeader parses the header of a cause or suppressed exception.
+// Returns nil if the line isn't one.
+func parseCauseHeader(line string) *cause {
+	matches := causeHeaderRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+
+	return &cause{
+		exception: exception{
+			exceptionType:    matches[3],
+			exceptionMessage: matches[4],
+			elements:         make([]element, 0),
+		},
+		kind:       causeKind(matches[2]),
+		hasMessage: strings.Contains(line[len(matches[1])+len(matches[2])+1:], ":"),
+		indent:     matches[1],
+	}
+}
+
+// enclosingIndex finds the exception a new cause belongs to from its
+// indentation. A cause is printed at the indentation of the exception it's
+// the cause of, and a suppressed exception one tab further than the exception
+// it was suppressed by.
+func (st *stackTrace) enclosingIndex(c *cause) int {
+	for i := len(st.causes) - 1; i >= 0; i-- {
+		indent := len(st.causes[i].indent)
+		if (c.kind == causeKindCausedBy && indent <= len(c.indent)) ||
+			(c.kind == causeKindSuppressed && indent < len(c.indent)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// enclosingException returns the exception a cause is enclosed by.
+func (st *stackTrace) enclosingException(c *cause) *exception {
+	if c.enclosing < 0 {
+		return &st.exception
+	}
+	return &st.causes[c.enclosing].exception
+}
+
+// expandFramesInCommon replaces the "... N more" of each cause with the
+// trailing frames of the exception enclosing it. Causes are expanded in order,
+// so an enclosing exception's own frames in common are already expanded.
+func (st *stackTrace) expandFramesInCommon() {
+	for _, c := range st.causes {
+		if c.framesInCommon == 0 {
+			continue
+		}
+
+		frames := make([]element, 0)
+		for _, e := range st.enclosingException(c).elements {
+			if e.frame != nil {
+				frames = append(frames, e)
+			}
+		}
+
+		n := c.framesInCommon
+		if n > len(frames) {
+			n = len(frames)
+		}
+		c.elements = append(c.elements, frames[len(frames)-n:]...)
+		c.framesInCommon = 0
+	}
+}
+
+// isCoroutineBoundary reports whether a frame's class is one of the markers
+// kotlinx.coroutines adds at coroutine boundaries, rather than app code.
+func isCoroutineBoundary(class string) bool {
+	return strings.HasPrefix(class, coroutineBoundaryPackage)
+}
+
 // parseStackFrame parses a single stack frame string into a stackFrame struct.
 // Returns nil if the line cannot be parsed as a valid stack frame.
 func parseStackFrame(line string) *stackFrame {
@@ -143,7 +313,8 @@ func parseStackFrame(line string) *stackFrame {
 
 	// Handle special source info values
 	// Based on https://developer.android.com/reference/java/lang/StackTraceElement#StackTraceElement(java.lang.String,%20java.lang.String,%20java.lang.String,%20int)
-	if sourceInfo == "Native Method" {
+	// ART prints "Native method" in thread dumps
+	if strings.EqualFold(sourceInfo, "Native Method") {
 		frame.line = -2 // Android convention for native methods
 	} else if lineNumStr != "" { // Parse line number if present
 		if lineNum, err := strconv.Atoi(lineNumStr); err == nil {
@@ -14,6 +14,7 @@ func TestParseStackTrace(t *testing.T) {
 		expectedType     string
 		expectedMessage  string
 		expectedElements []element
+		expectedCauses   []*cause
 		expectError      error
 	}{
 		{
@@ -101,7 +102,7 @@ func TestParseStackTrace(t *testing.T) {
 			},
 		},
 		{
-			name: "Stack trace with Caused by (should preserve all lines)",
+			name: "Stack trace with Caused by",
 			input: `java.lang.RuntimeException: Error
 	at com.example.MyClass.method(MyClass.java:100)
 Caused by: java.lang.IOException: IO error
@@ -110,8 +111,89 @@ Caused by: java.lang.IOException: IO error
 			expectedMessage: "Error",
 			expectedElements: []element{
 				{frame: &stackFrame{class: "com.example.MyClass", method: "method", sourceFile: "MyClass.java", line: 100}},
-				{line: "Caused by: java.lang.IOException: IO error"},
-				{frame: &stackFrame{class: "com.example.IOClass", method: "read", sourceFile: "IOClass.java", line: 50}},
+			},
+			expectedCauses: []*cause{
+				{
+					exception: exception{
+						exceptionType:    "java.lang.IOException",
+						exceptionMessage: "IO error",
+						elements: []element{
+							{frame: &stackFrame{class: "com.example.IOClass", method: "read", sourceFile: "IOClass.java", line: 50}},
+						},
+					},
+					kind:       causeKindCausedBy,
+					hasMessage: true,
+					enclosing:  -1,
+				},
+			},
+		},
+		{
+			name: "Stack trace with nested causes, suppressed exceptions and frames in common",
+			input: `java.lang.RuntimeException: Error
+	at a.b.c(SourceFile:10)
+	at a.b.d(SourceFile:20)
+	Suppressed: a.e: close failed
+		at a.e.f(SourceFile:30)
+		... 1 more
+	Caused by: a.g
+		at a.g.h(SourceFile:40)
+		... 2 more
+Caused by: java.lang.IllegalStateException:
+	at a.i.j(SourceFile:50)
+	... 1 more`,
+			expectedType:    "java.lang.RuntimeException",
+			expectedMessage: "Error",
+			expectedCauses: []*cause{
+				{
+					exception: exception{
+						exceptionType:    "a.e",
+						exceptionMessage: "close failed",
+						elements:         []element{{frame: &stackFrame{class: "a.e", method: "f", sourceFile: "SourceFile", line: 30}}},
+					},
+					kind:           causeKindSuppressed,
+					hasMessage:     true,
+					indent:         "\t",
+					enclosing:      -1,
+					framesInCommon: 1,
+				},
+				{
+					exception: exception{
+						exceptionType: "a.g",
+						elements:      []element{{frame: &stackFrame{class: "a.g", method: "h", sourceFile: "SourceFile", line: 40}}},
+					},
+					kind:           causeKindCausedBy,
+					indent:         "\t",
+					enclosing:      0,
+					framesInCommon: 2,
+				},
+				{
+					exception: exception{
+						exceptionType: "java.lang.IllegalStateException",
+						elements:      []element{{frame: &stackFrame{class: "a.i", method: "j", sourceFile: "SourceFile", line: 50}}},
+					},
+					kind:           causeKindCausedBy,
+					hasMessage:     true,
+					enclosing:      -1,
+					framesInCommon: 1,
+				},
+			},
+		},
+		{
+			name: "Kotlin stack trace with coroutine boundaries",
+			input: `java.lang.IllegalStateException: boom
+	at a.b.c(SourceFile:10)
+	at _COROUTINE._BOUNDARY._(CoroutineDebugging.kt:46)
+	at a.b$d.invokeSuspend(SourceFile:20)
+	(Coroutine boundary)
+	at a.b.e(SourceFile:30)`,
+			expectedType:    "java.lang.IllegalStateException",
+			expectedMessage: "boom",
+			expectedElements: []element{
+				{frame: &stackFrame{class: "a.b", method: "c", sourceFile: "SourceFile", line: 10}},
+				{frame: &stackFrame{class: "_COROUTINE._BOUNDARY", method: "_", sourceFile: "CoroutineDebugging.kt", line: 46}},
+				{frame: &stackFrame{class: "a.b$d", method: "invokeSuspend", sourceFile: "SourceFile", line: 20}},
+				{line: "\t(Coroutine boundary)", coroutineBoundary: true},
+				{frame: &stackFrame{class: "a.b", method: "e", sourceFile: "SourceFile", line: 30}},
 			},
 		},
 		{
@@ -208,10 +290,41 @@ Caused by: java.lang.IOException: IO error
 			if tt.expectedElements != nil {
 				assert.Equal(t, tt.expectedElements, result.elements)
 			}
+			if tt.expectedCauses != nil {
+				assert.Equal(t, tt.expectedCauses, result.causes)
+			}
 		})
 	}
 }
 
+func TestExpandFramesInCommon(t *testing.T) {
+	result, err := parseStackTrace(`java.lang.RuntimeException: Error
+	at a.b.c(SourceFile:10)
+	at a.b.d(SourceFile:20)
+Caused by: a.e: Nested
+	at a.e.f(SourceFile:30)
+	... 1 more
+Caused by: a.g: Root
+	at a.g.h(SourceFile:40)
+	... 2 more`)
+	require.NoError(t, err)
+
+	result.expandFramesInCommon()
+
+	require.Len(t, result.causes, 2)
+	assert.Equal(t, []element{
+		{frame: &stackFrame{class: "a.e", method: "f", sourceFile: "SourceFile", line: 30}},
+		{frame: &stackFrame{class: "a.b", method: "d", sourceFile: "SourceFile", line: 20}},
+	}, result.causes[0].elements)
+	// frames in common with a cause include the ones it has in common with its own enclosing exception
+	assert.Equal(t, []element{
+		{frame: &stackFrame{class: "a.g", method: "h", sourceFile: "SourceFile", line: 40}},
+		{frame: &stackFrame{class: "a.e", method: "f", sourceFile: "SourceFile", line: 30}},
+		{frame: &stackFrame{class: "a.b", method: "d", sourceFile: "SourceFile", line: 20}},
+	}, result.causes[1].elements)
+	assert.Zero(t, result.causes[1].framesInCommon)
+}
+
 func TestParseStackFrame(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -16,18 +16,24 @@ import (
 )
 
 type store struct {
-	fetch  func(ctx context.Context, key string) ([]byte, error)
+	fetch  func(ctx context.Context, key string) (io.ReadCloser, error)
 	logger *zap.Logger
 	prefix string
 }
 
-func (s *store) GetProguardMapping(ctx context.Context, uuid string) ([]byte, error) {
-	fileName := fmt.Sprintf("%s.txt", uuid)
-	key := filepath.Join(s.prefix, fileName)
+// GetProguardMapping opens the mapping with the given ID, which is either the
+// UUID of the build or its application ID and version code. The mapping is
+// streamed rather than read into memory, as it can be hundreds of megabytes.
+func (s *store) GetProguardMapping(ctx context.Context, id string) (io.ReadCloser, error) {
+	key, err := mappingKey(id)
+	if err != nil {
+		return nil, err
+	}
+	key = filepath.Join(s.prefix, key)
 
 	s.logger.Debug("Fetching proguard mapping", zap.String("key", key))
 
-	data, err := s.fetch(ctx, key)
+	r, err := s.fetch(ctx, key)
 
 	if err != nil {
 		s.logger.Error("Failed to fetch proguard mapping", zap.String("key", key), zap.Error(err))
@@ -36,7 +42,7 @@ func (s *store) GetProguardMapping(ctx context.Context, uuid string) ([]byte, er
 
 	s.logger.Debug("Successfully fetched proguard mapping", zap.String("key", key))
 
-	return data, nil
+	return r, nil
 }
 
 func newFileStore(_ context.Context, logger *zap.Logger, cfg *LocalStoreConfiguration) (*store, error) {
@@ -45,8 +51,8 @@ func newFileStore(_ context.Context, logger *zap.Logger, cfg *LocalStoreConfigur
 	}
 
 	return &store{
-		fetch: func(ctx context.Context, key string) ([]byte, error) {
-			return os.ReadFile(key)
+		fetch: func(ctx context.Context, key string) (io.ReadCloser, error) {
+			return os.Open(key)
 		},
 		logger: logger,
 		prefix: cfg.Path,
@@ -73,7 +79,7 @@ func newS3Store(ctx context.Context, logger *zap.Logger, cfg *S3StoreConfigurati
 	client := s3.NewFromConfig(awsConfig)
 
 	return &store{
-		fetch: func(ctx context.Context, key string) ([]byte, error) {
+		fetch: func(ctx context.Context, key string) (io.ReadCloser, error) {
 			key = strings.TrimPrefix(key, "/")
 
 			result, err := client.GetObject(ctx, &s3.GetObjectInput{
@@ -85,9 +91,7 @@ func newS3Store(ctx context.Context, logger *zap.Logger, cfg *S3StoreConfigurati
 				return nil, err
 			}
 
-			defer result.Body.Close()
-
-			return io.ReadAll(result.Body)
+			return result.Body, nil
 		},
 		logger: logger,
 		prefix: cfg.Prefix,
@@ -108,7 +112,7 @@ func newGCSStore(ctx context.Context, logger *zap.Logger, cfg *GCSStoreConfigura
 	bucket := client.Bucket(cfg.BucketName)
 
 	return &store{
-		fetch: func(ctx context.Context, key string) ([]byte, error) {
+		fetch: func(ctx context.Context, key string) (io.ReadCloser, error) {
 			// GCS keys can't start with a slash
 			key = strings.TrimPrefix(key, "/")
 
@@ -118,9 +122,7 @@ func newGCSStore(ctx context.Context, logger *zap.Logger, cfg *GCSStoreConfigura
 				return nil, err
 			}
 
-			defer r.Close()
-
-			return io.ReadAll(r)
+			return r, nil
 		},
 		logger: logger,
 		prefix: cfg.Prefix,
@@ -0,0 +1,65 @@
+package proguardprocessor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// storeLayoutUUID stores a mapping by the UUID injected into the build:
+	// <uuid>.txt.
+	storeLayoutUUID = "uuid"
+	// storeLayoutApplicationVersion stores a mapping by the application ID and
+	// version code of the build, for builds without a UUID:
+	// <applicationId>/<versionCode>/mapping.txt.
+	storeLayoutApplicationVersion = "application_version"
+)
+
+var errInvalidMappingID = errors.New("invalid proguard mapping ID")
+
+// applicationVersionID is the ID of the mapping of an application ID and
+// version code. UUIDs never have a slash, so it can't be mistaken for one.
+func applicationVersionID(applicationID, versionCode string) string {
+	return applicationID + "/" + versionCode
+}
+
+// mappingKey resolves the key of a mapping, relative to the store prefix, from
+// its ID: a UUID, or an application ID and version code. IDs come from the
+// attributes of log records, so any that aren't made of valid key elements
+// are rejected rather than escaping the prefix, eg. with "..".
+func mappingKey(id string) (string, error) {
+	parts := strings.Split(id, "/")
+	for _, part := range parts {
+		if !validMappingIDElement(part) {
+			return "", fmt.Errorf("%w: %q", errInvalidMappingID, id)
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		return fmt.Sprintf("%s.txt", id), nil
+	case 2:
+		return filepath.Join(parts[0], parts[1], "mapping.txt"), nil
+	default:
+		return "", fmt.Errorf("%w: %q", errInvalidMappingID, id)
+	}
+}
+
+// validMappingIDElement reports whether a UUID, application ID or version code
+// can be a single element of a store key.
+func validMappingIDElement(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
+
+// validateStoreLayouts checks that every layout is supported.
+func validateStoreLayouts(layouts []string) error {
+	for _, layout := range layouts {
+		if layout != storeLayoutUUID && layout != storeLayoutApplicationVersion {
+			return fmt.Errorf("unknown proguard store layout %q, must be one of %q or %q",
+				layout, storeLayoutUUID, storeLayoutApplicationVersion)
+		}
+	}
+	return nil
+}
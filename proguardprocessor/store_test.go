@@ -0,0 +1,69 @@
+package proguardprocessor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestStoreLayouts(t *testing.T) {
+	key, err := mappingKey("6f5d5a3e-1c2b-4d3a-9e8f-7a6b5c4d3e2f")
+	assert.NoError(t, err)
+	assert.Equal(t, "6f5d5a3e-1c2b-4d3a-9e8f-7a6b5c4d3e2f.txt", key)
+
+	key, err = mappingKey(applicationVersionID("com.example.app", "42"))
+	assert.NoError(t, err)
+	assert.Equal(t, "com.example.app/42/mapping.txt", key)
+
+	for _, id := range []string{
+		"../../etc/passwd",
+		"..",
+		applicationVersionID("..", ".."),
+		applicationVersionID("com.example.app", "../../other.app/42"),
+		applicationVersionID("", "42"),
+		`..\..\secrets`,
+	} {
+		_, err := mappingKey(id)
+		assert.ErrorIs(t, err, errInvalidMappingID, id)
+	}
+
+	assert.NoError(t, validateStoreLayouts([]string{storeLayoutApplicationVersion, storeLayoutUUID}))
+	assert.Error(t, validateStoreLayouts([]string{"build_id"}))
+}
+
+func TestFileStore_GetProguardMapping(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "com.example.app", "42"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "com.example.app", "42", "mapping.txt"), []byte("by version"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "uuid-1.txt"), []byte("by uuid"), 0o644))
+
+	s, err := newFileStore(context.Background(), zaptest.NewLogger(t), &LocalStoreConfiguration{Path: dir})
+	require.NoError(t, err)
+
+	read := func(id string) string {
+		r, err := s.GetProguardMapping(context.Background(), id)
+		require.NoError(t, err)
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	assert.Equal(t, "by uuid", read("uuid-1"))
+	assert.Equal(t, "by version", read(applicationVersionID("com.example.app", "42")))
+
+	_, err = s.GetProguardMapping(context.Background(), applicationVersionID("com.example.app", "43"))
+	assert.Error(t, err)
+
+	// IDs that would escape the store prefix are never fetched
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte("secret"), 0o644))
+	_, err = s.GetProguardMapping(context.Background(), "../secret")
+	assert.ErrorIs(t, err, errInvalidMappingID)
+}
@@ -3,7 +3,11 @@ package proguardprocessor
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"time"
 
 	lru "github.com/hashicorp/golang-lru/v2"
@@ -28,34 +32,61 @@ func (e *FetchError) Unwrap() error {
 }
 
 type fileStore interface {
-	GetProguardMapping(ctx context.Context, uuid string) ([]byte, error)
+	GetProguardMapping(ctx context.Context, uuid string) (io.ReadCloser, error)
+}
+
+// proguardMapping is a loaded mapping file, with the R8 mapping information
+// symbolic doesn't read.
+type proguardMapping struct {
+	mapper *symbolic.ProguardMapper
+	r8     *r8Mapping
+	// size is the size of the mapping file, which the memory symbolic uses for
+	// it grows with.
+	size int64
 }
 
 type basicSymbolicator struct {
 	store   fileStore
 	timeout time.Duration
 	ch      chan struct{}
-	cache   *lru.Cache[string, *symbolic.ProguardMapper]
+	cache   *lru.Cache[string, *proguardMapping]
+
+	// maxCacheBytes is the total size of the mapping files the cache holds
+	// before evicting the least recently used, or 0 for no limit.
+	maxCacheBytes int64
+	// cacheBytes is the total size of the mapping files in the cache.
+	cacheBytes int64
+	// mappingDirectory keeps the downloaded mapping files, so they're only
+	// downloaded once across cache misses and restarts. If it's empty, they're
+	// removed once they're loaded.
+	mappingDirectory string
+	// maxDirectoryBytes is the total size of the mapping files the mapping
+	// directory holds before removing the least recently used, or 0 for no limit.
+	maxDirectoryBytes int64
 
 	telemetryBuilder *metadata.TelemetryBuilder
 	attributes       metric.MeasurementOption
 }
 
 func newBasicSymbolicator(_ context.Context, timeout time.Duration, cacheSize int, store fileStore, tb *metadata.TelemetryBuilder, attributes attribute.Set) (*basicSymbolicator, error) {
-	cache, err := lru.New[string, *symbolic.ProguardMapper](cacheSize) // Adjust the size as needed
-
-	if err != nil {
-		return nil, err
-	}
-	return &basicSymbolicator{
+	ns := &basicSymbolicator{
 		store:   store,
 		timeout: timeout,
 		// the channel is buffered to allow for a single request to be in progress at a time
 		ch:               make(chan struct{}, 1),
-		cache:            cache,
 		telemetryBuilder: tb,
 		attributes:       metric.WithAttributeSet(attributes),
-	}, nil
+	}
+
+	cache, err := lru.NewWithEvict(cacheSize, func(_ string, pm *proguardMapping) {
+		ns.cacheBytes -= pm.size
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	ns.cache = cache
+	return ns, nil
 }
 
 type mappedStackFrame struct {
@@ -64,6 +95,9 @@ type mappedStackFrame struct {
 	LineNumber     int64
 	SourceFile     string
 	ParameterNames string
+	// Ambiguous is set when the frame is one of several alternatives that the
+	// obfuscated frame could map to, rather than one of its inlined frames.
+	Ambiguous bool
 }
 
 // symbolicate takes a line, column, function name, and URL and returns a string
@@ -90,13 +124,76 @@ func (ns *basicSymbolicator) symbolicate(ctx context.Context, uuid, class, metho
 	return msfs, nil
 }
 
+// remapClass deobfuscates a class name, returning it unchanged if the mapping
+// doesn't have it.
+func (ns *basicSymbolicator) remapClass(ctx context.Context, uuid, class string) (string, error) {
+	var remapped string
+	err := ns.withMapper(ctx, uuid, func(pm *proguardMapping) error {
+		var err error
+		remapped, err = pm.mapper.RemapClass(class)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if remapped == "" {
+		return class, nil
+	}
+	return remapped, nil
+}
+
+// remapMethod deobfuscates a method and its class without a line number. It
+// returns empty names if the mapping doesn't have the method, or the
+// obfuscated name is several methods.
+func (ns *basicSymbolicator) remapMethod(ctx context.Context, uuid, class, method string) (string, string, error) {
+	var frames []*symbolic.SymbolicJavaStackFrame
+	err := ns.withMapper(ctx, uuid, func(pm *proguardMapping) error {
+		var err error
+		frames, err = pm.mapper.RemapMethod(class, method)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(frames) == 0 || frames[0].ClassName == "" || frames[0].MethodName == "" {
+		return "", "", nil
+	}
+	return frames[0].ClassName, frames[0].MethodName, nil
+}
+
 // limitedSymbolicate performs the actual symbolication. It is limited to a single request at a time
 // it checks and caches the proguard cache before loading the proguard file from the store
 func (ns *basicSymbolicator) limitedSymbolicate(ctx context.Context, uuid, class, method string, line int) ([]*symbolic.SymbolicJavaStackFrame, error) {
+	var frames []*symbolic.SymbolicJavaStackFrame
+	err := ns.withMapper(ctx, uuid, func(pm *proguardMapping) error {
+		var err error
+		frames, err = pm.mapper.RemapFrame(class, method, line)
+		return err
+	})
+
+	return frames, err
+}
+
+// r8Mapping returns the R8 mapping information of a UUID's mapping file.
+func (ns *basicSymbolicator) r8Mapping(ctx context.Context, uuid string) (*r8Mapping, error) {
+	var r8 *r8Mapping
+	err := ns.withMapper(ctx, uuid, func(pm *proguardMapping) error {
+		r8 = pm.r8
+		return nil
+	})
+
+	return r8, err
+}
+
+// withMapper calls fn with the proguard mapping of a UUID. It is limited to a single request at a time
+// it checks and caches the proguard cache before loading the proguard file from the store
+func (ns *basicSymbolicator) withMapper(ctx context.Context, uuid string, fn func(pm *proguardMapping) error) error {
 	select {
 	case ns.ch <- struct{}{}:
 	case <-time.After(ns.timeout):
-		return nil, &FetchError{UUID: uuid, Err: fmt.Errorf("timeout")}
+		return &FetchError{UUID: uuid, Err: fmt.Errorf("timeout")}
 	}
 
 	defer func() {
@@ -107,37 +204,183 @@ func (ns *basicSymbolicator) limitedSymbolicate(ctx context.Context, uuid, class
 	ns.telemetryBuilder.ProcessorProguardCacheSize.Record(ctx, int64(ns.cache.Len()), ns.attributes)
 
 	if !ok {
-		pmf, err := ns.store.GetProguardMapping(ctx, uuid)
-
+		var err error
+		pm, err = ns.loadMapping(ctx, uuid)
 		if err != nil {
-			ns.telemetryBuilder.ProcessorTotalProguardFetchFailures.Add(ctx, 1, ns.attributes)
-			return nil, &FetchError{UUID: uuid, Err: err}
+			return err
 		}
 
-		f, err := os.CreateTemp("", "proguard-*.txt")
+		ns.cache.Add(uuid, pm)
+		ns.cacheBytes += pm.size
 
+		// the mapping just loaded is kept even if it's over the budget by itself
+		for ns.maxCacheBytes > 0 && ns.cacheBytes > ns.maxCacheBytes && ns.cache.Len() > 1 {
+			ns.cache.RemoveOldest()
+		}
+	}
+
+	// If the cache size has changed, we should record the new size
+	ns.telemetryBuilder.ProcessorProguardCacheSize.Record(ctx, int64(ns.cache.Len()), ns.attributes)
+	return fn(pm)
+}
+
+// loadMapping loads the mapping file of a UUID from the mapping directory, or
+// downloads it from the store. symbolic only opens mapping files by path, so
+// the download is streamed to a file rather than read into memory.
+func (ns *basicSymbolicator) loadMapping(ctx context.Context, uuid string) (*proguardMapping, error) {
+	var path string
+	if ns.mappingDirectory == "" {
+		tmp, err := ns.download(ctx, uuid, os.TempDir())
 		if err != nil {
-			return nil, fmt.Errorf("failed to create temp file: %w", err)
+			return nil, err
+		}
+		defer os.Remove(tmp)
+		path = tmp
+	} else {
+		key, err := mappingKey(uuid)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(ns.mappingDirectory, key)
+
+		if _, err := os.Stat(path); err == nil {
+			// the modification time orders the files by when they were last used
+			now := time.Now()
+			_ = os.Chtimes(path, now, now)
+		} else {
+			if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+				return nil, fmt.Errorf("failed to create proguard mapping directory: %w", err)
+			}
+			tmp, err := ns.download(ctx, uuid, filepath.Dir(path))
+			if err != nil {
+				return nil, err
+			}
+			// renamed into place once it's complete, so a partial download is never loaded
+			if err := os.Rename(tmp, path); err != nil {
+				os.Remove(tmp)
+				return nil, fmt.Errorf("failed to save proguard mapping: %w", err)
+			}
+			ns.pruneMappingDirectory(path)
 		}
+	}
+
+	pm, err := openMapping(path)
+	if err != nil && ns.mappingDirectory != "" {
+		// downloaded again next time, in case the file was corrupted
+		os.Remove(path)
+	}
+	return pm, err
+}
 
-		defer f.Close()
-		defer os.Remove(f.Name())
+// pruneMappingDirectory removes the least recently used mapping files from the
+// mapping directory until they fit in maxDirectoryBytes. The file just saved is
+// kept even if it's over the budget by itself. Mappings that are still cached
+// stay usable, as symbolic has the removed files mapped into memory.
+func (ns *basicSymbolicator) pruneMappingDirectory(keep string) {
+	if ns.maxDirectoryBytes <= 0 {
+		return
+	}
 
-		_, err = f.Write(pmf)
+	type mappingFile struct {
+		path string
+		size int64
+		used time.Time
+	}
 
+	var files []mappingFile
+	var total int64
+	root := filepath.Clean(ns.mappingDirectory)
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".txt" {
+			return nil
+		}
+		info, err := d.Info()
 		if err != nil {
-			return nil, fmt.Errorf("failed to write proguard mapping to temp file: %w", err)
+			return nil
 		}
+		files = append(files, mappingFile{path: path, size: info.Size(), used: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
 
-		pm, err = symbolic.NewProguardMapper(f.Name())
-		if err != nil {
-			return nil, err
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].used.Before(files[j].used)
+	})
+
+	for _, f := range files {
+		if total <= ns.maxDirectoryBytes {
+			return
+		}
+		if f.path == keep || os.Remove(f.path) != nil {
+			continue
 		}
+		total -= f.size
 
-		ns.cache.Add(uuid, pm)
+		// the directories of the application version layout are removed once empty
+		for dir := filepath.Dir(f.path); dir != root && os.Remove(dir) == nil; dir = filepath.Dir(dir) {
+		}
 	}
+}
 
-	// If the cache size has changed, we should record the new size
-	ns.telemetryBuilder.ProcessorProguardCacheSize.Record(ctx, int64(ns.cache.Len()), ns.attributes)
-	return pm.RemapFrame(class, method, line)
+// download streams the mapping file of a UUID from the store to a new file in
+// a directory, returning the file's path.
+func (ns *basicSymbolicator) download(ctx context.Context, uuid, dir string) (string, error) {
+	r, err := ns.store.GetProguardMapping(ctx, uuid)
+
+	if err != nil {
+		ns.telemetryBuilder.ProcessorTotalProguardFetchFailures.Add(ctx, 1, ns.attributes)
+		return "", &FetchError{UUID: uuid, Err: err}
+	}
+
+	defer r.Close()
+
+	// downloads in progress aren't .txt files, so they're never pruned
+	f, err := os.CreateTemp(dir, "proguard-*.tmp")
+
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		os.Remove(f.Name())
+		ns.telemetryBuilder.ProcessorTotalProguardFetchFailures.Add(ctx, 1, ns.attributes)
+		return "", &FetchError{UUID: uuid, Err: err}
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write proguard mapping to temp file: %w", err)
+	}
+
+	return f.Name(), nil
+}
+
+// openMapping opens a mapping file with symbolic, and reads its R8 mapping
+// information a line at a time.
+func openMapping(path string) (*proguardMapping, error) {
+	mapper, err := symbolic.NewProguardMapper(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proguard mapping: %w", err)
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proguard mapping: %w", err)
+	}
+
+	r8, err := parseR8Mapping(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proguardMapping{mapper: mapper, r8: r8, size: info.Size()}, nil
 }
@@ -1,8 +1,12 @@
 package proguardprocessor
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -21,9 +25,12 @@ type mockSymbolicatorStore struct {
 	calls   int
 }
 
-func (m *mockSymbolicatorStore) GetProguardMapping(ctx context.Context, uuid string) ([]byte, error) {
+func (m *mockSymbolicatorStore) GetProguardMapping(ctx context.Context, uuid string) (io.ReadCloser, error) {
 	m.calls++
-	return m.mapping[uuid], m.err
+	if m.err != nil {
+		return nil, m.err
+	}
+	return io.NopCloser(bytes.NewReader(m.mapping[uuid])), nil
 }
 
 func createMockSymbolicatorTelemetry(t *testing.T) (*metadata.TelemetryBuilder, attribute.Set) {
@@ -170,6 +177,29 @@ func TestBasicSymbolicator_LimitedSymbolicate_CacheHit(t *testing.T) {
 	assert.Equal(t, 1, mockStore.calls, "Store should be called only once due to caching")
 }
 
+func TestBasicSymbolicator_RemapClass(t *testing.T) {
+	mockStore := &mockSymbolicatorStore{mapping: map[string][]byte{
+		"test-uuid": []byte(`com.example.RootException -> a.g:
+    void <init>() -> <init>
+`),
+	}}
+	ctx := context.Background()
+	tb, attributes := createMockSymbolicatorTelemetry(t)
+
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, mockStore, tb, attributes)
+	require.NoError(t, err)
+
+	class, err := symbolicator.remapClass(ctx, "test-uuid", "a.g")
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.RootException", class)
+
+	// classes that aren't in the mapping are kept
+	class, err = symbolicator.remapClass(ctx, "test-uuid", "java.io.IOException")
+	require.NoError(t, err)
+	assert.Equal(t, "java.io.IOException", class)
+	assert.Equal(t, 1, mockStore.calls)
+}
+
 func TestBasicSymbolicator_LargeLineNumber(t *testing.T) {
 	mockStore := &mockSymbolicatorStore{}
 	ctx := context.Background()
@@ -311,3 +341,114 @@ func TestBasicSymbolicator_DifferentUUIDs(t *testing.T) {
 	_, _ = symbolicator.limitedSymbolicate(ctx, "uuid-2", "com.example.Test", "methodA", 1)
 	assert.Greater(t, mockStore.calls, callsAfterFirst, "Call with different UUID should fetch new mapping")
 }
+
+func TestBasicSymbolicator_CacheSizeBytes(t *testing.T) {
+	ctx := context.Background()
+	mapping := []byte("com.example.Main -> a:\n    1:1:void main():10:10 -> a\n")
+	mockStore := &mockSymbolicatorStore{mapping: map[string][]byte{"uuid-1": mapping, "uuid-2": mapping, "uuid-3": mapping}}
+	tb, attributes := createMockSymbolicatorTelemetry(t)
+
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, mockStore, tb, attributes)
+	require.NoError(t, err)
+	symbolicator.maxCacheBytes = int64(2 * len(mapping))
+
+	for _, uuid := range []string{"uuid-1", "uuid-2", "uuid-3"} {
+		_, err := symbolicator.remapClass(ctx, uuid, "a")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"uuid-2", "uuid-3"}, symbolicator.cache.Keys(), "the least recently used mapping is evicted")
+	assert.Equal(t, int64(2*len(mapping)), symbolicator.cacheBytes)
+
+	// a mapping over the budget by itself is still kept
+	symbolicator.maxCacheBytes = 1
+	_, err = symbolicator.remapClass(ctx, "uuid-1", "a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uuid-1"}, symbolicator.cache.Keys())
+	assert.Equal(t, int64(len(mapping)), symbolicator.cacheBytes)
+}
+
+func TestBasicSymbolicator_MappingDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	mockStore := &mockSymbolicatorStore{mapping: map[string][]byte{
+		"uuid-1":             []byte("com.example.Main -> a:\n    1:1:void main():10:10 -> a\n"),
+		"com.example.app/42": []byte("com.example.Other -> a:\n"),
+	}}
+	tb, attributes := createMockSymbolicatorTelemetry(t)
+
+	newSymbolicator := func() *basicSymbolicator {
+		symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, mockStore, tb, attributes)
+		require.NoError(t, err)
+		symbolicator.mappingDirectory = dir
+		return symbolicator
+	}
+
+	remapped, err := newSymbolicator().remapClass(ctx, "uuid-1", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Main", remapped)
+	assert.FileExists(t, filepath.Join(dir, "uuid-1.txt"))
+
+	remapped, err = newSymbolicator().remapClass(ctx, "com.example.app/42", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Other", remapped)
+	assert.FileExists(t, filepath.Join(dir, "com.example.app", "42", "mapping.txt"))
+	assert.Equal(t, 2, mockStore.calls)
+
+	// a new symbolicator, as after a restart, loads the mapping from the directory
+	remapped, err = newSymbolicator().remapClass(ctx, "uuid-1", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Main", remapped)
+	assert.Equal(t, 2, mockStore.calls)
+
+	_, err = newSymbolicator().remapClass(ctx, "../uuid-1", "a")
+	assert.Error(t, err)
+	assert.Equal(t, 2, mockStore.calls)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no temp files are left behind")
+}
+
+func TestBasicSymbolicator_MappingDirectorySizeBytes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	mapping := []byte("com.example.Main -> a:\n    1:1:void main():10:10 -> a\n")
+	mockStore := &mockSymbolicatorStore{mapping: map[string][]byte{"uuid-1": mapping, "uuid-2": mapping, "com.example.app/42": mapping}}
+	tb, attributes := createMockSymbolicatorTelemetry(t)
+
+	symbolicator, err := newBasicSymbolicator(ctx, 5*time.Second, 10, mockStore, tb, attributes)
+	require.NoError(t, err)
+	symbolicator.mappingDirectory = dir
+	symbolicator.maxDirectoryBytes = int64(2 * len(mapping))
+
+	for _, uuid := range []string{"com.example.app/42", "uuid-1"} {
+		_, err := symbolicator.remapClass(ctx, uuid, "a")
+		require.NoError(t, err)
+	}
+	// the application version was used last
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "uuid-1.txt"), time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour)))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "com.example.app", "42", "mapping.txt"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+
+	_, err = symbolicator.remapClass(ctx, "uuid-2", "a")
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(dir, "uuid-1.txt"), "the least recently used mapping is removed")
+	assert.FileExists(t, filepath.Join(dir, "com.example.app", "42", "mapping.txt"))
+	assert.FileExists(t, filepath.Join(dir, "uuid-2.txt"))
+
+	// mappings that are still cached can be used after they're removed
+	remapped, err := symbolicator.remapClass(ctx, "uuid-1", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Main", remapped)
+
+	symbolicator.maxDirectoryBytes = 1
+	symbolicator.cache.Purge()
+	_, err = symbolicator.remapClass(ctx, "uuid-1", "a")
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "the empty directories of the application version are removed")
+	assert.Equal(t, "uuid-1.txt", entries[0].Name())
+}
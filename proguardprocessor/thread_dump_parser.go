@@ -0,0 +1,105 @@
+package proguardprocessor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mainThreadName is the name of the thread an Android app's UI runs on, which
+// is the thread an ANR is blocked on.
+const mainThreadName = "main"
+
+var (
+	// threadHeaderRegex matches the header of a thread in a thread dump.
+	// Capture Groups:
+	// 		1: Thread name
+	//
+	// Examples that match:
+	// 		"main" prio=5 tid=1 Blocked
+	// 		"Signal Catcher" daemon prio=10 tid=6 Runnable
+	// 		"main" #1 prio=5 os_prio=0 cpu=52.10ms tid=0x00007f8c0800a000 nid=0x2803 waiting for monitor entry
+	//
+	threadHeaderRegex = regexp.MustCompile(`^"(.*)"\s.*\bprio=\d+.*$`)
+	// lockRegex matches the lines of a thread dump that describe a lock a thread
+	// holds or is waiting for.
+	// Capture Groups:
+	// 		1: The line up to the class of the lock
+	// 		2: Class of the lock
+	// 		3: The rest of the line
+	//
+	// Examples that match:
+	// 		- locked <0x0e4f5a6b> (a java.lang.Object)
+	// 		- waiting to lock <0x0a1b2c3d> (a a.b) held by thread 15
+	// 		- parking to wait for  <0x000000076ab1c2d8> (a java.util.concurrent.locks.AbstractQueuedSynchronizer$ConditionObject)
+	//
+	lockRegex = regexp.MustCompile(`^(\s*-\s.*\(a\s+)([^\s)]+)(\).*)$`)
+)
+
+// lockLine is a line of a thread dump that describes a lock, with the class of
+// the lock's object.
+type lockLine struct {
+	prefix string
+	class  string
+	suffix string
+}
+
+// thread is a thread of a thread dump with its frames, the locks it holds or
+// is waiting for, and any other lines, such as the details ART prints.
+type thread struct {
+	name     string
+	header   string
+	elements []element
+}
+
+// threadDump is a dump of the threads of a process, such as an ANR trace.
+type threadDump struct {
+	// preamble is the lines before the first thread, eg. the process's ID and
+	// command line.
+	preamble []string
+	threads  []*thread
+}
+
+// isThreadDump reports whether a stack trace is a thread dump rather than an
+// exception, which it is if it has a thread header.
+func isThreadDump(raw string) bool {
+	for _, line := range strings.Split(raw, "\n") {
+		if threadHeaderRegex.MatchString(strings.TrimRight(line, "\r")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseThreadDump parses a thread dump into its threads. Every line is kept,
+// so the thread dump can be rebuilt as it was printed.
+func parseThreadDump(raw string) *threadDump {
+	dump := &threadDump{}
+
+	var current *thread
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if matches := threadHeaderRegex.FindStringSubmatch(line); matches != nil {
+			current = &thread{name: matches[1], header: line, elements: make([]element, 0)}
+			dump.threads = append(dump.threads, current)
+			continue
+		}
+
+		if current == nil {
+			dump.preamble = append(dump.preamble, line)
+			continue
+		}
+
+		if frame := parseStackFrame(line); frame != nil {
+			// frames in a thread dump aren't always indented with a tab
+			frame.indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			current.elements = append(current.elements, element{frame: frame})
+		} else if matches := lockRegex.FindStringSubmatch(line); matches != nil {
+			current.elements = append(current.elements, element{lock: &lockLine{prefix: matches[1], class: matches[2], suffix: matches[3]}})
+		} else {
+			current.elements = append(current.elements, element{line: line})
+		}
+	}
+
+	return dump
+}
@@ -0,0 +1,59 @@
+package proguardprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const anrTrace = `----- pid 12345 at 2026-01-01 12:00:00.000 -----
+Cmd line: com.example.app
+
+DALVIK THREADS (2):
+"main" prio=5 tid=1 Blocked
+  | group="main" sCount=1 ucsCount=0 flags=1 obj=0x72f6d1a8 self=0xb400007b1c0e2be0
+  at a.b.c(SourceFile:12)
+  - waiting to lock <0x0a1b2c3d> (a a.d) held by thread 15
+  at java.lang.Object.wait(Native method)
+  native: #00 pc 000000000004c3a0  /apex/com.android.runtime/lib64/bionic/libc.so (syscall+32)
+
+"Signal Catcher" daemon prio=10 tid=6 Runnable
+  at a.b.e(SourceFile:34)
+  - locked <0x0e4f5a6b> (a java.lang.Object)
+----- end 12345 -----`
+
+func TestIsThreadDump(t *testing.T) {
+	assert.True(t, isThreadDump(anrTrace))
+	assert.True(t, isThreadDump(`"main" #1 prio=5 os_prio=0 cpu=52.10ms tid=0x00007f8c0800a000 nid=0x2803 waiting for monitor entry
+   java.lang.Thread.State: BLOCKED (on object monitor)
+	at a.b.c(SourceFile:12)`))
+	assert.False(t, isThreadDump("java.lang.RuntimeException: \"main\" prio=5\n\tat a.b.c(SourceFile:12)"))
+}
+
+func TestParseThreadDump(t *testing.T) {
+	dump := parseThreadDump(anrTrace)
+
+	assert.Equal(t, []string{"----- pid 12345 at 2026-01-01 12:00:00.000 -----", "Cmd line: com.example.app", "", "DALVIK THREADS (2):"}, dump.preamble)
+	require.Len(t, dump.threads, 2)
+
+	main := dump.threads[0]
+	assert.Equal(t, "main", main.name)
+	assert.Equal(t, `"main" prio=5 tid=1 Blocked`, main.header)
+	assert.Equal(t, []element{
+		{line: `  | group="main" sCount=1 ucsCount=0 flags=1 obj=0x72f6d1a8 self=0xb400007b1c0e2be0`},
+		{frame: &stackFrame{class: "a.b", method: "c", sourceFile: "SourceFile", line: 12, indent: "  "}},
+		{lock: &lockLine{prefix: "  - waiting to lock <0x0a1b2c3d> (a ", class: "a.d", suffix: ") held by thread 15"}},
+		{frame: &stackFrame{class: "java.lang.Object", method: "wait", sourceFile: "Native method", line: -2, indent: "  "}},
+		{line: "  native: #00 pc 000000000004c3a0  /apex/com.android.runtime/lib64/bionic/libc.so (syscall+32)"},
+		{line: ""},
+	}, main.elements)
+
+	signalCatcher := dump.threads[1]
+	assert.Equal(t, "Signal Catcher", signalCatcher.name)
+	assert.Equal(t, []element{
+		{frame: &stackFrame{class: "a.b", method: "e", sourceFile: "SourceFile", line: 34, indent: "  "}},
+		{lock: &lockLine{prefix: "  - locked <0x0e4f5a6b> (a ", class: "java.lang.Object", suffix: ")"}},
+		{line: "----- end 12345 -----"},
+	}, signalCatcher.elements)
+}
@@ -0,0 +1,42 @@
+package proguardprocessor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// ProcessTraces deobfuscates the names and configured attributes of spans,
+// which auto-instrumentation names after obfuscated classes and methods.
+func (p *proguardLogsProcessor) ProcessTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	p.logger.Debug("Processing traces")
+
+	if !p.cfg.DeobfuscateSpanNames && !p.deobfuscatesAttributes() {
+		return td, nil
+	}
+
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		p.processResourceSpans(ctx, rs)
+	}
+
+	return td, nil
+}
+
+func (p *proguardLogsProcessor) processResourceSpans(ctx context.Context, rs ptrace.ResourceSpans) {
+	resourceAttrs := rs.Resource().Attributes()
+	for j := 0; j < rs.ScopeSpans().Len(); j++ {
+		ss := rs.ScopeSpans().At(j)
+		p.processScopeSpans(ctx, ss, resourceAttrs)
+	}
+}
+
+func (p *proguardLogsProcessor) processScopeSpans(ctx context.Context, ss ptrace.ScopeSpans, resourceAttrs pcommon.Map) {
+	for k := 0; k < ss.Spans().Len(); k++ {
+		span := ss.Spans().At(k)
+		if p.languageAllowed(span.Attributes(), resourceAttrs) {
+			p.deobfuscateSpan(ctx, span, resourceAttrs)
+		}
+	}
+}